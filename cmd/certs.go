@@ -0,0 +1,172 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	// stdin is where the confirmations are read from
+	stdin io.Reader = os.Stdin
+
+	assumeYes     bool
+	hashAlgorithm string
+)
+
+const (
+	certsFetchLongDescription = `Fetch the CA certificate of a mirror and pin it in the configuration file.
+
+usage:
+
+$ caasp-init certs fetch https://mycompany.airgapped.com
+
+Connects to the mirror and shows the certificate chain it presents with the
+fingerprints of each certificate. After confirmation the CA certificate, its
+fingerprint and the hash algorithm are written to every entry of the mirror
+in the configuration file, keeping the comments and the ordering of the file.
+
+The mirror must already be declared in the configuration file.
+`
+
+	certsCAHashLongDescription = `Print the hash of the cluster CA certificate.
+
+usage:
+
+$ caasp-init certs ca-hash
+
+On the seeder the hash of the CA certificate in the certificates directory
+is printed, in the sha256:<hash> format of kubeadm, for setting
+'certificates.caCrtHash' in the configuration of the joining nodes.
+
+On a joining node the CA published by the seeder is fetched and verified
+against 'certificates.caCrtHash', and against the certificate of the API
+server of the seeder, before its hash is printed.
+`
+)
+
+// newCertsCmd represents the certs command
+func newCertsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "certs",
+		Short: "Manage the certificates of the mirrors and the cluster",
+	}
+
+	fetch := &cobra.Command{
+		Use:   "fetch <mirror-url>",
+		Short: "Fetch and pin the CA certificate of a mirror",
+		Long:  certsFetchLongDescription,
+		Args:  cobra.ExactArgs(1),
+		RunE:  runCertsFetch,
+	}
+	fetch.Flags().BoolVarP(&assumeYes, "yes", "y", false, "write the certificate without asking for confirmation")
+	fetch.Flags().StringVar(&hashAlgorithm, "hash-algorithm", certs.DefaultHashAlgorithm, "hash algorithm used for the fingerprint (SHA1 or SHA256)")
+	c.AddCommand(fetch)
+
+	c.AddCommand(&cobra.Command{
+		Use:   "ca-hash",
+		Short: "Print the hash of the cluster CA certificate",
+		Long:  certsCAHashLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runCertsCAHash,
+	})
+
+	return c
+}
+
+func runCertsFetch(cmd *cobra.Command, args []string) error {
+	mirrorURL := args[0]
+	algorithm := strings.ToUpper(hashAlgorithm)
+
+	chain, err := certs.FetchChain(mirrorURL)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "%s presented %d certificates:\n", mirrorURL, len(chain))
+	for i, cert := range chain {
+		fingerprint, err := certs.Fingerprint(cert, algorithm)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "  %d: %s\n", i, cert.Subject)
+		fmt.Fprintf(w, "     issuer:  %s\n", cert.Issuer)
+		fmt.Fprintf(w, "     expires: %s\n", cert.NotAfter.Format(time.RFC3339))
+		fmt.Fprintf(w, "     %s:  %s\n", algorithm, fingerprint)
+	}
+
+	ca := certs.ChainCA(chain)
+	fingerprint, err := certs.Fingerprint(ca, algorithm)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nCA certificate: %s\n", ca.Subject)
+	if ca.Subject.String() != ca.Issuer.String() {
+		fmt.Fprintf(w, "warning: the root certificate (%s) was not presented by the mirror\n", ca.Issuer)
+	}
+
+	if !assumeYes {
+		fmt.Fprintf(w, "Trust this certificate and write it to %s? [y/N] ", cfgFile)
+		answer, _ := bufio.NewReader(stdin).ReadString('\n')
+		answer = strings.ToLower(strings.TrimSpace(answer))
+		if answer != "y" && answer != "yes" {
+			return errors.New("certificate not trusted, the configuration file was not changed")
+		}
+	}
+
+	err = config.SetMirrorCertificate(cfgFile, mirrorURL, string(certs.EncodeCertificate(ca)), fingerprint, algorithm)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "Certificate of %s written to %s\n", mirrorURL, cfgFile)
+	return nil
+}
+
+func runCertsCAHash(cmd *cobra.Command, args []string) error {
+	kubicConfig, err := loadRoleConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	if kubicConfig.ClusterFormation.Role == config.RoleSeeder {
+		ca, err := certs.LoadCA(kubicConfig)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(cmd.OutOrStdout(), certs.CAHash(ca))
+		return nil
+	}
+
+	ca, err := fetchSeederCA(kubicConfig)
+	if err != nil {
+		return err
+	}
+	log.WithFields(log.Fields{"seeder": kubicConfig.ClusterFormation.Seeder, "subject": ca.Subject.String()}).Infof("CA of the seeder verified")
+	fmt.Fprintln(cmd.OutOrStdout(), certs.CAHash(ca))
+	return nil
+}
@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runCertsFetch(t *testing.T) {
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer registry.Close()
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-certs-fetch")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	content := fmt.Sprintf(`---
+bootstrap:
+  registries:
+    - prefix: https://mycompany.registry.com
+      mirrors:
+        - url: %s
+`, registry.URL)
+
+	tests := []struct {
+		name      string
+		mirrorURL string
+		answer    string
+		yes       bool
+		wantErr   bool
+	}{
+		{"confirmed", registry.URL, "y\n", false, false},
+		{"assume_yes", registry.URL, "", true, false},
+		{"rejected", registry.URL, "n\n", false, true},
+		{"unreachable", "https://127.0.0.1:1", "y\n", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgFile = filepath.Join(tmpDir, tt.name+".yaml")
+			if err := ioutil.WriteFile(cfgFile, []byte(content), os.FileMode(0644)); err != nil {
+				t.Fatalf("faliled to write config file: %s", err)
+			}
+			stdin = strings.NewReader(tt.answer)
+			assumeYes = tt.yes
+			hashAlgorithm = "sha256"
+
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			if err := runCertsFetch(c, []string{tt.mirrorURL}); (err != nil) != tt.wantErr {
+				t.Errorf("runCertsFetch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			b, _ := ioutil.ReadFile(cfgFile)
+			written := strings.Contains(string(b), "BEGIN CERTIFICATE")
+			if written == tt.wantErr {
+				t.Errorf("runCertsFetch() certificate written = %v, wantErr %v", written, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_runCertsCAHash(t *testing.T) {
+	defer func(f, role string) { cfgFile, nodeRole = f, role }(cfgFile, nodeRole)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-certs-ca-hash")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	srv.Close()
+	ca := srv.Certificate()
+	pkiDir := filepath.Join(tmpDir, "pki")
+	if err := os.Mkdir(pkiDir, 0755); err != nil {
+		t.Fatalf("creating %s: %s", pkiDir, err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(pkiDir, certs.CAFile), certs.EncodeCertificate(ca), 0644); err != nil {
+		t.Fatalf("writing the CA: %s", err)
+	}
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		return path
+	}
+	withCA := writeConfig("ca.yaml", fmt.Sprintf("certificates:\n  directory: %s\n", pkiDir))
+	withoutCA := writeConfig("no-ca.yaml", fmt.Sprintf("certificates:\n  directory: %s\n", filepath.Join(tmpDir, "missing")))
+	join := writeConfig("join.yaml", fmt.Sprintf("clusterFormation:\n  seeder: 127.0.0.1:1\ncertificates:\n  caCrtHash: %s\n", certs.CAHash(ca)))
+	joinNoHash := writeConfig("join-no-hash.yaml", "clusterFormation:\n  seeder: 127.0.0.1:1\n")
+
+	tests := []struct {
+		name    string
+		cfg     string
+		role    string
+		want    string
+		wantErr bool
+	}{
+		{"seeder", withCA, "seeder", certs.CAHash(ca) + "\n", false},
+		{"seeder_without_ca", withoutCA, "seeder", "", true},
+		{"join_unreachable", join, "join", "", true},
+		{"join_without_hash", joinNoHash, "join", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgFile, nodeRole = tt.cfg, tt.role
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			if err := runCertsCAHash(c, []string{}); (err != nil) != tt.wantErr {
+				t.Fatalf("runCertsCAHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runCertsCAHash() = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
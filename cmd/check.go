@@ -0,0 +1,212 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/check"
+	"github.com/kubic-project/caasp-init/pkg/cni"
+	"github.com/kubic-project/caasp-init/pkg/netutil"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	checkMirrorsLongDescription = `Check the health of the registry mirrors declared in the configuration file.
+
+usage:
+
+$ caasp-init check mirrors
+
+For each mirror a TLS handshake is done trusting the configured certificate,
+and the Docker Registry v2 '/v2/' endpoint is called. The latency, the
+certificate chain, the HTTP status and whether authentication is required
+are reported.
+
+The command fails if any mirror is not healthy.
+`
+
+	checkCniLongDescription = `Check the plugins needed by the CNI driver declared in the configuration file.
+
+usage:
+
+$ caasp-init check cni
+
+Every plugin binary needed by the driver has to be an executable in the CNI
+bin directory, 'network.cni.binDir'. The pods cannot start until they are
+installed.
+
+The command fails if any plugin is missing.
+`
+
+	checkNetworkLongDescription = `Check the pods and services subnets declared in the configuration file.
+
+usage:
+
+$ caasp-init check network
+
+The subnets must not overlap the networks of the node, except the ones
+created by the CNI drivers and the docker0 bridge, nor contain the bind
+address. Each node gets a /24
+of the pods subnet, a /64 for IPv6, which has to make room for the
+'network.expectedNodes'.
+
+The command fails if any problem is found.
+`
+)
+
+// newCheckCmd represents the check command
+func newCheckCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "check",
+		Short: "Check the health of the configured services",
+	}
+	c.AddCommand(&cobra.Command{
+		Use:   "mirrors",
+		Short: "Check the health of the registry mirrors",
+		Long:  checkMirrorsLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runCheckMirrors,
+	})
+	c.AddCommand(&cobra.Command{
+		Use:   "cni",
+		Short: "Check the plugins of the CNI driver",
+		Long:  checkCniLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runCheckCni,
+	})
+	c.AddCommand(&cobra.Command{
+		Use:   "network",
+		Short: "Check the pods and services subnets",
+		Long:  checkNetworkLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runCheckNetwork,
+	})
+	return c
+}
+
+func runCheckMirrors(cmd *cobra.Command, args []string) error {
+	kubicConfig, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	results, err := check.Mirrors(kubicConfig)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	failed := 0
+	for _, r := range results {
+		fmt.Fprintf(w, "%s (mirror of %s)\n", r.URL, r.Prefix)
+		if r.Status != 0 {
+			fmt.Fprintf(w, "  status:   %d %s\n", r.Status, http.StatusText(r.Status))
+			fmt.Fprintf(w, "  latency:  %s\n", r.Latency.Round(time.Millisecond))
+		}
+		if r.AuthRequired {
+			fmt.Fprintf(w, "  auth:     required (%s)\n", r.AuthChallenge)
+		}
+		for i, c := range r.Chain {
+			fmt.Fprintf(w, "  cert %d:   %s\n", i, c.Subject)
+			fmt.Fprintf(w, "            issuer:  %s\n", c.Issuer)
+			fmt.Fprintf(w, "            expires: %s\n", c.NotAfter.Format(time.RFC3339))
+			fmt.Fprintf(w, "            SHA256:  %s\n", c.Fingerprint)
+		}
+		if r.Err != nil {
+			failed++
+			fmt.Fprintf(w, "  result:   FAILED: %v\n", r.Err)
+		} else {
+			fmt.Fprintln(w, "  result:   OK")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d mirrors failed the check", failed, len(results))
+	}
+	return nil
+}
+
+func runCheckCni(cmd *cobra.Command, args []string) error {
+	kubicConfig, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	driver := kubicConfig.Network.Cni.Driver
+	if driver == "" {
+		fmt.Fprintln(w, "no CNI driver configured")
+		return nil
+	}
+	plugins, err := cni.Plugins(driver)
+	if err != nil {
+		return err
+	}
+	missing := map[string]bool{}
+	for _, plugin := range cni.MissingPlugins(kubicConfig) {
+		missing[plugin] = true
+	}
+
+	fmt.Fprintf(w, "driver:   %s\n", driver)
+	fmt.Fprintf(w, "conflist: %s\n", cni.Path(kubicConfig, driver))
+	fmt.Fprintf(w, "binDir:   %s\n", kubicConfig.Network.Cni.BinDir)
+	for _, plugin := range plugins {
+		result := "OK"
+		if missing[plugin] {
+			result = "MISSING"
+		}
+		fmt.Fprintf(w, "  %-12s %s\n", plugin, result)
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%d of %d CNI plugins missing in %s", len(missing), len(plugins), kubicConfig.Network.Cni.BinDir)
+	}
+	return nil
+}
+
+func runCheckNetwork(cmd *cobra.Command, args []string) error {
+	kubicConfig, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	fmt.Fprintf(w, "podSubnet:     %s\n", kubicConfig.Network.PodSubnet)
+	fmt.Fprintf(w, "serviceSubnet: %s\n", kubicConfig.Network.ServiceSubnet)
+	if bind := kubicConfig.Network.Bind; bind.Address != "" {
+		if bind.Interface != "" {
+			fmt.Fprintf(w, "bind:          %s on %s\n", bind.Address, bind.Interface)
+		} else {
+			fmt.Fprintf(w, "bind:          %s\n", bind.Address)
+		}
+	}
+	warnings, err := netutil.CheckSubnets(kubicConfig, interfaces)
+	if err != nil {
+		fmt.Fprintf(w, "  result:   FAILED: %v\n", err)
+		return err
+	}
+	for _, warning := range warnings {
+		fmt.Fprintf(w, "  warning:  %s\n", warning)
+	}
+	if len(warnings) > 0 {
+		return fmt.Errorf("%d problems found in the subnets", len(warnings))
+	}
+	fmt.Fprintln(w, "  result:   OK")
+	return nil
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/netutil"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runCheckMirrors(t *testing.T) {
+	registry := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer registry.Close()
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-check")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	untrusted := filepath.Join(tmpDir, "untrusted.yaml")
+	err = ioutil.WriteFile(untrusted, []byte(fmt.Sprintf(`---
+bootstrap:
+  registries:
+    - prefix: https://mycompany.registry.com
+      mirrors:
+        - url: %s
+`, registry.URL)), os.FileMode(0644))
+	if err != nil {
+		t.Fatalf("faliled to write config file: %s", err)
+	}
+	ca := strings.Replace(string(certs.EncodeCertificate(registry.Certificate())), "\n", "\n            ", -1)
+	healthy := filepath.Join(tmpDir, "healthy.yaml")
+	err = ioutil.WriteFile(healthy, []byte(fmt.Sprintf(`---
+bootstrap:
+  registries:
+    - prefix: https://mycompany.registry.com
+      mirrors:
+        - url: %s
+          certificate: |
+            %s
+`, registry.URL, ca)), os.FileMode(0644))
+	if err != nil {
+		t.Fatalf("faliled to write config file: %s", err)
+	}
+	failing := filepath.Join(tmpDir, "failing.yaml")
+	err = ioutil.WriteFile(failing, []byte(`---
+bootstrap:
+  registries:
+    - prefix: https://mycompany.registry.com
+      mirrors:
+        - url: https://127.0.0.1:1
+`), os.FileMode(0644))
+	if err != nil {
+		t.Fatalf("faliled to write config file: %s", err)
+	}
+
+	// the mirrors of the drop-ins are checked like the ones apply writes
+	dropIn := filepath.Join(tmpDir, "drop-in.yaml")
+	if err := ioutil.WriteFile(dropIn, []byte("runtime:\n  engine: docker\n"), 0644); err != nil {
+		t.Fatalf("faliled to write config file: %s", err)
+	}
+	if err := os.Mkdir(dropIn+".d", 0755); err != nil {
+		t.Fatalf("creating drop-in dir: %s", err)
+	}
+	failingContent, _ := ioutil.ReadFile(failing)
+	if err := ioutil.WriteFile(filepath.Join(dropIn+".d", "10-mirrors.yaml"), failingContent, 0644); err != nil {
+		t.Fatalf("faliled to write drop-in: %s", err)
+	}
+	invalid := filepath.Join(tmpDir, "invalid.yaml")
+	err = ioutil.WriteFile(invalid, []byte(`---
+bootstrap:
+  registries:
+    - prefix: https://mycompany.registry.com
+      mirrors:
+        - url: http://127.0.0.1:1
+`), os.FileMode(0644))
+	if err != nil {
+		t.Fatalf("faliled to write config file: %s", err)
+	}
+
+	tests := []struct {
+		name       string
+		configFile string
+		want       string
+		wantErr    bool
+	}{
+		{"healthy", healthy, "auth:     required", false},
+		{"drop_in", dropIn, "https://127.0.0.1:1 (mirror of https://mycompany.registry.com)", true},
+		{"invalid_config", invalid, "", true},
+		{"untrusted", untrusted, "FAILED", true},
+		{"failing", failing, "FAILED", true},
+		{"missing_config", filepath.Join(tmpDir, "missing.yaml"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			cfgFile = tt.configFile
+			if err := runCheckMirrors(c, []string{}); (err != nil) != tt.wantErr {
+				t.Errorf("runCheckMirrors() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("runCheckMirrors() output %q does not contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_runCheckCni(t *testing.T) {
+	defer func(f string) { cfgFile = f }(cfgFile)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-check")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	binDir := filepath.Join(tmpDir, "bin")
+	os.MkdirAll(binDir, 0755)
+	for _, plugin := range []string{"bridge", "host-local", "portmap", "loopback"} {
+		if err := ioutil.WriteFile(filepath.Join(binDir, plugin), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("writing plugin: %s", err)
+		}
+	}
+	writeConfig := func(name, driver string) string {
+		path := filepath.Join(tmpDir, name+".yaml")
+		content := fmt.Sprintf("network:\n  cni:\n    binDir: %s\n    confDir: %s\n    driver: %s\n", binDir, filepath.Join(tmpDir, "net.d"), driver)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name       string
+		configFile string
+		want       string
+		wantErr    bool
+	}{
+		{"none", writeConfig("none", `""`), "no CNI driver configured", false},
+		{"bridge", writeConfig("bridge", "bridge"), "  portmap      OK\n", false},
+		{"flannel", writeConfig("flannel", "flannel"), "  flannel      MISSING\n", true},
+		{"unknown", writeConfig("unknown", "weave"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			cfgFile = tt.configFile
+			if err := runCheckCni(c, []string{}); (err != nil) != tt.wantErr {
+				t.Errorf("runCheckCni() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("runCheckCni() output %q does not contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func Test_runCheckNetwork(t *testing.T) {
+	defer func(f string, l netutil.InterfaceLister) { cfgFile, interfaces = f, l }(cfgFile, interfaces)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-check")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	ip, eth0, _ := net.ParseCIDR("192.168.1.10/24")
+	eth0.IP = ip
+	interfaces = netutil.StaticLister{{Name: "eth0", Addrs: []*net.IPNet{eth0}}}
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(tmpDir, name+".yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		return path
+	}
+
+	tests := []struct {
+		name       string
+		configFile string
+		want       string
+		wantErr    bool
+	}{
+		{"defaults", writeConfig("defaults", "network: {}\n"), "  result:   OK\n", false},
+		{"overlap", writeConfig("overlap", "network:\n  podSubnet: 192.168.0.0/16\n"), "overlaps the network 192.168.1.0/24", true},
+		{"bind", writeConfig("bind", "network:\n  podSubnet: 192.168.0.0/16\n  bind:\n    address: 192.168.1.10\n"), "FAILED: bind address", true},
+		{"bind_interface", writeConfig("bind_interface", "network:\n  bind:\n    interface: eth0\n"), "bind:          192.168.1.10 on eth0\n", false},
+		{"bind_unknown_interface", writeConfig("bind_unknown_interface", "network:\n  bind:\n    interface: eth1\n"), "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			cfgFile = tt.configFile
+			if err := runCheckNetwork(c, []string{}); (err != nil) != tt.wantErr {
+				t.Errorf("runCheckNetwork() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("runCheckNetwork() output %q does not contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	configShowLongDescription = `Print the effective configuration.
+
+usage:
+
+$ caasp-init config show
+
+The configuration file and its drop-ins are loaded, the default values are
+set and the bind address is resolved. The values of the secrets, like the
+bootstrap token and the passwords of the mirrors, are replaced by
+'(redacted)'.
+`
+)
+
+// newConfigCmd represents the config command
+func newConfigCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "config",
+		Short: "Inspect the configuration",
+	}
+	c.AddCommand(&cobra.Command{
+		Use:   "show",
+		Short: "Print the effective configuration, without its secrets",
+		Long:  configShowLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runConfigShow,
+	})
+	return c
+}
+
+func runConfigShow(cmd *cobra.Command, args []string) error {
+	kubicConfig, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+	redacted, err := config.Redact(kubicConfig, func(string) string { return "(redacted)" })
+	if err != nil {
+		return err
+	}
+	b, err := yaml.Marshal(redacted)
+	if err != nil {
+		return err
+	}
+	_, err = cmd.OutOrStdout().Write(b)
+	return err
+}
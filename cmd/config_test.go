@@ -0,0 +1,74 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/log"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runConfigShow(t *testing.T) {
+	defer func(f string) { cfgFile = f }(cfgFile)
+	defer log.ResetSecrets()
+	tmpDir, err := ioutil.TempDir("", "caasp-init-config")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfgFile = filepath.Join(tmpDir, "kubic-init.yaml")
+	content := `clusterFormation:
+  seeder: seeder.example.com
+  token: 94dcda.c271f4ff502789ca
+bootstrap:
+  registries:
+  - prefix: https://registry.suse.com
+    mirrors:
+    - url: https://mirror.example.com
+      username: user
+      password: s3cr3t
+`
+	if err := ioutil.WriteFile(cfgFile, []byte(content), 0644); err != nil {
+		t.Fatalf("writing %s: %s", cfgFile, err)
+	}
+
+	out := &bytes.Buffer{}
+	c := &cobra.Command{}
+	c.SetOutput(out)
+	if err := runConfigShow(c, []string{}); err != nil {
+		t.Fatalf("runConfigShow() error = %v", err)
+	}
+	for _, want := range []string{"seeder: seeder.example.com\n", "token: (redacted)\n", "password: (redacted)\n", "engine: docker\n"} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("runConfigShow() output %q does not contain %q", out.String(), want)
+		}
+	}
+	for _, secret := range []string{"94dcda.c271f4ff502789ca", "s3cr3t"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("runConfigShow() output shows the secret %q", secret)
+		}
+		if log.Redact(secret) != "(redacted)" {
+			t.Errorf("loadConfig() did not hide the secret %q from the logs", secret)
+		}
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/cni"
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/credentials"
+	"github.com/kubic-project/caasp-init/pkg/daemon"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/registries"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	driftLongDescription = `Compare the files on disk with what the configuration renders.
+
+usage:
+
+$ caasp-init drift
+
+Each difference is either owned, a file or key written by caasp-init that
+was edited or removed, or foreign, a key added by hand to daemon.json that
+caasp-init keeps when it writes the file. The entries of other registries in
+the auth file are kept by caasp-init, they are not reported.
+
+With '--fix' the configuration is applied again, which sets the owned files
+and keys back to what it renders, keeping the foreign keys. With '--reload' the container runtime is
+reloaded or restarted afterwards when needed.
+
+The output and the exit code follow the monitoring plugins conventions:
+
+  0  OK        the files match the configuration
+  1  WARNING   only foreign keys were added
+  2  CRITICAL  owned files or keys differ
+  3  UNKNOWN   the check could not be done
+`
+)
+
+var (
+	driftFix bool
+
+	// driftChecks compare the files written by caasp-init with the configuration
+	driftChecks = []func(*config.KubicInitConfiguration) ([]drift.Finding, error){
+		daemonDrift,
+		registries.Drift,
+		certs.Drift,
+		credentials.Drift,
+		cni.Drift,
+	}
+
+	// remediateDrift sets the owned files and keys back to the configuration,
+	// applying it as caasp-init does, which keeps the keys added by hand
+	remediateDrift = apply
+)
+
+// newDriftCmd represents the drift command
+func newDriftCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "drift",
+		Short: "Compare the files on disk with the configuration",
+		Long:  driftLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runDrift,
+	}
+	c.Flags().BoolVar(&driftFix, "fix", false, "set the files and keys owned by caasp-init back to the configuration")
+	c.Flags().BoolVar(&reloadRuntime, "reload", false, "reload or restart the container runtime when its configuration changed")
+	return c
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	w := cmd.OutOrStdout()
+	status, err := checkDrift(w)
+	if err != nil {
+		fmt.Fprintf(w, "DRIFT %s - %v\n", drift.Unknown, err)
+		status = drift.Unknown
+	}
+	exitStatus = int(status)
+	return nil
+}
+
+func checkDrift(w io.Writer) (drift.Status, error) {
+	kubicConfig, err := loadConfig(cfgFile)
+	if err != nil {
+		return drift.Unknown, err
+	}
+	findings, err := findDrift(kubicConfig)
+	if err != nil {
+		return drift.Unknown, err
+	}
+
+	var fixed []drift.Finding
+	if driftFix && drift.Check(findings) == drift.Critical {
+		if err := remediateDrift(kubicConfig); err != nil {
+			return drift.Unknown, err
+		}
+		for _, f := range findings {
+			if f.Owner == drift.Owned {
+				fixed = append(fixed, f)
+			}
+		}
+		findings, err = findDrift(kubicConfig)
+		if err != nil {
+			return drift.Unknown, err
+		}
+	}
+
+	status := drift.Check(findings)
+	counts := map[string]int{}
+	for _, f := range findings {
+		counts[f.Owner]++
+	}
+	summary := "files match the configuration"
+	if len(findings) > 0 {
+		summary = fmt.Sprintf("%d owned, %d foreign differences", counts[drift.Owned], counts[drift.Foreign])
+	}
+	fmt.Fprintf(w, "DRIFT %s - %s | owned=%d foreign=%d fixed=%d\n",
+		status, summary, counts[drift.Owned], counts[drift.Foreign], len(fixed))
+	for _, f := range findings {
+		fmt.Fprintf(w, "%-8s %-9s %s\n", f.Owner, f.Kind, target(f))
+	}
+	for _, f := range fixed {
+		fmt.Fprintf(w, "%-8s %-9s %s\n", "fixed", f.Kind, target(f))
+	}
+	return status, nil
+}
+
+func findDrift(kubicConfig *config.KubicInitConfiguration) ([]drift.Finding, error) {
+	var findings []drift.Finding
+	for _, check := range driftChecks {
+		f, err := check(kubicConfig)
+		if err != nil {
+			return nil, err
+		}
+		findings = append(findings, f...)
+	}
+	return findings, nil
+}
+
+// daemonDrift compares daemon.json with the configuration, for the docker
+// flavor of the node
+func daemonDrift(kubicConfig *config.KubicInitConfiguration) ([]drift.Finding, error) {
+	return daemon.Drift(kubicConfig, daemon.ResolveCompat(kubicConfig))
+}
+
+func target(f drift.Finding) string {
+	if f.Key == "" {
+		return f.Path
+	}
+	return f.Path + " " + f.Key
+}
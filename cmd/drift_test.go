@@ -0,0 +1,107 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runDrift(t *testing.T) {
+	defer func(checks []func(*config.KubicInitConfiguration) ([]drift.Finding, error), f func(*config.KubicInitConfiguration) error, file string, fix bool, status int) {
+		driftChecks, remediateDrift, cfgFile, driftFix, exitStatus = checks, f, file, fix, status
+	}(driftChecks, remediateDrift, cfgFile, driftFix, exitStatus)
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-drift")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cfgFile = filepath.Join(tmpDir, "kubic-init.yaml")
+	if err := ioutil.WriteFile(cfgFile, []byte("runtime:\n  engine: docker\n"), 0644); err != nil {
+		t.Fatalf("writing %s: %s", cfgFile, err)
+	}
+
+	owned := drift.Finding{Path: "/etc/docker/daemon.json", Key: "log-level", Owner: drift.Owned, Kind: drift.Modified}
+	foreign := drift.Finding{Path: "/etc/docker/daemon.json", Key: "debug", Owner: drift.Foreign, Kind: drift.Extra}
+	cert := drift.Finding{Path: "/etc/docker/certs.d/mirror.com/ca.crt", Owner: drift.Owned, Kind: drift.Missing}
+	tests := []struct {
+		name         string
+		fix          bool
+		findings     []drift.Finding
+		after        []drift.Finding
+		err          error
+		wantOut      string
+		wantStatus   int
+		wantRemedied bool
+	}{
+		{"ok", false, nil, nil, nil, "DRIFT OK - files match the configuration | owned=0 foreign=0 fixed=0\n", 0, false},
+		{"warning", true, []drift.Finding{foreign}, nil, nil, `DRIFT WARNING - 0 owned, 1 foreign differences | owned=0 foreign=1 fixed=0
+foreign  extra     /etc/docker/daemon.json debug
+`, 1, false},
+		{"critical", false, []drift.Finding{foreign, owned, cert}, nil, nil, `DRIFT CRITICAL - 2 owned, 1 foreign differences | owned=2 foreign=1 fixed=0
+foreign  extra     /etc/docker/daemon.json debug
+owned    modified  /etc/docker/daemon.json log-level
+owned    missing   /etc/docker/certs.d/mirror.com/ca.crt
+`, 2, false},
+		{"fixed", true, []drift.Finding{foreign, owned}, []drift.Finding{foreign}, nil, `DRIFT WARNING - 0 owned, 1 foreign differences | owned=0 foreign=1 fixed=1
+foreign  extra     /etc/docker/daemon.json debug
+fixed    modified  /etc/docker/daemon.json log-level
+`, 1, true},
+		{"unknown", false, nil, nil, errors.New("permission denied"), "DRIFT UNKNOWN - permission denied\n", 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			driftFix = tt.fix
+			remedied := false
+			remediateDrift = func(*config.KubicInitConfiguration) error {
+				remedied = true
+				return nil
+			}
+			driftChecks = []func(*config.KubicInitConfiguration) ([]drift.Finding, error){
+				func(*config.KubicInitConfiguration) ([]drift.Finding, error) {
+					if remedied {
+						return tt.after, nil
+					}
+					return tt.findings, tt.err
+				},
+			}
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			if err := runDrift(c, nil); err != nil {
+				t.Fatalf("runDrift() error = %v", err)
+			}
+			if out.String() != tt.wantOut {
+				t.Errorf("runDrift() output = %q, want %q", out.String(), tt.wantOut)
+			}
+			if exitStatus != tt.wantStatus {
+				t.Errorf("runDrift() exit status = %d, want %d", exitStatus, tt.wantStatus)
+			}
+			if remedied != tt.wantRemedied {
+				t.Errorf("runDrift() remediated = %v, want %v", remedied, tt.wantRemedied)
+			}
+		})
+	}
+}
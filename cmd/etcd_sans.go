@@ -0,0 +1,98 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/etcd"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	etcdSANsLongDescription = `Print the Subject Alternative Names of the etcd certificates.
+
+usage:
+
+$ caasp-init etcd-sans --format kubeadm
+
+The 'etcd.local.serverCertSANs' and 'etcd.local.peerCertSANs' entries are
+checked to be IP addresses or DNS names and, with 'etcd.local.autoSANs', the
+name, the bind address and the external FQDN of the node are added to them.
+The duplicates are removed.
+
+The names of the server certificate are printed one per line, the ones of
+the peer certificate with '--peer', for generating the etcd certificates
+locally. With '--format kubeadm' both lists are printed as the 'etcd.local'
+section of a kubeadm ClusterConfiguration.
+`
+)
+
+var (
+	etcdSANsFormat string
+	etcdSANsPeer   bool
+)
+
+// newEtcdSANsCmd represents the etcd-sans command
+func newEtcdSANsCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "etcd-sans",
+		Short: "Print the Subject Alternative Names of the etcd certificates",
+		Long:  etcdSANsLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runEtcdSANs,
+	}
+	c.Flags().StringVar(&etcdSANsFormat, "format", "list", "output format: list or kubeadm")
+	c.Flags().BoolVar(&etcdSANsPeer, "peer", false, "print the names of the peer certificate instead of the server one")
+	return c
+}
+
+func runEtcdSANs(cmd *cobra.Command, args []string) error {
+	if etcdSANsFormat != "list" && etcdSANsFormat != "kubeadm" {
+		return fmt.Errorf("unknown format \"%s\", use list or kubeadm", etcdSANsFormat)
+	}
+
+	kubicConfig, err := loadRoleConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+	sans, err := etcd.Compute(kubicConfig)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	if etcdSANsFormat == "list" {
+		list := sans.Server
+		if etcdSANsPeer {
+			list = sans.Peer
+		}
+		for _, san := range list {
+			fmt.Fprintln(w, san)
+		}
+		return nil
+	}
+	b, err := yaml.Marshal(map[string]interface{}{
+		"etcd": map[string]interface{}{"local": sans},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
@@ -0,0 +1,75 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runEtcdSANs(t *testing.T) {
+	defer func(f, format string, peer bool) {
+		cfgFile, etcdSANsFormat, etcdSANsPeer = f, format, peer
+	}(cfgFile, etcdSANsFormat, etcdSANsPeer)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-etcd-sans")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(tmpDir, name+".yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		return path
+	}
+	sans := writeConfig("sans", "etcd:\n  local:\n    serverCertSANs:\n    - etcd.example.com\n    - Etcd.Example.com\n    - 192.168.1.11\n    peerCertSANs:\n    - etcd-0.example.com\n")
+	invalid := writeConfig("invalid", "etcd:\n  local:\n    serverCertSANs:\n    - etcd_0\n")
+
+	tests := []struct {
+		name       string
+		configFile string
+		format     string
+		peer       bool
+		want       string
+		wantErr    bool
+	}{
+		{"server", sans, "list", false, "etcd.example.com\n192.168.1.11\n", false},
+		{"peer", sans, "list", true, "etcd-0.example.com\n", false},
+		{"kubeadm", sans, "kubeadm", false, "etcd:\n  local:\n    serverCertSANs:\n    - etcd.example.com\n    - 192.168.1.11\n    peerCertSANs:\n    - etcd-0.example.com\n", false},
+		{"unknown_format", sans, "json", false, "", true},
+		{"invalid", invalid, "list", false, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			cfgFile, etcdSANsFormat, etcdSANsPeer = tt.configFile, tt.format, tt.peer
+			if err := runEtcdSANs(c, []string{}); (err != nil) != tt.wantErr {
+				t.Fatalf("runEtcdSANs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runEtcdSANs() output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	explainLongDescription = `Describe the fields of the kubic-init.yaml configuration file.
+
+usage:
+
+$ caasp-init explain bootstrap.registries[].mirrors[].hashalgorithm
+
+Prints the type, the default value, the allowed values and the description
+of the field. For objects the list of its fields is printed too.
+
+Without a field path the whole configuration is described.
+`
+)
+
+// newExplainCmd represents the explain command
+func newExplainCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "explain [field.path]",
+		Short: "Show the documentation of a configuration field",
+		Long:  explainLongDescription,
+		Args:  cobra.MaximumNArgs(1),
+		RunE:  runExplain,
+	}
+}
+
+func runExplain(cmd *cobra.Command, args []string) error {
+	path := ""
+	if len(args) > 0 {
+		path = args[0]
+	}
+
+	info, err := config.Explain(path)
+	if err != nil {
+		return err
+	}
+
+	printFieldInfo(cmd.OutOrStdout(), info)
+	return nil
+}
+
+func printFieldInfo(w io.Writer, info *config.FieldInfo) {
+	fmt.Fprintln(w, "KIND:     KubicInitConfiguration")
+	if info.Path != "" {
+		fmt.Fprintf(w, "FIELD:    %s <%s>\n", info.Name, info.Type)
+		fmt.Fprintf(w, "PATH:     %s\n", info.Path)
+	}
+	if info.Doc.Default != "" {
+		fmt.Fprintf(w, "DEFAULT:  %s\n", info.Doc.Default)
+	}
+	if len(info.Doc.Allowed) > 0 {
+		fmt.Fprintf(w, "ALLOWED:  %s\n", strings.Join(info.Doc.Allowed, ", "))
+	}
+
+	fmt.Fprintln(w, "\nDESCRIPTION:")
+	fmt.Fprintf(w, "     %s\n", info.Doc.Description)
+
+	if len(info.Fields) == 0 {
+		return
+	}
+	fmt.Fprintln(w, "\nFIELDS:")
+	for _, f := range info.Fields {
+		fmt.Fprintf(w, "   %s\t<%s>\n", f.Name, f.Type)
+		fmt.Fprintf(w, "     %s\n\n", f.Doc.Description)
+	}
+}
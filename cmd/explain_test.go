@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runExplain(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    []string
+		wantErr bool
+	}{
+		{"root", []string{}, []string{"KIND:", "bootstrap\t<Object>"}, false},
+		{"field", []string{"runtime.engine"}, []string{"FIELD:    engine <string>", "DEFAULT:  docker", "ALLOWED:  docker, crio"}, false},
+		{"unknown", []string{"runtime.foo"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			if err := runExplain(c, tt.args); (err != nil) != tt.wantErr {
+				t.Errorf("runExplain() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(out.String(), w) {
+					t.Errorf("runExplain() output %q does not contain %q", out.String(), w)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/kubeadm"
+	"github.com/kubic-project/caasp-init/pkg/log"
+	"github.com/kubic-project/caasp-init/pkg/oidc"
+	"github.com/kubic-project/caasp-init/pkg/token"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	kubeadmConfigLongDescription = `Render the kubeadm configuration of the node.
+
+usage:
+
+$ caasp-init kubeadm-config
+
+The role of the node is detected from the seeder, '--role' overrides it.
+For the seeder the InitConfiguration and ClusterConfiguration documents are
+rendered, with the subnets, the DNS domain, the external FQDN, the etcd SANs,
+the OIDC flags of the API server and the bootstrap token. For a joining node
+the JoinConfiguration document is rendered, with the seeder, the token and
+the CA hash: the CA published by the seeder is fetched and verified against
+'certificates.caCrtHash' first. Without the hash the node cannot join,
+unless 'certificates.unsafeSkipCAVerification' is set.
+
+The configuration is printed, or written to the file given with '--output',
+readable only by its owner as it contains the bootstrap token.
+`
+)
+
+var (
+	kubeadmOutput string
+
+	// fetchSeederCA fetches the CA of the seeder, verified against the hash
+	fetchSeederCA = certs.FetchSeederCA
+
+	// resolveToken sets the bootstrap token, generating it on the seeder
+	resolveToken = token.Resolve
+)
+
+// newKubeadmConfigCmd represents the kubeadm-config command
+func newKubeadmConfigCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "kubeadm-config",
+		Short: "Render the kubeadm configuration of the node",
+		Long:  kubeadmConfigLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runKubeadmConfig,
+	}
+	c.Flags().StringVarP(&kubeadmOutput, "output", "o", "", "write the configuration to this file instead of printing it")
+	return c
+}
+
+func runKubeadmConfig(cmd *cobra.Command, args []string) error {
+	kubicConfig, err := loadRoleConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+	seeder := kubicConfig.ClusterFormation.Role == config.RoleSeeder
+	if seeder {
+		if err := oidc.CheckCA(kubicConfig.Auth.OIDC); err != nil {
+			return err
+		}
+	}
+	if !seeder && kubicConfig.Certificates.CaHash != "" {
+		// the seeder is checked before handing its endpoint to kubeadm
+		ca, err := fetchSeederCA(kubicConfig)
+		if err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{"seeder": kubicConfig.ClusterFormation.Seeder, "subject": ca.Subject.String()}).Infof("CA of the seeder verified")
+	}
+	fsutil.ResetChanges()
+	err = resolveToken(kubicConfig, seeder)
+	if err != nil {
+		return err
+	}
+	// the token kept on the node is recorded, so that reset removes it
+	err = recordFiles(fsutil.Changes(), fsutil.Dirs(), version)
+	if err != nil {
+		return err
+	}
+	content, err := kubeadm.Render(kubicConfig, seeder)
+	if err != nil {
+		return err
+	}
+
+	if kubeadmOutput == "" {
+		_, err = cmd.OutOrStdout().Write(content)
+		return err
+	}
+	_, err = fsutil.WriteFile(kubeadmOutput, content, os.FileMode(0600))
+	return err
+}
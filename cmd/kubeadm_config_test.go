@@ -0,0 +1,150 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runKubeadmConfig(t *testing.T) {
+	defer func(f, role, output string, fetch func(*config.KubicInitConfiguration) (*x509.Certificate, error)) {
+		cfgFile, nodeRole, kubeadmOutput, fetchSeederCA = f, role, output, fetch
+	}(cfgFile, nodeRole, kubeadmOutput, fetchSeederCA)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-kubeadm")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	seederHash := "sha256:" + strings.Repeat("0", 64)
+	fetchSeederCA = func(cfg *config.KubicInitConfiguration) (*x509.Certificate, error) {
+		if cfg.Certificates.CaHash != seederHash {
+			return nil, errors.New("the CA of the seeder has another hash")
+		}
+		return &x509.Certificate{Subject: pkix.Name{CommonName: "kubernetes"}}, nil
+	}
+
+	writeConfig := func(name, certificates string) string {
+		path := filepath.Join(tmpDir, name)
+		content := "clusterFormation:\n  seeder: seeder.example.com\n  token: 94dcda.c271f4ff502789ca\nnetwork:\n  dns:\n    externalFqdn: k8s.example.com\n" + certificates
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		return path
+	}
+	noHash := writeConfig("no-hash.yaml", "")
+	unsafeSkip := writeConfig("unsafe-skip.yaml", "certificates:\n  unsafeSkipCAVerification: true\n")
+	withHash := writeConfig("hash.yaml", "certificates:\n  caCrtHash: "+seederHash+"\n")
+	otherHash := writeConfig("other-hash.yaml", "certificates:\n  caCrtHash: sha256:"+strings.Repeat("f", 64)+"\n")
+
+	tests := []struct {
+		name    string
+		cfg     string
+		role    string
+		output  string
+		want    string
+		wantErr bool
+	}{
+		{"seeder", noHash, "seeder", "", "controlPlaneEndpoint: k8s.example.com:6443\n", false},
+		{"join", withHash, "join", "", "- " + seederHash + "\n", false},
+		{"join_other_ca", otherHash, "join", "", "", true},
+		{"join_no_ca_hash", noHash, "join", "", "", true},
+		{"join_unsafe_skip", unsafeSkip, "join", "", "unsafeSkipCAVerification: true\n", false},
+		{"output", withHash, "join", filepath.Join(tmpDir, "kubeadm.yaml"), "", false},
+		{"unknown_role", noHash, "master", "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			cfgFile, nodeRole, kubeadmOutput = tt.cfg, tt.role, tt.output
+			if err := runKubeadmConfig(c, []string{}); (err != nil) != tt.wantErr {
+				t.Fatalf("runKubeadmConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("runKubeadmConfig() output %q does not contain %q", out.String(), tt.want)
+			}
+			if tt.output == "" {
+				return
+			}
+			info, err := os.Stat(tt.output)
+			if err != nil {
+				t.Fatalf("runKubeadmConfig() did not write %s: %v", tt.output, err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("runKubeadmConfig() wrote %s with mode %04o, want 0600", tt.output, info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func Test_runKubeadmConfigRecordsToken(t *testing.T) {
+	defer func(f, role, output string) {
+		cfgFile, nodeRole, kubeadmOutput = f, role, output
+	}(cfgFile, nodeRole, kubeadmOutput)
+	defer func(resolve func(*config.KubicInitConfiguration, bool) error, record func([]fsutil.Change, []string, string) error) {
+		resolveToken, recordFiles = resolve, record
+	}(resolveToken, recordFiles)
+	defer fsutil.ResetChanges()
+	tmpDir, err := ioutil.TempDir("", "caasp-init-kubeadm")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfgFile = filepath.Join(tmpDir, "kubic-init.yaml")
+	if err := ioutil.WriteFile(cfgFile, []byte("network:\n  dns:\n    externalFqdn: k8s.example.com\n"), 0644); err != nil {
+		t.Fatalf("writing %s: %s", cfgFile, err)
+	}
+	nodeRole, kubeadmOutput = "seeder", ""
+
+	// the token generated on the seeder is kept in a file
+	tokenFile := filepath.Join(tmpDir, "token")
+	resolveToken = func(cfg *config.KubicInitConfiguration, seeder bool) error {
+		cfg.ClusterFormation.Token = "94dcda.c271f4ff502789ca"
+		_, err := fsutil.WriteFile(tokenFile, []byte(cfg.ClusterFormation.Token+"\n"), 0600)
+		return err
+	}
+	var recorded []string
+	recordFiles = func(changes []fsutil.Change, dirs []string, version string) error {
+		for _, change := range changes {
+			recorded = append(recorded, change.Path)
+		}
+		return nil
+	}
+
+	c := &cobra.Command{}
+	c.SetOutput(&bytes.Buffer{})
+	if err := runKubeadmConfig(c, []string{}); err != nil {
+		t.Fatalf("runKubeadmConfig() error = %v", err)
+	}
+	if len(recorded) != 1 || recorded[0] != tokenFile {
+		t.Errorf("runKubeadmConfig() recorded %q, want the token file %s", recorded, tokenFile)
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/kubeadm"
+	"github.com/kubic-project/caasp-init/pkg/log"
+	"github.com/kubic-project/caasp-init/pkg/oidc"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	oidcLongDescription = `Render the OpenID Connect flags of the API server.
+
+usage:
+
+$ caasp-init oidc --format kubeadm
+
+The 'auth.OIDC' settings are checked, the CA file included, and rendered as
+the '--oidc-*' flags of kube-apiserver, one per line, or with
+'--format kubeadm' as the 'apiServer.extraArgs' of a kubeadm
+ClusterConfiguration.
+
+With '--discover' the OpenID provider metadata of the issuer is fetched,
+trusting the configured CA, and checked to publish the configured issuer.
+`
+)
+
+var (
+	oidcFormat   string
+	oidcDiscover bool
+)
+
+// newOIDCCmd represents the oidc command
+func newOIDCCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "oidc",
+		Short: "Render the OpenID Connect flags of the API server",
+		Long:  oidcLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runOIDC,
+	}
+	c.Flags().StringVar(&oidcFormat, "format", "flags", "output format: flags or kubeadm")
+	c.Flags().BoolVar(&oidcDiscover, "discover", false, "check the OpenID provider metadata published by the issuer")
+	return c
+}
+
+func runOIDC(cmd *cobra.Command, args []string) error {
+	if oidcFormat != "flags" && oidcFormat != "kubeadm" {
+		return fmt.Errorf("unknown format \"%s\", use flags or kubeadm", oidcFormat)
+	}
+
+	kubicConfig, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+	settings := kubicConfig.Auth.OIDC
+	if !oidc.Enabled(settings) {
+		return errors.New("auth.OIDC.issuer is not set, OpenID Connect is disabled")
+	}
+	if err := oidc.CheckCA(settings); err != nil {
+		return err
+	}
+	if oidcDiscover {
+		metadata, err := oidc.Discover(settings)
+		if err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{"issuer": metadata.Issuer, "jwks_uri": metadata.JwksURI}).Infof("OpenID issuer discovered")
+	}
+
+	w := cmd.OutOrStdout()
+	if oidcFormat == "flags" {
+		for _, flag := range oidc.Flags(settings) {
+			fmt.Fprintln(w, flag)
+		}
+		return nil
+	}
+	b, err := yaml.Marshal(struct {
+		APIServer kubeadm.APIServer `yaml:"apiServer"`
+	}{kubeadm.APIServer{ExtraArgs: oidc.Args(settings)}})
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
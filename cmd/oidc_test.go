@@ -0,0 +1,75 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runOIDC(t *testing.T) {
+	defer func(f, format string, discover bool) {
+		cfgFile, oidcFormat, oidcDiscover = f, format, discover
+	}(cfgFile, oidcFormat, oidcDiscover)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-oidc")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(tmpDir, name+".yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		return path
+	}
+	enabled := writeConfig("enabled", "auth:\n  OIDC:\n    issuer: https://dex.example.com\n    clientID: kubernetes\n    username: email\n    groups: groups\n")
+	missingCA := writeConfig("missing_ca", "auth:\n  OIDC:\n    issuer: https://dex.example.com\n    clientID: kubernetes\n    username: email\n    groups: groups\n    ca: "+filepath.Join(tmpDir, "missing.crt")+"\n")
+	disabled := writeConfig("disabled", "auth: {}\n")
+
+	tests := []struct {
+		name       string
+		configFile string
+		format     string
+		want       string
+		wantErr    bool
+	}{
+		{"flags", enabled, "flags", "--oidc-client-id=kubernetes\n--oidc-groups-claim=groups\n--oidc-issuer-url=https://dex.example.com\n--oidc-username-claim=email\n", false},
+		{"kubeadm", enabled, "kubeadm", "apiServer:\n  extraArgs:\n    oidc-client-id: kubernetes\n    oidc-groups-claim: groups\n    oidc-issuer-url: https://dex.example.com\n    oidc-username-claim: email\n", false},
+		{"unknown_format", enabled, "json", "", true},
+		{"missing_ca", missingCA, "flags", "", true},
+		{"disabled", disabled, "flags", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			cfgFile, oidcFormat, oidcDiscover = tt.configFile, tt.format, false
+			if err := runOIDC(c, []string{}); (err != nil) != tt.wantErr {
+				t.Fatalf("runOIDC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runOIDC() output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/pki"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	pkiInitLongDescription = `Generate the certificate authorities of the cluster.
+
+usage:
+
+$ caasp-init pki init --key-type ecdsa
+
+Generates in the certificates directory, with the layout of kubeadm:
+
+  ca.crt, ca.key                          the cluster CA
+  front-proxy-ca.crt, front-proxy-ca.key  the front proxy CA
+  etcd/ca.crt, etcd/ca.key                the etcd CA
+  sa.key, sa.pub                          the key pair of the service accounts
+
+The keys are readable only by root. Nothing is written when one of the files
+already exists, unless '--force' is given. The hash of the cluster CA is
+printed, for setting 'certificates.caCrtHash' on the joining nodes.
+`
+)
+
+var pkiOptions pki.Options
+
+// newPKICmd represents the pki command
+func newPKICmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "pki",
+		Short: "Manage the certificate authorities of the cluster",
+	}
+
+	pkiInit := &cobra.Command{
+		Use:   "init",
+		Short: "Generate the certificate authorities of the cluster",
+		Long:  pkiInitLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runPKIInit,
+	}
+	pkiInit.Flags().StringVar(&pkiOptions.KeyType, "key-type", pki.KeyTypeRSA, "type of the keys: rsa or ecdsa")
+	pkiInit.Flags().IntVar(&pkiOptions.KeySize, "key-size", 0, "bits of the RSA keys or of the ECDSA curve (default 2048 for rsa, 256 for ecdsa)")
+	pkiInit.Flags().DurationVar(&pkiOptions.Validity, "validity", pki.DefaultValidity, "validity of the CA certificates")
+	pkiInit.Flags().BoolVar(&pkiOptions.Force, "force", false, "overwrite the existing certificates and keys")
+	c.AddCommand(pkiInit)
+
+	return c
+}
+
+func runPKIInit(cmd *cobra.Command, args []string) error {
+	kubicConfig, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+	written, err := pki.Init(kubicConfig.Certificates.Directory, pkiOptions)
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	for _, path := range written {
+		fmt.Fprintf(w, "written %s\n", path)
+	}
+	ca, err := certs.LoadCA(kubicConfig)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "CA hash: %s, valid until %s\n", certs.CAHash(ca), ca.NotAfter.Format(time.RFC3339))
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/pki"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runPKIInit(t *testing.T) {
+	defer func(f string, opts pki.Options) { cfgFile, pkiOptions = f, opts }(cfgFile, pkiOptions)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-pki")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	pkiDir := filepath.Join(tmpDir, "pki")
+	cfgFile = filepath.Join(tmpDir, "kubic-init.yaml")
+	if err := ioutil.WriteFile(cfgFile, []byte(fmt.Sprintf("certificates:\n  directory: %s\n", pkiDir)), 0644); err != nil {
+		t.Fatalf("writing %s: %s", cfgFile, err)
+	}
+
+	tests := []struct {
+		name    string
+		opts    pki.Options
+		want    string
+		wantErr bool
+	}{
+		{"generated", pki.Options{KeyType: "ecdsa", Validity: time.Hour}, "CA hash: sha256:", false},
+		{"existing", pki.Options{KeyType: "ecdsa", Validity: time.Hour}, "", true},
+		{"forced", pki.Options{KeyType: "ecdsa", Validity: time.Hour, Force: true}, "written " + filepath.Join(pkiDir, "etcd", "ca.key"), false},
+		{"invalid_size", pki.Options{KeyType: "rsa", KeySize: 512, Force: true}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			pkiOptions = tt.opts
+			if err := runPKIInit(c, []string{}); (err != nil) != tt.wantErr {
+				t.Fatalf("runPKIInit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("runPKIInit() output %q does not contain %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/kubic-project/caasp-init/pkg/state"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	resetLongDescription = `Remove everything caasp-init created on the node.
+
+usage:
+
+$ caasp-init reset
+
+Every file written by caasp-init is recorded in '/var/lib/caasp-init/state.json'
+along with a backup of its content before caasp-init first wrote it. The files
+that existed before are restored from their backup, the others are removed.
+The directories caasp-init created, like the 'certs.d' ones, are removed once
+empty. The manifest and the backups are removed too.
+
+With '--dry-run' the actions are only printed.
+
+The container runtime has to be restarted afterwards to forget the mirrors.
+`
+)
+
+var (
+	resetDryRun bool
+
+	// resetState removes the files managed by caasp-init
+	resetState = state.Reset
+)
+
+// newResetCmd represents the reset command
+func newResetCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "reset",
+		Short: "Remove everything caasp-init created on the node",
+		Long:  resetLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runReset,
+	}
+	c.Flags().BoolVar(&resetDryRun, "dry-run", false, "only print the actions")
+	return c
+}
+
+func runReset(cmd *cobra.Command, args []string) error {
+	steps, err := resetState(resetDryRun)
+
+	w := cmd.OutOrStdout()
+	verb := map[string]string{
+		state.StepRestored: "restored",
+		state.StepRemoved:  "removed",
+		state.StepMissing:  "already removed",
+	}
+	if resetDryRun {
+		verb[state.StepRestored] = "would restore"
+		verb[state.StepRemoved] = "would remove"
+	}
+	counts := map[string]int{}
+	for _, step := range steps {
+		fmt.Fprintf(w, "%-16s %s\n", verb[step.Action], step.Path)
+		counts[step.Action]++
+	}
+	if err != nil {
+		return err
+	}
+
+	if len(steps) == 0 {
+		fmt.Fprintln(w, "nothing to reset")
+		return nil
+	}
+	fmt.Fprintf(w, "%d restored, %d removed, %d already removed\n",
+		counts[state.StepRestored], counts[state.StepRemoved], counts[state.StepMissing])
+	return nil
+}
@@ -0,0 +1,79 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/state"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runReset(t *testing.T) {
+	defer func(f func(bool) ([]state.Step, error), dryRun bool) {
+		resetState, resetDryRun = f, dryRun
+	}(resetState, resetDryRun)
+
+	steps := []state.Step{
+		{Path: "/etc/docker/certs.d/mirror.com/ca.crt", Action: state.StepRemoved},
+		{Path: "/etc/docker/daemon.json", Action: state.StepRestored},
+		{Path: "/etc/containers/auth.json", Action: state.StepMissing},
+		{Path: "/etc/docker/certs.d/mirror.com", Action: state.StepRemoved},
+	}
+	tests := []struct {
+		name    string
+		dryRun  bool
+		steps   []state.Step
+		err     error
+		want    string
+		wantErr bool
+	}{
+		{"reset", false, steps, nil, `removed          /etc/docker/certs.d/mirror.com/ca.crt
+restored         /etc/docker/daemon.json
+already removed  /etc/containers/auth.json
+removed          /etc/docker/certs.d/mirror.com
+1 restored, 2 removed, 1 already removed
+`, false},
+		{"dry_run", true, steps[:2], nil, `would remove     /etc/docker/certs.d/mirror.com/ca.crt
+would restore    /etc/docker/daemon.json
+1 restored, 1 removed, 0 already removed
+`, false},
+		{"nothing", false, nil, nil, "nothing to reset\n", false},
+		{"error", false, steps[:1], errors.New("permission denied"), "removed          /etc/docker/certs.d/mirror.com/ca.crt\n", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resetDryRun = tt.dryRun
+			resetState = func(dryRun bool) ([]state.Step, error) {
+				if dryRun != tt.dryRun {
+					t.Errorf("reset with dryRun = %v, want %v", dryRun, tt.dryRun)
+				}
+				return tt.steps, tt.err
+			}
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			if err := runReset(c, nil); (err != nil) != tt.wantErr {
+				t.Fatalf("runReset() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if out.String() != tt.want {
+				t.Errorf("runReset() output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -19,18 +19,51 @@ import (
 	"os"
 
 	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/cni"
 	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/credentials"
 	"github.com/kubic-project/caasp-init/pkg/daemon"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/log"
+	"github.com/kubic-project/caasp-init/pkg/netutil"
+	"github.com/kubic-project/caasp-init/pkg/notify"
+	"github.com/kubic-project/caasp-init/pkg/registries"
+	"github.com/kubic-project/caasp-init/pkg/reload"
+	"github.com/kubic-project/caasp-init/pkg/report"
+	"github.com/kubic-project/caasp-init/pkg/role"
+	"github.com/kubic-project/caasp-init/pkg/state"
 
 	"github.com/spf13/cobra"
 )
 
 var (
 	cfgFile              string
+	logLevel             string
+	logFormat            string
+	reloadRuntime        bool
+	detailedExitCode     bool
+	reportFile           string
+	nodeRole             string
+	exitStatus           int
 	registryConfigFolder = "/etc/docker"
+
+	// reloadUnit reloads or restarts a systemd unit
+	reloadUnit = reload.Unit
+
+	// writeFiles writes the files of the configuration
+	writeFiles = writeConfigFiles
+
+	// recordFiles records the files written by a failed run in the state
+	recordFiles = state.RecordFiles
+
+	// interfaces lists the network interfaces of the node
+	interfaces netutil.InterfaceLister = netutil.SystemLister{}
 )
 
 const (
+	// exitChanges is the exit status with `--detailed-exitcode` when files changed
+	exitChanges = 2
+
 	longDescription = `Set the initial docker daemon mirrors configuration.
 
 usage:
@@ -70,6 +103,22 @@ If there is no mirror declared the configuration file will just be the default:
     "log-level": "warn"
     }
 
+Mirrors declared with 'insecure: true' are added to the "insecure-registries".
+When the runtime engine is crio the mirrors are also written to
+'/etc/containers/registries.conf.d/50-caasp-init.conf'.
+
+With '--reload' the container runtime is told about the changes through
+systemd, only when a file changed: docker is reloaded (SIGHUP) when only the
+mirrors changed and restarted otherwise, CRI-O is restarted.
+
+The files are only written when their content changed. With
+'--detailed-exitcode' the exit status is 0 when nothing changed, 2 when files
+changed and 1 on errors.
+
+With '--report=<path>' a JSON report of the run is written, even when it
+fails: the configuration files loaded, the registries, every file managed
+with its SHA-256 checksum, the certificates installed and the warnings.
+
 For help use 'caasp-init help'
 `
 )
@@ -80,42 +129,250 @@ var rootCmd = &cobra.Command{
 	Short: "Set the initial docker daemon mirrors configuration.",
 	Long:  longDescription,
 	RunE:  runE,
+
+	PersistentPreRunE: setupLogging,
+	// the errors are logged by Execute
+	SilenceErrors: true,
+}
+
+// setupLogging configures the logger from the `--log-*` flags
+func setupLogging(cmd *cobra.Command, args []string) error {
+	err := log.SetLevel(logLevel)
+	if err != nil {
+		return err
+	}
+	return log.SetFormat(logFormat)
 }
 
 func runE(cmd *cobra.Command, args []string) error {
-	err := os.MkdirAll(registryConfigFolder, 0644)
+	fsutil.ResetChanges()
+	log.ResetWarnings()
+
+	kubicConfig, err := loadConfig(cfgFile)
+	if err == nil {
+		err = applyConfig(kubicConfig)
+	}
+
+	if reportFile != "" {
+		r := report.New(cfgFile, kubicConfig, fsutil.Changes(), log.Warnings(), err)
+		if reportErr := report.Write(r, reportFile); reportErr != nil && err == nil {
+			err = fmt.Errorf("unable to write the report: %v", reportErr)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	notify.Ready("configuration applied")
+	if detailedExitCode && fsutil.Changed() {
+		exitStatus = exitChanges
+	}
+	return nil
+}
+
+// loadConfig loads and validates the configuration file at `cfgPath`. The
+// role of the node is only detected, when it is not set, to apply the roles
+// sections of the configuration.
+func loadConfig(cfgPath string) (*config.KubicInitConfiguration, error) {
+	return loadNodeConfig(cfgPath, false)
+}
+
+// loadRoleConfig loads the configuration like loadConfig, for the commands
+// rendering the configuration of the node by role, which always need it
+func loadRoleConfig(cfgPath string) (*config.KubicInitConfiguration, error) {
+	return loadNodeConfig(cfgPath, true)
+}
+
+// loadNodeConfig loads and validates the configuration file at `cfgPath`,
+// detecting the role of the node when it is not set and `needRole` or the
+// configuration has roles sections. The detection may resolve the seeder, so
+// its failure only drops the roles sections when the role is not needed.
+func loadNodeConfig(cfgPath string, needRole bool) (*config.KubicInitConfiguration, error) {
+	kubicConfig, err := config.FileAndDefaultsToKubicInitConfig(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+
+	if nodeRole != "" {
+		kubicConfig.ClusterFormation.Role = nodeRole
+	}
+	r := kubicConfig.ClusterFormation.Role
+	if r == "" && (needRole || len(kubicConfig.Roles) > 0) {
+		r, err = role.Detect(kubicConfig, interfaces)
+		if err != nil {
+			if needRole {
+				return nil, err
+			}
+			log.Warnf("unable to detect the role of the node, the roles sections are not applied: %v", err)
+			kubicConfig.Roles = nil
+			r = ""
+		}
+	}
+	if r != "" {
+		err = config.ApplyRole(kubicConfig, r)
+		if err != nil {
+			return nil, err
+		}
+		log.WithFields(log.Fields{"seeder": kubicConfig.ClusterFormation.Seeder}).Debugf("node role %s", r)
+	}
+
+	for _, secret := range config.Secrets(kubicConfig) {
+		log.AddSecret(secret)
+	}
+
+	err = config.Validate(kubicConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	err = netutil.ResolveBind(kubicConfig, interfaces)
+	if err != nil {
+		return nil, err
+	}
+	if kubicConfig.Network.Bind.Address != "" {
+		log.WithFields(log.Fields{"interface": kubicConfig.Network.Bind.Interface}).Debugf("bind address %s", kubicConfig.Network.Bind.Address)
+	}
+	return kubicConfig, nil
+}
+
+// apply writes the configuration of the container runtime, reloading it
+// with `--reload`
+func apply(kubicConfig *config.KubicInitConfiguration) error {
+	fsutil.ResetChanges()
+
+	warnings, err := netutil.CheckSubnets(kubicConfig, interfaces)
+	if err != nil {
+		return err
+	}
+	for _, w := range warnings {
+		log.Warnf("%s", w)
+	}
+	err = cni.CheckPlugins(kubicConfig)
 	if err != nil {
 		return err
 	}
 
-	kubicConfig, err := config.FileAndDefaultsToKubicInitConfig(cfgFile)
+	manifest, err := state.Load()
+	if err != nil {
+		return err
+	}
+	previous, err := manifest.Config()
+	if err != nil {
+		return err
+	}
+	staleCerts, err := certs.Stale(kubicConfig, previous)
 	if err != nil {
 		return err
 	}
 
-	err = daemon.WriteConfigFile(kubicConfig)
+	err = writeFiles(kubicConfig, previous)
 	if err != nil {
+		// the files written before the failure are recorded with their
+		// backup, so that the next run does not take them as the original
+		if recordErr := recordFiles(fsutil.Changes(), fsutil.Dirs(), version); recordErr != nil {
+			log.Errorf("unable to record the files written: %v", recordErr)
+		}
 		return err
 	}
 
-	err = certs.WriteCertificates(kubicConfig)
+	err = state.Record(fsutil.Changes(), fsutil.Dirs(), cfgFile, kubicConfig, version)
 	if err != nil {
 		return err
 	}
 
+	// the certificates of the removed mirrors are released once the files
+	// written are recorded, so that a restored backup is not managed again
+	_, err = state.Release(staleCerts)
+	if err != nil {
+		return err
+	}
+
+	if reloadRuntime {
+		return reloadEngine(kubicConfig.Runtime.Engine, fsutil.Changes())
+	}
 	return nil
 }
 
+// writeConfigFiles writes the files of the configuration `kubicConfig`,
+// removing the ones the `previous` configuration applied no longer needs
+func writeConfigFiles(kubicConfig, previous *config.KubicInitConfiguration) error {
+	err := fsutil.MkdirAll(registryConfigFolder, 0644)
+	if err != nil {
+		return err
+	}
+
+	err = daemon.WriteConfigFile(kubicConfig, daemon.ResolveCompat(kubicConfig))
+	if err != nil {
+		return err
+	}
+
+	err = registries.WriteConfigFile(kubicConfig)
+	if err != nil {
+		return err
+	}
+
+	err = certs.WriteCertificates(kubicConfig)
+	if err != nil {
+		return err
+	}
+
+	err = credentials.WriteCredentials(kubicConfig)
+	if err != nil {
+		return err
+	}
+
+	err = credentials.RemoveStale(kubicConfig, previous)
+	if err != nil {
+		return err
+	}
+
+	return cni.WriteConfigFile(kubicConfig)
+}
+
+// reloadEngine reloads or restarts the unit of the runtime `engine` when the
+// changes made to its configuration require it
+func reloadEngine(engine string, changes []fsutil.Change) error {
+	unit, action := daemon.Unit, daemon.ReloadAction(changes)
+	if engine == "crio" {
+		unit, action = registries.Unit, registries.ReloadAction(changes)
+	}
+	if action == reload.None {
+		log.Debugf("%s configuration unchanged", unit)
+		return nil
+	}
+	log.WithFields(log.Fields{"unit": unit, "action": action}).Infof("applying the changes to %s", unit)
+	return reloadUnit(unit, action)
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		fmt.Println(err)
+		log.Errorf("%v", err)
 		os.Exit(1)
 	}
+	os.Exit(exitStatus)
 }
 
 func init() {
 	rootCmd.PersistentFlags().StringVarP(&cfgFile, "config", "c", "/etc/kubic/kubic-init.yaml", "kubibc-init.yaml config file")
+	rootCmd.PersistentFlags().StringVar(&logLevel, "log-level", "info", "minimum level of the logged messages: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&nodeRole, "role", "", "role of the node: seeder or join, detected from the seeder by default")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", log.TextFormat, "format of the logged messages: text or json")
+	rootCmd.Flags().BoolVar(&reloadRuntime, "reload", false, "reload or restart the container runtime when its configuration changed")
+	rootCmd.Flags().StringVar(&reportFile, "report", "", "write a JSON report of the run to this file, - for the standard output")
+	rootCmd.Flags().BoolVar(&detailedExitCode, "detailed-exitcode", false, "exit with 0 when nothing changed, 2 when files changed and 1 on errors")
 	rootCmd.AddCommand(newVersionCmd())
+	rootCmd.AddCommand(newExplainCmd())
+	rootCmd.AddCommand(newCheckCmd())
+	rootCmd.AddCommand(newCertsCmd())
+	rootCmd.AddCommand(newWatchCmd())
+	rootCmd.AddCommand(newResetCmd())
+	rootCmd.AddCommand(newStatusCmd())
+	rootCmd.AddCommand(newDriftCmd())
+	rootCmd.AddCommand(newKubeadmConfigCmd())
+	rootCmd.AddCommand(newOIDCCmd())
+	rootCmd.AddCommand(newEtcdSANsCmd())
+	rootCmd.AddCommand(newPKICmd())
+	rootCmd.AddCommand(newConfigCmd())
 }
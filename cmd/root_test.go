@@ -15,10 +15,21 @@
 package cmd
 
 import (
+	"encoding/json"
+	"errors"
 	"io/ioutil"
+	"net"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/netutil"
+	"github.com/kubic-project/caasp-init/pkg/reload"
+	"github.com/kubic-project/caasp-init/pkg/report"
+
 	"github.com/spf13/cobra"
 )
 
@@ -131,6 +142,8 @@ DiH5uEqBXExjrj0FslxcVKdVj5glVcSmkLwZKbEU1OKwleT/iXFhvooWhQ==
 )
 
 func Test_runE(t *testing.T) {
+	defer func(f func([]fsutil.Change, []string, string) error) { recordFiles = f }(recordFiles)
+	recordFiles = func([]fsutil.Change, []string, string) error { return nil }
 	c := &cobra.Command{}
 	err := ioutil.WriteFile(filename, []byte(configContent), os.FileMode(0644))
 	if err != nil {
@@ -172,3 +185,369 @@ func Test_runE(t *testing.T) {
 		})
 	}
 }
+
+func Test_reloadEngine(t *testing.T) {
+	defer func(f func(string, reload.Action) error) { reloadUnit = f }(reloadUnit)
+
+	daemonJSON := "/etc/docker/daemon.json"
+	dropIn := "/etc/containers/registries.conf.d/50-caasp-init.conf"
+	tests := []struct {
+		name       string
+		engine     string
+		changes    []fsutil.Change
+		wantUnit   string
+		wantAction reload.Action
+	}{
+		{"docker_unchanged", "docker", []fsutil.Change{
+			{Path: daemonJSON, Action: fsutil.Unchanged, Previous: []byte("{}"), Content: []byte("{}")},
+		}, "", reload.None},
+		{"docker_mirrors", "docker", []fsutil.Change{
+			{Path: daemonJSON, Action: fsutil.Updated, Previous: []byte(`{"iptables": false}`), Content: []byte(`{"registry-mirrors": ["https://mirror.com"], "iptables": false}`)},
+		}, "docker.service", reload.Reload},
+		{"docker_created", "", []fsutil.Change{
+			{Path: daemonJSON, Action: fsutil.Created, Content: []byte("{}")},
+		}, "docker.service", reload.Restart},
+		{"crio_drop_in", "crio", []fsutil.Change{
+			{Path: daemonJSON, Action: fsutil.Created, Content: []byte("{}")},
+			{Path: dropIn, Action: fsutil.Updated},
+		}, "crio.service", reload.Restart},
+		{"crio_unchanged", "crio", []fsutil.Change{
+			{Path: daemonJSON, Action: fsutil.Created, Content: []byte("{}")},
+		}, "", reload.None},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUnit, gotAction := "", reload.None
+			reloadUnit = func(unit string, action reload.Action) error {
+				gotUnit, gotAction = unit, action
+				return nil
+			}
+			if err := reloadEngine(tt.engine, tt.changes); err != nil {
+				t.Fatalf("reloadEngine() error = %v", err)
+			}
+			if gotUnit != tt.wantUnit || gotAction != tt.wantAction {
+				t.Errorf("reloadEngine() = %s %v, want %s %v", gotUnit, gotAction, tt.wantUnit, tt.wantAction)
+			}
+		})
+	}
+}
+
+func Test_runEDetailedExitCode(t *testing.T) {
+	defer func(f func(*config.KubicInitConfiguration) error, file string, detailed bool) {
+		applyConfig, cfgFile, detailedExitCode = f, file, detailed
+		exitStatus = 0
+	}(applyConfig, cfgFile, detailedExitCode)
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-exitcode")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cfgFile = filepath.Join(tmpDir, "kubic-init.yaml")
+	if err := ioutil.WriteFile(cfgFile, []byte("runtime:\n  engine: docker\n"), 0644); err != nil {
+		t.Fatalf("writing config: %s", err)
+	}
+	daemonJSON := filepath.Join(tmpDir, "daemon.json")
+	applyConfig = func(cfg *config.KubicInitConfiguration) error {
+		fsutil.ResetChanges()
+		_, err := fsutil.WriteFile(daemonJSON, []byte(`{"iptables": false}`), 0644)
+		return err
+	}
+
+	tests := []struct {
+		name     string
+		detailed bool
+		want     int
+	}{
+		{"first_run", true, exitChanges},
+		{"no_changes", true, 0},
+		{"not_detailed", false, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.name == "not_detailed" {
+				os.Remove(daemonJSON)
+			}
+			exitStatus = 0
+			detailedExitCode = tt.detailed
+			if err := runE(&cobra.Command{}, nil); err != nil {
+				t.Fatalf("runE() error = %v", err)
+			}
+			if exitStatus != tt.want {
+				t.Errorf("runE() exit status = %d, want %d", exitStatus, tt.want)
+			}
+		})
+	}
+}
+
+func Test_setupLogging(t *testing.T) {
+	defer func(level, format string) {
+		logLevel, logFormat = level, format
+		setupLogging(nil, nil)
+	}(logLevel, logFormat)
+
+	tests := []struct {
+		name    string
+		level   string
+		format  string
+		wantErr bool
+	}{
+		{"defaults", "info", "text", false},
+		{"json", "debug", "json", false},
+		{"unknown_level", "verbose", "text", true},
+		{"unknown_format", "info", "xml", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logLevel, logFormat = tt.level, tt.format
+			if err := setupLogging(nil, nil); (err != nil) != tt.wantErr {
+				t.Errorf("setupLogging() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_runEReport(t *testing.T) {
+	defer func(f func(*config.KubicInitConfiguration) error, file, report string) {
+		applyConfig, cfgFile, reportFile = f, file, report
+	}(applyConfig, cfgFile, reportFile)
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-report")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	valid := filepath.Join(tmpDir, "kubic-init.yaml")
+	if err := ioutil.WriteFile(valid, []byte("bootstrap:\n  registries:\n    - prefix: docker.io\n      mirrors:\n        - url: https://mirror.com\n"), 0644); err != nil {
+		t.Fatalf("writing config: %s", err)
+	}
+	invalid := filepath.Join(tmpDir, "invalid.yaml")
+	if err := ioutil.WriteFile(invalid, []byte("runtime:\n  dockerCompat: unknown\n"), 0644); err != nil {
+		t.Fatalf("writing config: %s", err)
+	}
+	applyConfig = func(cfg *config.KubicInitConfiguration) error {
+		_, err := fsutil.WriteFile(filepath.Join(tmpDir, "daemon.json"), []byte("{}"), 0644)
+		return err
+	}
+
+	tests := []struct {
+		name        string
+		configFile  string
+		wantErr     bool
+		wantFiles   int
+		wantChanged bool
+	}{
+		{"applied", valid, false, 1, true},
+		{"unchanged", valid, false, 1, false},
+		{"invalid", invalid, true, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgFile = tt.configFile
+			reportFile = filepath.Join(tmpDir, tt.name+".json")
+			if err := runE(&cobra.Command{}, nil); (err != nil) != tt.wantErr {
+				t.Fatalf("runE() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			b, err := ioutil.ReadFile(reportFile)
+			if err != nil {
+				t.Fatalf("reading report: %s", err)
+			}
+			r := report.Report{}
+			if err := json.Unmarshal(b, &r); err != nil {
+				t.Fatalf("decoding report: %s", err)
+			}
+			if len(r.Files) != tt.wantFiles || r.Changed != tt.wantChanged || (r.Error != "") != tt.wantErr {
+				t.Errorf("runE() report = %s", b)
+			}
+		})
+	}
+}
+
+func Test_loadConfigRole(t *testing.T) {
+	defer func(role string, l netutil.InterfaceLister) { nodeRole, interfaces = role, l }(nodeRole, interfaces)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-role")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "kubic-init.yaml")
+	content := "clusterFormation:\n  seeder: 192.168.1.10\nroles:\n  join:\n    network:\n      cni:\n        driver: flannel\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing %s: %s", path, err)
+	}
+	seeder := netutil.StaticLister{{Name: "eth0", Addrs: []*net.IPNet{{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}}}}
+	worker := netutil.StaticLister{{Name: "eth0", Addrs: []*net.IPNet{{IP: net.ParseIP("192.168.1.11"), Mask: net.CIDRMask(24, 32)}}}}
+
+	tests := []struct {
+		name       string
+		role       string
+		lister     netutil.InterfaceLister
+		wantRole   string
+		wantDriver string
+		wantErr    bool
+	}{
+		{"seeder", "", seeder, config.RoleSeeder, "", false},
+		{"join", "", worker, config.RoleJoin, "flannel", false},
+		{"flag", config.RoleJoin, seeder, config.RoleJoin, "flannel", false},
+		{"unknown_flag", "master", seeder, "", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nodeRole, interfaces = tt.role, tt.lister
+			cfg, err := loadConfig(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("loadConfig() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.ClusterFormation.Role != tt.wantRole || cfg.Network.Cni.Driver != tt.wantDriver {
+				t.Errorf("loadConfig() role = %q, driver = %q, want %q, %q", cfg.ClusterFormation.Role, cfg.Network.Cni.Driver, tt.wantRole, tt.wantDriver)
+			}
+		})
+	}
+}
+
+// failingLister fails to list the network interfaces, as when detecting the
+// role of the node cannot be done
+type failingLister struct{}
+
+func (failingLister) Interfaces() ([]netutil.Interface, error) {
+	return nil, errors.New("no interfaces")
+}
+
+func Test_loadConfigRoleDetection(t *testing.T) {
+	defer func(role string, l netutil.InterfaceLister) { nodeRole, interfaces = role, l }(nodeRole, interfaces)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-role")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	withoutRoles := filepath.Join(tmpDir, "without-roles.yaml")
+	withRoles := filepath.Join(tmpDir, "with-roles.yaml")
+	files := map[string]string{
+		withoutRoles: "clusterFormation:\n  seeder: 192.168.1.10\n",
+		withRoles:    "clusterFormation:\n  seeder: 192.168.1.10\nroles:\n  join:\n    network:\n      cni:\n        driver: flannel\n",
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+	}
+
+	// the lister fails when the role is detected
+	nodeRole, interfaces = "", failingLister{}
+	tests := []struct {
+		name     string
+		path     string
+		load     func(string) (*config.KubicInitConfiguration, error)
+		wantRole string
+		wantErr  bool
+	}{
+		{"not_needed", withoutRoles, loadConfig, "", false},
+		{"roles_sections", withRoles, loadConfig, "", false},
+		{"needed", withoutRoles, loadRoleConfig, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.load(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("load error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && (cfg.ClusterFormation.Role != tt.wantRole || cfg.Network.Cni.Driver != "" || cfg.Roles != nil) {
+				t.Errorf("load role = %q, driver = %q, want %q without the roles sections", cfg.ClusterFormation.Role, cfg.Network.Cni.Driver, tt.wantRole)
+			}
+		})
+	}
+
+	// a configured role needs no detection
+	nodeRole = config.RoleJoin
+	cfg, err := loadRoleConfig(withRoles)
+	if err != nil {
+		t.Fatalf("loadRoleConfig() error = %v with --role", err)
+	}
+	if cfg.Network.Cni.Driver != "flannel" {
+		t.Errorf("loadRoleConfig() driver = %q with --role, want flannel", cfg.Network.Cni.Driver)
+	}
+}
+
+func Test_applyRecordsFailedRun(t *testing.T) {
+	defer func(l netutil.InterfaceLister, w func(*config.KubicInitConfiguration, *config.KubicInitConfiguration) error, r func([]fsutil.Change, []string, string) error) {
+		interfaces, writeFiles, recordFiles = l, w, r
+	}(interfaces, writeFiles, recordFiles)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-apply")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	defer fsutil.ResetChanges()
+
+	path := filepath.Join(tmpDir, "kubic-init.yaml")
+	if err := ioutil.WriteFile(path, []byte("runtime:\n  engine: docker\n"), 0644); err != nil {
+		t.Fatalf("writing %s: %s", path, err)
+	}
+	interfaces = netutil.StaticLister{{Name: "eth0", Addrs: []*net.IPNet{{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}}}}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+
+	// the CNI configuration fails after daemon.json was written
+	daemonJSON := filepath.Join(tmpDir, "daemon.json")
+	writeFiles = func(*config.KubicInitConfiguration, *config.KubicInitConfiguration) error {
+		if _, err := fsutil.WriteFile(daemonJSON, []byte(`{"iptables": false}`), 0644); err != nil {
+			return err
+		}
+		return errors.New("missing CNI plugins")
+	}
+	var recorded []string
+	recordFiles = func(changes []fsutil.Change, dirs []string, version string) error {
+		for _, change := range changes {
+			recorded = append(recorded, change.Path)
+		}
+		return nil
+	}
+
+	if err := apply(cfg); err == nil {
+		t.Fatalf("apply() succeeded with a failing writer")
+	}
+	if want := []string{daemonJSON}; !reflect.DeepEqual(recorded, want) {
+		t.Errorf("apply() recorded %q, want %q", recorded, want)
+	}
+}
+
+func Test_applyChecksPlugins(t *testing.T) {
+	defer func(l netutil.InterfaceLister, w func(*config.KubicInitConfiguration, *config.KubicInitConfiguration) error) {
+		interfaces, writeFiles = l, w
+	}(interfaces, writeFiles)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-apply")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "kubic-init.yaml")
+	content := "network:\n  cni:\n    driver: flannel\n    binDir: " + filepath.Join(tmpDir, "bin") + "\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("writing %s: %s", path, err)
+	}
+	interfaces = netutil.StaticLister{{Name: "eth0", Addrs: []*net.IPNet{{IP: net.ParseIP("192.168.1.10"), Mask: net.CIDRMask(24, 32)}}}}
+	cfg, err := loadConfig(path)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	written := false
+	writeFiles = func(*config.KubicInitConfiguration, *config.KubicInitConfiguration) error {
+		written = true
+		return nil
+	}
+
+	if err := apply(cfg); err == nil {
+		t.Errorf("apply() accepted missing CNI plugins")
+	}
+	if written {
+		t.Errorf("apply() wrote files before checking the CNI plugins")
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/state"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	statusLongDescription = `Show what caasp-init manages on the node.
+
+usage:
+
+$ caasp-init status
+
+Every run of caasp-init records in '/var/lib/caasp-init/state.json' the files
+it manages with the checksum of their content, the generation of the
+configuration that last wrote them and the version of caasp-init. The
+generation is incremented every time a different configuration is applied.
+
+The status shows the last configuration applied, the fields of the current
+configuration that changed since then, and whether each managed file still
+has the content caasp-init wrote:
+
+  ok        the file has the content caasp-init wrote
+  modified  the file was edited since caasp-init wrote it
+  missing   the file was removed since caasp-init wrote it
+  unknown   the content was not recorded, caasp-init has to run again
+
+The values of the secrets are never shown.
+`
+)
+
+// loadState reads the state manifest
+var loadState = state.Load
+
+// newStatusCmd represents the status command
+func newStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the files managed by caasp-init and their drift",
+		Long:  statusLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runStatus,
+	}
+}
+
+func runStatus(cmd *cobra.Command, args []string) error {
+	m, err := loadState()
+	if err != nil {
+		return err
+	}
+
+	w := cmd.OutOrStdout()
+	if m.Generation == 0 {
+		fmt.Fprintln(w, "no configuration applied yet")
+		return nil
+	}
+	fmt.Fprintf(w, "generation %d applied at %s by caasp-init %s from %s\n",
+		m.Generation, m.Applied.Time.Format(time.RFC3339), m.Applied.Version, m.Applied.Path)
+
+	changes, err := configChanges(m, cfgFile)
+	if err != nil {
+		fmt.Fprintf(w, "configuration: %v\n", err)
+	} else if len(changes) == 0 {
+		fmt.Fprintln(w, "configuration: unchanged")
+	} else {
+		fmt.Fprintf(w, "configuration: %d changes not applied yet\n", len(changes))
+		for _, change := range changes {
+			fmt.Fprintf(w, "  %s\n", change)
+		}
+	}
+
+	files, err := m.Status()
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "files:")
+	for _, f := range files {
+		fmt.Fprintf(w, "  %-9s %-14s %s\n", f.State, fmt.Sprintf("generation %d", f.Generation), f.Path)
+	}
+	return nil
+}
+
+// configChanges compares the configuration at `cfgPath` with the one last
+// applied
+func configChanges(m *state.Manifest, cfgPath string) ([]config.FieldChange, error) {
+	applied, err := m.Config()
+	if err != nil {
+		return nil, err
+	}
+	current, err := loadConfig(cfgPath)
+	if err != nil {
+		return nil, err
+	}
+	sum, err := config.Checksum(current)
+	if err != nil || sum == m.Applied.SHA256 {
+		return nil, err
+	}
+	redacted, err := config.Redact(current, config.HashSecret)
+	if err != nil {
+		return nil, err
+	}
+	return config.Diff(applied, redacted), nil
+}
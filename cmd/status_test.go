@@ -0,0 +1,119 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/state"
+
+	"github.com/spf13/cobra"
+)
+
+func Test_runStatus(t *testing.T) {
+	defer func(f func() (*state.Manifest, error), file string) {
+		loadState, cfgFile = f, file
+	}(loadState, cfgFile)
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-status")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	writeConfig := func(name, content string) string {
+		path := filepath.Join(tmpDir, name)
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		return path
+	}
+	applied := writeConfig("applied.yaml", "runtime:\n  engine: docker\nclusterFormation:\n  token: 94dcda.c271f4ff502789ca\n")
+	changed := writeConfig("changed.yaml", "runtime:\n  engine: crio\nclusterFormation:\n  token: 94dcda.0000000000000000\n")
+	invalid := writeConfig("invalid.yaml", "runtime: [\n")
+
+	cfg, err := loadConfig(applied)
+	if err != nil {
+		t.Fatalf("loadConfig() error = %v", err)
+	}
+	sum, _ := config.Checksum(cfg)
+	redacted, _ := config.Redact(cfg, config.HashSecret)
+	content, _ := yaml.Marshal(redacted)
+
+	daemonJSON := writeConfig("daemon.json", "{}")
+	manifest := &state.Manifest{
+		Generation: 3,
+		Applied: state.Applied{
+			Time:    time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC),
+			Version: "1.0",
+			Path:    "/etc/kubic/kubic-init.yaml",
+			SHA256:  sum,
+			Config:  string(content),
+		},
+		Files: []state.ManagedFile{
+			{Path: daemonJSON, SHA256: "0000", Generation: 2},
+			{Path: filepath.Join(tmpDir, "gone.json"), SHA256: "0000", Generation: 3},
+		},
+	}
+	files := `files:
+  modified  generation 2   ` + daemonJSON + `
+  missing   generation 3   ` + filepath.Join(tmpDir, "gone.json") + `
+`
+	header := "generation 3 applied at 2026-10-01T12:00:00Z by caasp-init 1.0 from /etc/kubic/kubic-init.yaml\n"
+
+	tests := []struct {
+		name       string
+		manifest   *state.Manifest
+		configFile string
+		want       string
+	}{
+		{"never_applied", &state.Manifest{}, applied, "no configuration applied yet\n"},
+		{"unchanged", manifest, applied, header + "configuration: unchanged\n" + files},
+		{"changed", manifest, changed, header + `configuration: 2 changes not applied yet
+  clusterFormation.token: (redacted) -> (redacted)
+  runtime.engine: "docker" -> "crio"
+` + files},
+		{"invalid", manifest, invalid, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loadState = func() (*state.Manifest, error) { return tt.manifest, nil }
+			cfgFile = tt.configFile
+			out := &bytes.Buffer{}
+			c := &cobra.Command{}
+			c.SetOutput(out)
+			if err := runStatus(c, nil); err != nil {
+				t.Fatalf("runStatus() error = %v", err)
+			}
+			if tt.want == "" {
+				if !bytes.Contains(out.Bytes(), []byte("configuration: ")) || !bytes.Contains(out.Bytes(), []byte("files:")) {
+					t.Errorf("runStatus() output = %q", out.String())
+				}
+				return
+			}
+			if out.String() != tt.want {
+				t.Errorf("runStatus() output = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/log"
+	"github.com/kubic-project/caasp-init/pkg/notify"
+	"github.com/kubic-project/caasp-init/pkg/watch"
+
+	"github.com/spf13/cobra"
+)
+
+const (
+	watchLongDescription = `Apply the configuration and apply it again every time it changes.
+
+usage:
+
+$ caasp-init watch
+
+The configuration file and the '*.yaml' files of its drop-in directory
+('/etc/kubic/kubic-init.yaml.d' by default) are watched with inotify. Once
+the edits settle for the '--debounce' delay the configuration is loaded and
+validated again: a configuration with errors is reported and ignored, the
+current one is kept. Otherwise the fields that changed are logged, with the
+secrets redacted, and the configuration is applied.
+
+When run by a 'Type=notify' systemd unit, systemd is notified once the
+configuration is applied and watched, and every time it is applied again.
+
+With '--reload' the container runtime is reloaded or restarted every time its
+configuration files change.
+`
+)
+
+var (
+	watchDebounce time.Duration
+
+	// applyConfig writes the configuration of the container runtime
+	applyConfig = apply
+)
+
+// newWatchCmd represents the watch command
+func newWatchCmd() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "watch",
+		Short: "Apply the configuration every time it changes",
+		Long:  watchLongDescription,
+		Args:  cobra.NoArgs,
+		RunE:  runWatch,
+	}
+	c.Flags().DurationVar(&watchDebounce, "debounce", 2*time.Second, "time without changes to wait for before applying the configuration")
+	c.Flags().BoolVar(&reloadRuntime, "reload", false, "reload or restart the container runtime when its configuration changed")
+	return c
+}
+
+func runWatch(cmd *cobra.Command, args []string) error {
+	stop := make(chan struct{})
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		<-signals
+		close(stop)
+	}()
+
+	return watchConfig(stop)
+}
+
+// watchConfig applies the configuration and applies it again on every
+// change until `stop` is closed
+func watchConfig(stop <-chan struct{}) error {
+	current, err := loadConfig(cfgFile)
+	if err != nil {
+		return err
+	}
+	err = applyConfig(current)
+	if err != nil {
+		return err
+	}
+	log.Infof("watching %s and %s", cfgFile, config.DropInDir(cfgFile))
+	notify.Ready(fmt.Sprintf("watching %s", cfgFile))
+	defer notify.Stopping()
+
+	return watch.Config(cfgFile, watchDebounce, stop, func() {
+		next, err := loadConfig(cfgFile)
+		if err != nil {
+			log.Errorf("ignoring the new configuration: %v", err)
+			notify.Status("ignoring the new configuration: %v", err)
+			return
+		}
+		changes := config.Diff(current, next)
+		if len(changes) == 0 {
+			log.Infof("configuration unchanged")
+			return
+		}
+		for _, change := range changes {
+			log.WithFields(log.Fields{"field": change.Path, "old": change.Old, "new": change.New}).Infof("configuration changed: %s", change)
+		}
+		notify.Reloading("applying the new configuration")
+		if err := applyConfig(next); err != nil {
+			log.Errorf("unable to apply the new configuration: %v", err)
+			notify.Ready(fmt.Sprintf("unable to apply the new configuration: %v", err))
+			return
+		}
+		current = next
+		log.Infof("configuration applied")
+		notify.Ready(fmt.Sprintf("watching %s, configuration applied at %s", cfgFile, time.Now().Format(time.RFC3339)))
+	})
+}
@@ -0,0 +1,150 @@
+// Copyright © 2019 openSUSE opensuse-project@opensuse.org
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+// syncBuffer is a buffer safe for concurrent use
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func Test_watchConfig(t *testing.T) {
+	defer func(f func(*config.KubicInitConfiguration) error, file string, d time.Duration) {
+		applyConfig, cfgFile, watchDebounce = f, file, d
+	}(applyConfig, cfgFile, watchDebounce)
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-watch")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cfgFile = filepath.Join(tmpDir, "kubic-init.yaml")
+	watchDebounce = 20 * time.Millisecond
+	if err := ioutil.WriteFile(cfgFile, []byte("runtime:\n  engine: docker\n"), 0644); err != nil {
+		t.Fatalf("writing config: %s", err)
+	}
+
+	applied := make(chan *config.KubicInitConfiguration, 10)
+	applyConfig = func(cfg *config.KubicInitConfiguration) error {
+		applied <- cfg
+		return nil
+	}
+	socket := filepath.Join(tmpDir, "notify.sock")
+	notifications, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listening on %s: %s", socket, err)
+	}
+	defer notifications.Close()
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	os.Setenv("NOTIFY_SOCKET", socket)
+	waitNotification := func() string {
+		buf := make([]byte, 1024)
+		notifications.SetReadDeadline(time.Now().Add(time.Second))
+		n, _ := notifications.Read(buf)
+		return string(buf[:n])
+	}
+
+	out := &syncBuffer{}
+	log.SetOutput(out)
+	defer log.SetOutput(os.Stderr)
+	stop := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- watchConfig(stop)
+	}()
+
+	waitApply := func() *config.KubicInitConfiguration {
+		select {
+		case cfg := <-applied:
+			return cfg
+		case <-time.After(time.Second):
+			return nil
+		}
+	}
+	if cfg := waitApply(); cfg == nil || cfg.Runtime.Engine != "docker" {
+		t.Fatalf("watchConfig() did not apply the initial configuration")
+	}
+	if got := waitNotification(); !strings.HasPrefix(got, "READY=1\n") {
+		t.Errorf("watchConfig() notified %q, want READY=1", got)
+	}
+	// give the watcher the time to start
+	time.Sleep(50 * time.Millisecond)
+
+	tests := []struct {
+		name       string
+		content    string
+		wantApply  bool
+		wantOutput string
+	}{
+		{"invalid", "runtime:\n  dockerCompat: unknown\n", false, "ignoring the new configuration"},
+		{"unchanged", "runtime:\n  engine: docker\n", false, "configuration unchanged"},
+		{"mirror_added", "runtime:\n  engine: docker\nbootstrap:\n  registries:\n    - prefix: docker.io\n      mirrors:\n        - url: https://mirror.com\n          username: user\n          password: secret\n",
+			true, `bootstrap.registries[0].mirrors[0].password: (unset) -> (redacted)`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(cfgFile, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("writing config: %s", err)
+			}
+			if cfg := waitApply(); (cfg != nil) != tt.wantApply {
+				t.Errorf("watchConfig() applied = %v, want %v", cfg != nil, tt.wantApply)
+			}
+			if !strings.Contains(out.String(), tt.wantOutput) {
+				t.Errorf("watchConfig() output = %q, want %q", out.String(), tt.wantOutput)
+			}
+		})
+	}
+	if strings.Contains(out.String(), "secret") {
+		t.Errorf("watchConfig() logged a password: %q", out.String())
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("watchConfig() error = %v", err)
+	}
+	for got := waitNotification(); got != "STOPPING=1"; got = waitNotification() {
+		if got == "" {
+			t.Errorf("watchConfig() did not notify STOPPING=1")
+			break
+		}
+	}
+}
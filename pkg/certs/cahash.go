@@ -0,0 +1,170 @@
+package certs
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+const (
+	// CAFile is the name of the cluster CA certificate in the certificates directory
+	CAFile = "ca.crt"
+
+	// clusterInfoPath is where the API server of the seeder publishes the
+	// CA of the cluster to the joining nodes
+	clusterInfoPath = "/api/v1/namespaces/kube-public/configmaps/cluster-info"
+
+	// apiServerPort is the port of the API server when the seeder has none
+	apiServerPort = "6443"
+)
+
+// CAHash returns the hash of the CA certificate in the format kubeadm uses
+// for pinning it: sha256 and the hex SHA-256 of its public key
+func CAHash(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// VerifyCAHash checks the CA certificate has the hash `hash`
+func VerifyCAHash(cert *x509.Certificate, hash string) error {
+	if hash == "" {
+		return errors.New("certificates.caCrtHash is not set")
+	}
+	got := CAHash(cert)
+	if subtle.ConstantTimeCompare([]byte(got), []byte(strings.ToLower(hash))) != 1 {
+		return fmt.Errorf("the CA certificate %s has the hash %s, not the configured %s", cert.Subject, got, hash)
+	}
+	return nil
+}
+
+// LoadCA reads the cluster CA certificate from the certificates directory
+func LoadCA(cfg *config.KubicInitConfiguration) (*x509.Certificate, error) {
+	path := filepath.Join(cfg.Certificates.Directory, CAFile)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the cluster CA: %v", err)
+	}
+	cas, err := ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cluster CA %s: %v", path, err)
+	}
+	return cas[0], nil
+}
+
+// FetchSeederCA fetches the cluster CA published by the API server of the
+// seeder and returns it only when it has the configured hash and it signed
+// the certificate of the API server. Nothing from the seeder is trusted
+// before that.
+func FetchSeederCA(cfg *config.KubicInitConfiguration) (*x509.Certificate, error) {
+	if cfg.ClusterFormation.Seeder == "" {
+		return nil, errors.New("clusterFormation.seeder is not set")
+	}
+	if cfg.Certificates.CaHash == "" {
+		return nil, errors.New("certificates.caCrtHash is not set, the CA of the seeder cannot be verified")
+	}
+
+	host := cfg.ClusterFormation.Seeder
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, apiServerPort)
+	}
+	url := "https://" + host + clusterInfoPath
+	// the certificate of the API server is verified below, against the
+	// pinned CA
+	client := &http.Client{
+		Timeout:   dialTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach the seeder: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	ca, err := decodeClusterInfo(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %v", url, err)
+	}
+	if err := VerifyCAHash(ca, cfg.Certificates.CaHash); err != nil {
+		return nil, err
+	}
+	if err := verifyPeer(resp.TLS, ca); err != nil {
+		return nil, fmt.Errorf("the seeder is not signed by the CA it published: %v", err)
+	}
+	return ca, nil
+}
+
+// clusterInfo is the part of the cluster-info config map holding the CA
+type clusterInfo struct {
+	Data struct {
+		Kubeconfig string `json:"kubeconfig"`
+	} `json:"data"`
+}
+
+// kubeconfig is the part of the kubeconfig holding the CA
+type kubeconfig struct {
+	Clusters []struct {
+		Cluster struct {
+			CertificateAuthorityData string `yaml:"certificate-authority-data"`
+		} `yaml:"cluster"`
+	} `yaml:"clusters"`
+}
+
+// decodeClusterInfo returns the CA of the kubeconfig in the cluster-info
+// config map
+func decodeClusterInfo(r io.Reader) (*x509.Certificate, error) {
+	info := &clusterInfo{}
+	if err := json.NewDecoder(r).Decode(info); err != nil {
+		return nil, err
+	}
+	kc := &kubeconfig{}
+	if err := yaml.Unmarshal([]byte(info.Data.Kubeconfig), kc); err != nil {
+		return nil, err
+	}
+	if len(kc.Clusters) == 0 || kc.Clusters[0].Cluster.CertificateAuthorityData == "" {
+		return nil, errors.New("no certificate-authority-data in the kubeconfig")
+	}
+	data, err := base64.StdEncoding.DecodeString(kc.Clusters[0].Cluster.CertificateAuthorityData)
+	if err != nil {
+		return nil, err
+	}
+	cas, err := ParseCertificates(data)
+	if err != nil {
+		return nil, err
+	}
+	return cas[0], nil
+}
+
+// verifyPeer checks the certificate presented in the connection is signed
+// by the CA
+func verifyPeer(state *tls.ConnectionState, ca *x509.Certificate) error {
+	if state == nil || len(state.PeerCertificates) == 0 {
+		return errors.New("no certificate presented")
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+	return err
+}
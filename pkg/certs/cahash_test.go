@@ -0,0 +1,120 @@
+package certs
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+func TestCAHash(t *testing.T) {
+	cert := newTestCertificate(t)
+	hash := CAHash(cert)
+	if !strings.HasPrefix(hash, "sha256:") || len(hash) != len("sha256:")+64 {
+		t.Fatalf("CAHash() = %q, want sha256:<64 hex digits>", hash)
+	}
+	if CAHash(cert) != hash {
+		t.Errorf("CAHash() is not stable")
+	}
+
+	tests := []struct {
+		name    string
+		hash    string
+		wantErr bool
+	}{
+		{"match", hash, false},
+		{"uppercase", strings.ToUpper(hash), false},
+		{"other", CAHash(newTestCertificate(t)), true},
+		{"empty", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyCAHash(cert, tt.hash); (err != nil) != tt.wantErr {
+				t.Errorf("VerifyCAHash() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoadCA(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-certs")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cert := newTestCertificate(t)
+	if err := ioutil.WriteFile(filepath.Join(tmpDir, CAFile), EncodeCertificate(cert), 0644); err != nil {
+		t.Fatalf("writing the CA: %s", err)
+	}
+	got, err := LoadCA(&config.KubicInitConfiguration{Certificates: config.CertsConfiguration{Directory: tmpDir}})
+	if err != nil {
+		t.Fatalf("LoadCA() error = %v", err)
+	}
+	if !got.Equal(cert) {
+		t.Errorf("LoadCA() = %s, want %s", got.Subject, cert.Subject)
+	}
+	if _, err := LoadCA(&config.KubicInitConfiguration{Certificates: config.CertsConfiguration{Directory: filepath.Join(tmpDir, "missing")}}); err == nil {
+		t.Errorf("LoadCA() of a missing directory should fail")
+	}
+}
+
+func TestFetchSeederCA(t *testing.T) {
+	// published is the CA in the cluster-info of the seeder
+	var published *x509.Certificate
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != clusterInfoPath {
+			http.NotFound(w, r)
+			return
+		}
+		kubeconfig := "apiVersion: v1\nkind: Config\nclusters:\n- name: \"\"\n  cluster:\n    certificate-authority-data: " +
+			base64.StdEncoding.EncodeToString(EncodeCertificate(published)) + "\n"
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"kind": "ConfigMap",
+			"data": map[string]string{"kubeconfig": kubeconfig},
+		})
+	}))
+	defer srv.Close()
+	seeder := strings.TrimPrefix(srv.URL, "https://")
+	other := newTestCertificate(t)
+
+	tests := []struct {
+		name      string
+		published *x509.Certificate
+		seeder    string
+		hash      string
+		wantErr   bool
+	}{
+		{"verified", srv.Certificate(), seeder, CAHash(srv.Certificate()), false},
+		{"other_hash", srv.Certificate(), seeder, CAHash(other), true},
+		// the CA matches the hash but did not sign the API server
+		{"not_signed", other, seeder, CAHash(other), true},
+		{"no_hash", srv.Certificate(), seeder, "", true},
+		{"no_seeder", srv.Certificate(), "", CAHash(srv.Certificate()), true},
+		{"unreachable", srv.Certificate(), "127.0.0.1:1", CAHash(srv.Certificate()), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			published = tt.published
+			cfg := &config.KubicInitConfiguration{
+				ClusterFormation: config.ClusterFormationConfiguration{Seeder: tt.seeder},
+				Certificates:     config.CertsConfiguration{CaHash: tt.hash},
+			}
+			got, err := FetchSeederCA(cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FetchSeederCA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !got.Equal(tt.published) {
+				t.Errorf("FetchSeederCA() = %s, want %s", got.Subject, tt.published.Subject)
+			}
+		})
+	}
+}
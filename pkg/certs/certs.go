@@ -3,12 +3,13 @@ package certs
 import (
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/url"
 	"os"
 	"path"
 
 	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
 )
 
 var (
@@ -29,21 +30,17 @@ func WriteCertificates(config *config.KubicInitConfiguration) error {
 			if mirror.Certificate == "" {
 				continue
 			}
-			url, err := url.Parse(mirror.URL)
+			certPath, err := Path(mirror)
 			if err != nil {
 				return err
 			}
 
-			if url.Scheme == "" {
-				return fmt.Errorf("Error in configuration file: malformed Mirror URL \"%s\"", url.String())
-			}
-
-			err = os.MkdirAll(path.Join(certsFolder, url.Hostname()), 0744)
+			err = fsutil.MkdirAll(path.Dir(certPath), 0744)
 			if err != nil {
 				return err
 			}
 
-			err = ioutil.WriteFile(path.Join(certsFolder, url.Hostname(), certName), []byte(mirror.Certificate), os.FileMode(0644))
+			_, err = fsutil.WriteFile(certPath, []byte(mirror.Certificate), os.FileMode(0644))
 			if err != nil {
 				return err
 			}
@@ -51,3 +48,89 @@ func WriteCertificates(config *config.KubicInitConfiguration) error {
 	}
 	return nil
 }
+
+// Drift compares the certificates installed with the ones of the mirrors
+func Drift(config *config.KubicInitConfiguration) ([]drift.Finding, error) {
+	if config == nil {
+		return nil, errors.New("configuration is nil")
+	}
+	var findings []drift.Finding
+	for _, reg := range config.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		for _, mirror := range reg.Mirrors {
+			if mirror.Certificate == "" {
+				continue
+			}
+			certPath, err := Path(mirror)
+			if err != nil {
+				return nil, err
+			}
+			f, err := drift.File(certPath, []byte(mirror.Certificate))
+			if err != nil {
+				return nil, err
+			}
+			findings = append(findings, f...)
+		}
+	}
+	return findings, nil
+}
+
+// Stale returns the certificates installed for the mirrors of the
+// `previous` configuration that `config` no longer has
+func Stale(config, previous *config.KubicInitConfiguration) ([]string, error) {
+	if config == nil {
+		return nil, errors.New("configuration is nil")
+	}
+	if previous == nil {
+		return nil, nil
+	}
+	current := map[string]bool{}
+	for _, reg := range config.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		for _, mirror := range reg.Mirrors {
+			if mirror.Certificate == "" {
+				continue
+			}
+			certPath, err := Path(mirror)
+			if err != nil {
+				return nil, err
+			}
+			current[certPath] = true
+		}
+	}
+	var stale []string
+	for _, reg := range previous.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		for _, mirror := range reg.Mirrors {
+			if mirror.Certificate == "" {
+				continue
+			}
+			// a malformed mirror had no certificate installed
+			certPath, err := Path(mirror)
+			if err != nil || current[certPath] {
+				continue
+			}
+			current[certPath] = true
+			stale = append(stale, certPath)
+		}
+	}
+	return stale, nil
+}
+
+// Path returns where the certificate of the mirror is installed
+func Path(mirror config.Mirror) (string, error) {
+	url, err := url.Parse(mirror.URL)
+	if err != nil {
+		return "", err
+	}
+	if url.Scheme == "" {
+		return "", fmt.Errorf("Error in configuration file: malformed Mirror URL \"%s\"", url.String())
+	}
+	return path.Join(certsFolder, url.Hostname(), certName), nil
+}
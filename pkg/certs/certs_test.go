@@ -3,9 +3,12 @@ package certs
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 
 	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
 )
 
 var (
@@ -120,3 +123,110 @@ func TestWriteCertificates(t *testing.T) {
 		})
 	}
 }
+
+func TestDrift(t *testing.T) {
+	defer func(f string) { certsFolder = f }(certsFolder)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-certs")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	certsFolder = tmpDir
+
+	if err := WriteCertificates(twoRegistriesWithCerts); err != nil {
+		t.Fatalf("WriteCertificates() error = %v", err)
+	}
+	if got, err := Drift(twoRegistriesWithCerts); err != nil || got != nil {
+		t.Errorf("Drift() = %v, %v after WriteCertificates()", got, err)
+	}
+
+	os.Remove(filepath.Join(tmpDir, "first.mirror.com", certName))
+	ioutil.WriteFile(filepath.Join(tmpDir, "local.lan.mirror.com", certName), []byte("edited"), 0644)
+	want := []drift.Finding{
+		{Path: filepath.Join(tmpDir, "first.mirror.com", certName), Owner: drift.Owned, Kind: drift.Missing},
+		{Path: filepath.Join(tmpDir, "local.lan.mirror.com", certName), Owner: drift.Owned, Kind: drift.Modified},
+	}
+	got, err := Drift(twoRegistriesWithCerts)
+	if err != nil {
+		t.Fatalf("Drift() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Drift() = %v, want %v", got, want)
+	}
+	if _, err := Drift(testNoHTTP); err == nil {
+		t.Errorf("Drift() accepted a mirror without scheme")
+	}
+}
+
+func TestStale(t *testing.T) {
+	defer func(f string) { certsFolder = f }(certsFolder)
+	certsFolder = "/etc/docker/certs.d"
+
+	oneMirror := &config.KubicInitConfiguration{
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "mycompany.registry.com",
+					Mirrors: []config.Mirror{
+						{URL: "https://first.mirror.com:5000", Certificate: "---- Cert Start ------ ACBDFEBABCDBFDBEBCDBABCDBABCBC"},
+						{URL: "https://second.mirror.com"},
+					},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name     string
+		config   *config.KubicInitConfiguration
+		previous *config.KubicInitConfiguration
+		want     []string
+		wantErr  bool
+	}{
+		{"nil", nil, twoRegistriesWithCerts, nil, true},
+		{"never_applied", oneMirror, nil, nil, false},
+		{"same", twoRegistriesWithCerts, twoRegistriesWithCerts, nil, false},
+		{"removed", oneMirror, twoRegistriesWithCerts, []string{
+			"/etc/docker/certs.d/second.mirror.com/ca.crt",
+			"/etc/docker/certs.d/local.lan.mirror.com/ca.crt",
+		}, false},
+		{"malformed_previous", oneMirror, testURLError, nil, false},
+		{"malformed", testNoHTTP, twoRegistriesWithCerts, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Stale(tt.config, tt.previous)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Stale() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Stale() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPath(t *testing.T) {
+	defer func(f string) { certsFolder = f }(certsFolder)
+	certsFolder = "/etc/docker/certs.d"
+
+	tests := []struct {
+		name    string
+		url     string
+		want    string
+		wantErr bool
+	}{
+		{"https", "https://mycompany.airgapped.com", "/etc/docker/certs.d/mycompany.airgapped.com/ca.crt", false},
+		{"port", "https://mycompany.airgapped.com:5000/v2", "/etc/docker/certs.d/mycompany.airgapped.com/ca.crt", false},
+		{"no_scheme", "mycompany.airgapped.com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Path(config.Mirror{URL: tt.url})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Path() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Path() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
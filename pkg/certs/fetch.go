@@ -0,0 +1,57 @@
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+)
+
+var (
+	// dialTimeout for connecting to a mirror
+	dialTimeout = 10 * time.Second
+)
+
+// FetchChain connects to the server in `rawURL` and returns the certificate
+// chain it presents. The chain is not verified, it is up to the caller to
+// decide whether it can be trusted.
+func FetchChain(rawURL string) ([]*x509.Certificate, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "https" {
+		return nil, fmt.Errorf("malformed Mirror URL \"%s\": only https mirrors present certificates", rawURL)
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "443")
+	}
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: dialTimeout}, "tcp", host, &tls.Config{
+		ServerName:         u.Hostname(),
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	chain := conn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return nil, errors.New("no certificate presented")
+	}
+	return chain, nil
+}
+
+// ChainCA returns the certificate of the chain that should be trusted as CA:
+// the last certificate presented, which is the root or the closest to it
+func ChainCA(chain []*x509.Certificate) *x509.Certificate {
+	if len(chain) == 0 {
+		return nil
+	}
+	return chain[len(chain)-1]
+}
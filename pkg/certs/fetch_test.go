@@ -0,0 +1,42 @@
+package certs
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetchChain(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	tests := []struct {
+		name    string
+		url     string
+		want    int
+		wantErr bool
+	}{
+		{"tls", server.URL, 1, false},
+		{"plain_http", "http://mycompany.airgapped.com", 0, true},
+		{"unreachable", "https://127.0.0.1:1", 0, true},
+		{"malformed", ":", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FetchChain(tt.url)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("FetchChain() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != tt.want {
+				t.Errorf("FetchChain() returned %d certificates, want %d", len(got), tt.want)
+			}
+			if len(got) > 0 && ChainCA(got) != got[len(got)-1] {
+				t.Errorf("ChainCA() is not the last certificate of the chain")
+			}
+		})
+	}
+	if ChainCA(nil) != nil {
+		t.Errorf("ChainCA() of an empty chain should be nil")
+	}
+}
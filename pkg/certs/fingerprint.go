@@ -0,0 +1,68 @@
+package certs
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"hash"
+	"strings"
+)
+
+const (
+	// DefaultHashAlgorithm hash algorithm used for the fingerprints when none is configured
+	DefaultHashAlgorithm = "SHA256"
+)
+
+// Fingerprint returns the fingerprint of the certificate computed with
+// `algorithm`, formatted as colon separated uppercase hex pairs
+func Fingerprint(cert *x509.Certificate, algorithm string) (string, error) {
+	var h hash.Hash
+	switch strings.ToUpper(algorithm) {
+	case "", "SHA256":
+		h = sha256.New()
+	case "SHA1":
+		h = sha1.New()
+	default:
+		return "", fmt.Errorf("unsupported hash algorithm \"%s\"", algorithm)
+	}
+	h.Write(cert.Raw)
+
+	sum := h.Sum(nil)
+	pairs := make([]string, len(sum))
+	for i, b := range sum {
+		pairs[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(pairs, ":"), nil
+}
+
+// ParseCertificates decodes all the PEM encoded certificates in `data`
+func ParseCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, errors.New("no PEM encoded certificate found")
+	}
+	return certs, nil
+}
+
+// EncodeCertificate returns the PEM encoding of the certificate
+func EncodeCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
@@ -0,0 +1,95 @@
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestCertificate(t *testing.T) *x509.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %s", err)
+	}
+	return cert
+}
+
+func TestFingerprint(t *testing.T) {
+	cert := newTestCertificate(t)
+	tests := []struct {
+		name      string
+		algorithm string
+		wantPairs int
+		wantErr   bool
+	}{
+		{"default", "", 32, false},
+		{"sha256", "SHA256", 32, false},
+		{"sha1", "sha1", 20, false},
+		{"unsupported", "MD5", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Fingerprint(cert, tt.algorithm)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Fingerprint() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err == nil && len(strings.Split(got, ":")) != tt.wantPairs {
+				t.Errorf("Fingerprint() = %s, want %d pairs", got, tt.wantPairs)
+			}
+			if got != strings.ToUpper(got) {
+				t.Errorf("Fingerprint() = %s, want uppercase", got)
+			}
+		})
+	}
+}
+
+func TestParseCertificates(t *testing.T) {
+	cert := newTestCertificate(t)
+	pem := EncodeCertificate(cert)
+	tests := []struct {
+		name    string
+		data    []byte
+		want    int
+		wantErr bool
+	}{
+		{"one", pem, 1, false},
+		{"two", append(append([]byte{}, pem...), pem...), 2, false},
+		{"garbage", []byte("---- Cert Start ------ ACBDFEBABCDBFDBEBCDBABCDBABCBC"), 0, true},
+		{"empty", nil, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseCertificates(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseCertificates() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != tt.want {
+				t.Errorf("ParseCertificates() returned %d certificates, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,169 @@
+package check
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+var (
+	// timeout for getting an answer from a mirror
+	timeout = 10 * time.Second
+)
+
+// CertInfo struct
+// Summary of a certificate presented by a mirror
+type CertInfo struct {
+	Subject     string
+	Issuer      string
+	NotAfter    time.Time
+	Fingerprint string
+}
+
+// MirrorResult struct
+// Outcome of probing a mirror
+// Prefix: registry replaced by the mirror.
+// URL: url of the mirror registry.
+// Status: HTTP status code returned by the `/v2/` endpoint.
+// Latency: time spent on the `/v2/` request.
+// AuthRequired: the mirror requested authentication.
+// AuthChallenge: content of the `WWW-Authenticate` header.
+// Chain: certificates presented by the mirror during the TLS handshake.
+// Err: reason of the failure, nil when the mirror is healthy.
+type MirrorResult struct {
+	Prefix        string
+	URL           string
+	Status        int
+	Latency       time.Duration
+	AuthRequired  bool
+	AuthChallenge string
+	Chain         []CertInfo
+	Err           error
+}
+
+// Mirrors probes every mirror declared in the configuration
+func Mirrors(config *config.KubicInitConfiguration) ([]MirrorResult, error) {
+	if config == nil {
+		return nil, errors.New("configuration is nil")
+	}
+	var results []MirrorResult
+	for _, reg := range config.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		for _, mirror := range reg.Mirrors {
+			result := Mirror(mirror)
+			result.Prefix = reg.Prefix
+			results = append(results, result)
+		}
+	}
+	return results, nil
+}
+
+// Mirror performs a TLS handshake with the mirror, trusting the configured
+// certificate, and calls the Docker Registry v2 `/v2/` endpoint. The
+// certificate of insecure mirrors is not verified.
+func Mirror(mirror config.Mirror) MirrorResult {
+	result := MirrorResult{URL: mirror.URL}
+
+	u, err := url.Parse(mirror.URL)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	if u.Scheme != "https" && u.Scheme != "http" {
+		result.Err = fmt.Errorf("malformed Mirror URL \"%s\"", mirror.URL)
+		return result
+	}
+
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+	}
+	if u.Scheme == "https" {
+		tlsConfig, err := tlsConfig(mirror)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		chain, err := handshake(u, tlsConfig)
+		result.Chain = chain
+		if err != nil && (len(chain) == 0 || !mirror.Insecure) {
+			result.Err = fmt.Errorf("TLS handshake failed: %v", err)
+			return result
+		}
+		tlsConfig.InsecureSkipVerify = mirror.Insecure
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	client := &http.Client{Transport: transport, Timeout: timeout}
+	start := time.Now()
+	resp, err := client.Get(u.Scheme + "://" + u.Host + "/v2/")
+	result.Latency = time.Since(start)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	resp.Body.Close()
+
+	result.Status = resp.StatusCode
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusUnauthorized:
+		result.AuthRequired = true
+		result.AuthChallenge = resp.Header.Get("WWW-Authenticate")
+	default:
+		result.Err = fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return result
+}
+
+// tlsConfig trusts the system CAs plus the certificate of the mirror
+func tlsConfig(mirror config.Mirror) (*tls.Config, error) {
+	pool, err := x509.SystemCertPool()
+	if err != nil || pool == nil {
+		pool = x509.NewCertPool()
+	}
+	if mirror.Certificate != "" {
+		if !pool.AppendCertsFromPEM([]byte(mirror.Certificate)) {
+			return nil, errors.New("unable to parse the configured certificate")
+		}
+	}
+	return &tls.Config{RootCAs: pool}, nil
+}
+
+// handshake connects to the mirror and returns the presented chain
+func handshake(u *url.URL, tlsConfig *tls.Config) ([]CertInfo, error) {
+	presented, err := certs.FetchChain(u.String())
+	if err != nil {
+		return nil, err
+	}
+
+	chain := make([]CertInfo, 0, len(presented))
+	for _, cert := range presented {
+		fingerprint, _ := certs.Fingerprint(cert, certs.DefaultHashAlgorithm)
+		chain = append(chain, CertInfo{
+			Subject:     cert.Subject.String(),
+			Issuer:      cert.Issuer.String(),
+			NotAfter:    cert.NotAfter,
+			Fingerprint: fingerprint,
+		})
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range presented[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = presented[0].Verify(x509.VerifyOptions{
+		DNSName:       u.Hostname(),
+		Roots:         tlsConfig.RootCAs,
+		Intermediates: intermediates,
+	})
+	return chain, err
+}
@@ -0,0 +1,107 @@
+package check
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+func newRegistry(status int) *httptest.Server {
+	return httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if status == http.StatusUnauthorized {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="https://auth.example.com/token"`)
+		}
+		w.WriteHeader(status)
+	}))
+}
+
+func TestMirror(t *testing.T) {
+	healthy := newRegistry(http.StatusOK)
+	defer healthy.Close()
+	auth := newRegistry(http.StatusUnauthorized)
+	defer auth.Close()
+	broken := newRegistry(http.StatusInternalServerError)
+	defer broken.Close()
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer plain.Close()
+
+	ca := func(s *httptest.Server) string {
+		return string(certs.EncodeCertificate(s.Certificate()))
+	}
+
+	tests := []struct {
+		name       string
+		mirror     config.Mirror
+		wantStatus int
+		wantAuth   bool
+		wantChain  int
+		wantErr    bool
+	}{
+		{"healthy", config.Mirror{URL: healthy.URL, Certificate: ca(healthy)}, http.StatusOK, false, 1, false},
+		{"auth_required", config.Mirror{URL: auth.URL, Certificate: ca(auth)}, http.StatusUnauthorized, true, 1, false},
+		{"server_error", config.Mirror{URL: broken.URL, Certificate: ca(broken)}, http.StatusInternalServerError, false, 1, true},
+		{"untrusted", config.Mirror{URL: healthy.URL}, 0, false, 1, true},
+		{"untrusted_insecure", config.Mirror{URL: healthy.URL, Insecure: true}, http.StatusOK, false, 1, false},
+		{"invalid_ca", config.Mirror{URL: healthy.URL, Certificate: "---- Cert Start ------"}, 0, false, 0, true},
+		{"plain_http", config.Mirror{URL: plain.URL}, http.StatusOK, false, 0, false},
+		{"no_scheme", config.Mirror{URL: "second.mirror.com"}, 0, false, 0, true},
+		{"unreachable", config.Mirror{URL: "https://127.0.0.1:1"}, 0, false, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mirror(tt.mirror)
+			if (got.Err != nil) != tt.wantErr {
+				t.Errorf("Mirror() error = %v, wantErr %v", got.Err, tt.wantErr)
+			}
+			if got.Status != tt.wantStatus || got.AuthRequired != tt.wantAuth || len(got.Chain) != tt.wantChain {
+				t.Errorf("Mirror() = status %d, auth %v, chain %d, want status %d, auth %v, chain %d",
+					got.Status, got.AuthRequired, len(got.Chain), tt.wantStatus, tt.wantAuth, tt.wantChain)
+			}
+		})
+	}
+}
+
+func TestMirrors(t *testing.T) {
+	healthy := newRegistry(http.StatusOK)
+	defer healthy.Close()
+
+	cfg := &config.KubicInitConfiguration{
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "",
+					Mirrors: []config.Mirror{{URL: healthy.URL}},
+				},
+				{Prefix: "mycompany.registry.com",
+					Mirrors: []config.Mirror{
+						{URL: healthy.URL, Certificate: string(certs.EncodeCertificate(healthy.Certificate()))},
+						{URL: "second.mirror.com"},
+					},
+				},
+			},
+		},
+	}
+
+	if _, err := Mirrors(nil); err == nil {
+		t.Errorf("Mirrors() expected error for nil configuration")
+	}
+	results, err := Mirrors(cfg)
+	if err != nil {
+		t.Fatalf("Mirrors() error = %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("Mirrors() returned %d results, want 2", len(results))
+	}
+	if results[0].Err != nil || results[0].Prefix != "mycompany.registry.com" {
+		t.Errorf("Mirrors() first result = %+v, want healthy mirror of mycompany.registry.com", results[0])
+	}
+	if results[1].Err == nil {
+		t.Errorf("Mirrors() second result expected error")
+	}
+}
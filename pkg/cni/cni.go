@@ -0,0 +1,273 @@
+package cni
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+// version of the CNI specification of the configuration lists
+const version = "0.3.1"
+
+// driver struct
+// CNI driver supported by caasp-init
+// Plugins: binaries that have to be in the CNI bin directory.
+// Render: returns the plugins of the configuration list.
+type driver struct {
+	Plugins []string
+	Render  func(cfg *config.KubicInitConfiguration) []interface{}
+}
+
+var drivers = map[string]driver{
+	"bridge": {
+		Plugins: []string{"bridge", "host-local", "portmap", "loopback"},
+		Render:  bridgePlugins,
+	},
+	"flannel": {
+		Plugins: []string{"flannel", "bridge", "host-local", "portmap", "loopback"},
+		Render:  flannelPlugins,
+	},
+	"cilium": {
+		Plugins: []string{"cilium-cni", "loopback"},
+		Render:  ciliumPlugins,
+	},
+}
+
+// Drivers returns the names of the supported CNI drivers
+func Drivers() []string {
+	var names []string
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Plugins returns the plugin binaries needed by `driver`
+func Plugins(driver string) ([]string, error) {
+	d, err := lookup(driver)
+	if err != nil {
+		return nil, err
+	}
+	return d.Plugins, nil
+}
+
+// Path returns where the configuration list of `driver` is written in the
+// CNI configuration directory
+func Path(cfg *config.KubicInitConfiguration, driver string) string {
+	return filepath.Join(cfg.Network.Cni.ConfDir, fmt.Sprintf("10-caasp-init-%s.conflist", driver))
+}
+
+// CheckPlugins checks the plugin binaries of the driver are in the CNI bin
+// directory, before any file is written. A missing one is an error, as the
+// pods cannot start until it is installed, unless `allowMissingPlugins` is
+// set: they are then only logged as warnings.
+func CheckPlugins(cfg *config.KubicInitConfiguration) error {
+	if cfg == nil {
+		return errors.New("configuration is nil")
+	}
+	if cfg.Network.Cni.Driver == "" {
+		return nil
+	}
+	if _, err := lookup(cfg.Network.Cni.Driver); err != nil {
+		return err
+	}
+	missing := MissingPlugins(cfg)
+	if len(missing) > 0 && !cfg.Network.Cni.AllowMissingPlugins {
+		return fmt.Errorf("CNI plugins %s of the %s driver not found in %s, install them or set network.cni.allowMissingPlugins",
+			strings.Join(missing, ", "), cfg.Network.Cni.Driver, cfg.Network.Cni.BinDir)
+	}
+	for _, plugin := range missing {
+		log.WithFields(log.Fields{"driver": cfg.Network.Cni.Driver, "binDir": cfg.Network.Cni.BinDir}).Warnf("CNI plugin \"%s\" not found in %s", plugin, cfg.Network.Cni.BinDir)
+	}
+	return nil
+}
+
+// WriteConfigFile writes the CNI configuration list of the driver, removing
+// the ones of the other drivers. When no driver is set, the configuration
+// lists caasp-init wrote are removed.
+func WriteConfigFile(cfg *config.KubicInitConfiguration) error {
+	if cfg == nil {
+		return errors.New("configuration is nil")
+	}
+	if cfg.Network.Cni.Driver == "" {
+		return removeConfigFiles(cfg, "")
+	}
+
+	content, err := Render(cfg)
+	if err != nil {
+		return err
+	}
+
+	err = fsutil.MkdirAll(cfg.Network.Cni.ConfDir, 0755)
+	if err != nil {
+		return err
+	}
+	_, err = fsutil.WriteFile(Path(cfg, cfg.Network.Cni.Driver), content, os.FileMode(0644))
+	if err != nil {
+		return err
+	}
+	return removeConfigFiles(cfg, cfg.Network.Cni.Driver)
+}
+
+// removeConfigFiles removes the configuration lists of the drivers but `keep`
+func removeConfigFiles(cfg *config.KubicInitConfiguration, keep string) error {
+	for _, name := range Drivers() {
+		if name == keep {
+			continue
+		}
+		if _, err := fsutil.RemoveFile(Path(cfg, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Render returns the CNI configuration list of the driver
+func Render(cfg *config.KubicInitConfiguration) ([]byte, error) {
+	d, err := lookup(cfg.Network.Cni.Driver)
+	if err != nil {
+		return nil, err
+	}
+	conflist := map[string]interface{}{
+		"cniVersion": version,
+		"name":       cfg.Network.Cni.Driver,
+		"plugins":    d.Render(cfg),
+	}
+	b, err := json.MarshalIndent(conflist, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// MissingPlugins returns the plugin binaries of the driver that are not in
+// the CNI bin directory
+func MissingPlugins(cfg *config.KubicInitConfiguration) []string {
+	plugins, err := Plugins(cfg.Network.Cni.Driver)
+	if err != nil {
+		return nil
+	}
+	var missing []string
+	for _, plugin := range plugins {
+		info, err := os.Stat(filepath.Join(cfg.Network.Cni.BinDir, plugin))
+		if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+			missing = append(missing, plugin)
+		}
+	}
+	return missing
+}
+
+// Drift compares the CNI configuration list with the one of the driver
+func Drift(cfg *config.KubicInitConfiguration) ([]drift.Finding, error) {
+	if cfg == nil {
+		return nil, errors.New("configuration is nil")
+	}
+	if cfg.Network.Cni.Driver == "" {
+		return nil, nil
+	}
+	content, err := Render(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return drift.File(Path(cfg, cfg.Network.Cni.Driver), content)
+}
+
+func lookup(name string) (driver, error) {
+	d, ok := drivers[name]
+	if !ok {
+		return driver{}, fmt.Errorf("unknown CNI driver \"%s\", use one of %s", name, strings.Join(Drivers(), ", "))
+	}
+	return d, nil
+}
+
+// bridgePlugins connects the pods to a local bridge, with addresses of the
+// pod subnet. It does not route the pods of other nodes: it suits single
+// node clusters.
+func bridgePlugins(cfg *config.KubicInitConfiguration) []interface{} {
+	var ranges []interface{}
+	for _, subnet := range podSubnets(cfg) {
+		ranges = append(ranges, []interface{}{map[string]interface{}{"subnet": subnet}})
+	}
+	return []interface{}{
+		map[string]interface{}{
+			"type":             "bridge",
+			"bridge":           "cni0",
+			"isGateway":        true,
+			"ipMasq":           true,
+			"hairpinMode":      true,
+			"isDefaultGateway": true,
+			"ipam": map[string]interface{}{
+				"type":   "host-local",
+				"ranges": ranges,
+			},
+		},
+		portmapPlugin(),
+	}
+}
+
+// flannelPlugins delegates to the bridge plugin with the subnet flannel
+// allocated to the node from the pod subnet. The routes of the pod subnet
+// are added to the ones flannel passes to the host-local IPAM of the bridge.
+func flannelPlugins(cfg *config.KubicInitConfiguration) []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"type": "flannel",
+			"ipam": map[string]interface{}{"routes": podRoutes(cfg)},
+			"delegate": map[string]interface{}{
+				"hairpinMode":      true,
+				"isDefaultGateway": true,
+			},
+		},
+		portmapPlugin(),
+	}
+}
+
+// ciliumPlugins uses the cilium agent, which allocates the addresses of the
+// node from the pod subnet. The routes of the pod subnet are set for the
+// IPAM of the plugin, so that the list follows `network.podSubnet`.
+func ciliumPlugins(cfg *config.KubicInitConfiguration) []interface{} {
+	return []interface{}{
+		map[string]interface{}{
+			"type": "cilium-cni",
+			"ipam": map[string]interface{}{"routes": podRoutes(cfg)},
+		},
+	}
+}
+
+// podSubnets returns the subnets of `network.podSubnet`, two for dual-stack
+// clusters
+func podSubnets(cfg *config.KubicInitConfiguration) []string {
+	var subnets []string
+	for _, subnet := range strings.Split(cfg.Network.PodSubnet, ",") {
+		if subnet = strings.TrimSpace(subnet); subnet != "" {
+			subnets = append(subnets, subnet)
+		}
+	}
+	return subnets
+}
+
+// podRoutes returns the routes of the pod subnets
+func podRoutes(cfg *config.KubicInitConfiguration) []interface{} {
+	var routes []interface{}
+	for _, subnet := range podSubnets(cfg) {
+		routes = append(routes, map[string]interface{}{"dst": subnet})
+	}
+	return routes
+}
+
+func portmapPlugin() interface{} {
+	return map[string]interface{}{
+		"type":         "portmap",
+		"capabilities": map[string]interface{}{"portMappings": true},
+	}
+}
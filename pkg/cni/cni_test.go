@@ -0,0 +1,191 @@
+package cni
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+)
+
+func newConfig(tmpDir, driver string) *config.KubicInitConfiguration {
+	return &config.KubicInitConfiguration{
+		Network: config.NetworkConfiguration{
+			PodSubnet: "172.16.0.0/13, fd00:10::/56",
+			Cni: config.CniConfiguration{
+				Driver:  driver,
+				BinDir:  filepath.Join(tmpDir, "bin"),
+				ConfDir: filepath.Join(tmpDir, "net.d"),
+			},
+		},
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		driver  string
+		want    string
+		wantErr bool
+	}{
+		{"bridge", "bridge", `{"cniVersion":"0.3.1","name":"bridge","plugins":[` +
+			`{"bridge":"cni0","hairpinMode":true,"ipMasq":true,"ipam":{"ranges":[[{"subnet":"172.16.0.0/13"}],[{"subnet":"fd00:10::/56"}]],"type":"host-local"},"isDefaultGateway":true,"isGateway":true,"type":"bridge"},` +
+			`{"capabilities":{"portMappings":true},"type":"portmap"}]}`, false},
+		{"flannel", "flannel", `{"cniVersion":"0.3.1","name":"flannel","plugins":[` +
+			`{"delegate":{"hairpinMode":true,"isDefaultGateway":true},"ipam":{"routes":[{"dst":"172.16.0.0/13"},{"dst":"fd00:10::/56"}]},"type":"flannel"},` +
+			`{"capabilities":{"portMappings":true},"type":"portmap"}]}`, false},
+		{"cilium", "cilium", `{"cniVersion":"0.3.1","name":"cilium","plugins":[` +
+			`{"ipam":{"routes":[{"dst":"172.16.0.0/13"},{"dst":"fd00:10::/56"}]},"type":"cilium-cni"}]}`, false},
+		{"unknown", "weave", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(newConfig("/tmp", tt.driver))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var compact map[string]interface{}
+			if err := json.Unmarshal(got, &compact); err != nil {
+				t.Fatalf("Render() = %s: %v", got, err)
+			}
+			b, _ := json.Marshal(compact)
+			if string(b) != tt.want {
+				t.Errorf("Render() = %s, want %s", b, tt.want)
+			}
+			for _, subnet := range []string{"172.16.0.0/13", "fd00:10::/56"} {
+				if !strings.Contains(string(got), `"`+subnet+`"`) {
+					t.Errorf("Render() = %s without the pod subnet %s", got, subnet)
+				}
+			}
+		})
+	}
+}
+
+// installPlugins writes executable plugin binaries of the driver in the CNI
+// bin directory
+func installPlugins(t *testing.T, cfg *config.KubicInitConfiguration) {
+	plugins, err := Plugins(cfg.Network.Cni.Driver)
+	if err != nil {
+		t.Fatalf("Plugins() error = %v", err)
+	}
+	os.MkdirAll(cfg.Network.Cni.BinDir, 0755)
+	for _, plugin := range plugins {
+		if err := ioutil.WriteFile(filepath.Join(cfg.Network.Cni.BinDir, plugin), nil, 0755); err != nil {
+			t.Fatalf("writing plugin: %s", err)
+		}
+	}
+}
+
+func TestWriteConfigFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-cni")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := WriteConfigFile(newConfig(tmpDir, "")); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v without driver", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "net.d")); !os.IsNotExist(err) {
+		t.Errorf("WriteConfigFile() wrote a configuration without driver")
+	}
+
+	flannel := newConfig(tmpDir, "flannel")
+	if err := WriteConfigFile(flannel); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v", err)
+	}
+	if got, err := Drift(flannel); err != nil || got != nil {
+		t.Errorf("Drift() = %v, %v after WriteConfigFile()", got, err)
+	}
+
+	// switching driver removes the configuration of the previous one
+	bridge := newConfig(tmpDir, "bridge")
+	if err := WriteConfigFile(bridge); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v", err)
+	}
+	files, _ := filepath.Glob(filepath.Join(tmpDir, "net.d", "*"))
+	if want := []string{Path(bridge, "bridge")}; !reflect.DeepEqual(files, want) {
+		t.Errorf("WriteConfigFile() files = %v, want %v", files, want)
+	}
+	want := []drift.Finding{{Path: Path(flannel, "flannel"), Owner: drift.Owned, Kind: drift.Missing}}
+	if got, _ := Drift(flannel); !reflect.DeepEqual(got, want) {
+		t.Errorf("Drift() = %v, want %v", got, want)
+	}
+
+	// unsetting the driver removes the configuration list
+	if err := WriteConfigFile(newConfig(tmpDir, "")); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v without driver", err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(tmpDir, "net.d", "*")); len(files) != 0 {
+		t.Errorf("WriteConfigFile() kept %v without driver", files)
+	}
+
+	if err := WriteConfigFile(newConfig(tmpDir, "weave")); err == nil {
+		t.Errorf("WriteConfigFile() accepted an unknown driver")
+	}
+	if err := WriteConfigFile(nil); err == nil {
+		t.Errorf("WriteConfigFile() accepted a nil configuration")
+	}
+}
+
+func TestCheckPlugins(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-cni")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	if err := CheckPlugins(newConfig(tmpDir, "")); err != nil {
+		t.Errorf("CheckPlugins() error = %v without driver", err)
+	}
+	if err := CheckPlugins(newConfig(tmpDir, "weave")); err == nil {
+		t.Errorf("CheckPlugins() accepted an unknown driver")
+	}
+
+	// missing plugins fail unless they are allowed
+	flannel := newConfig(tmpDir, "flannel")
+	if err := CheckPlugins(flannel); err == nil {
+		t.Errorf("CheckPlugins() accepted missing plugins")
+	}
+	flannel.Network.Cni.AllowMissingPlugins = true
+	if err := CheckPlugins(flannel); err != nil {
+		t.Errorf("CheckPlugins() error = %v with allowMissingPlugins", err)
+	}
+	flannel.Network.Cni.AllowMissingPlugins = false
+	installPlugins(t, flannel)
+	if err := CheckPlugins(flannel); err != nil {
+		t.Errorf("CheckPlugins() error = %v with the plugins installed", err)
+	}
+}
+
+func TestMissingPlugins(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-cni")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfg := newConfig(tmpDir, "bridge")
+	os.MkdirAll(filepath.Join(cfg.Network.Cni.BinDir, "portmap"), 0755)
+	for plugin, mode := range map[string]os.FileMode{"bridge": 0755, "host-local": 0644} {
+		if err := ioutil.WriteFile(filepath.Join(cfg.Network.Cni.BinDir, plugin), nil, mode); err != nil {
+			t.Fatalf("writing plugin: %s", err)
+		}
+	}
+
+	want := []string{"host-local", "portmap", "loopback"}
+	if got := MissingPlugins(cfg); !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingPlugins() = %v, want %v", got, want)
+	}
+	if got := MissingPlugins(newConfig(tmpDir, "weave")); got != nil {
+		t.Errorf("MissingPlugins() = %v for an unknown driver", got)
+	}
+}
@@ -4,10 +4,11 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 
 	yaml "gopkg.in/yaml.v2"
 
-	"github.com/golang/glog"
+	"github.com/kubic-project/caasp-init/pkg/log"
 )
 
 const (
@@ -22,22 +23,58 @@ const (
 
 	// DefaultAPIServerPort Default API server port
 	DefaultAPIServerPort = 6443
+
+	// DefaultRuntimeEngine Default container runtime engine
+	DefaultRuntimeEngine = "docker"
+
+	// DefaultDockerCompat Default compatibility mode of the docker daemon configuration
+	DefaultDockerCompat = "suse"
+
+	// DefaultBindFamily Default IP family of the bind address resolved from the interface
+	DefaultBindFamily = "ipv4"
+
+	// DefaultKubeadmPath Default path to the kubeadm binary
+	DefaultKubeadmPath = "/usr/bin/kubeadm"
+
+	// DefaultCertsDirectory Default directory for the cluster certificates
+	DefaultCertsDirectory = "/etc/kubernetes/pki"
+
+	// DefaultPodSubnet Default subnet used for the pods
+	DefaultPodSubnet = "172.16.0.0/13"
+
+	// DefaultServiceSubnet Default subnet used for the services
+	DefaultServiceSubnet = "172.24.0.0/16"
+
+	// DefaultDNSDomain Default cluster DNS domain
+	DefaultDNSDomain = "cluster.local"
+
+	// DefaultCniBinDir Default directory for the CNI plugin binaries
+	DefaultCniBinDir = "/var/lib/kubelet/cni/bin"
+
+	// DefaultCniConfDir Default directory for the CNI configuration files
+	DefaultCniConfDir = "/etc/cni/net.d"
 )
 
 // CniConfiguration The CNI configuration
 // Subnets details are specified in the kubeadm configuration file
+// AllowMissingPlugins: write the configuration list even when plugin
+// binaries are missing, for the ones installed later by the driver.
 type CniConfiguration struct {
-	BinDir  string `yaml:"binDir,omitempty"`
-	ConfDir string `yaml:"confDir,omitempty"`
-	Driver  string `yaml:"driver,omitempty"`
-	Image   string `yaml:"image,omitempty"`
+	BinDir              string `yaml:"binDir,omitempty"`
+	ConfDir             string `yaml:"confDir,omitempty"`
+	Driver              string `yaml:"driver,omitempty"`
+	Image               string `yaml:"image,omitempty"`
+	AllowMissingPlugins bool   `yaml:"allowMissingPlugins,omitempty"`
 }
 
 // ClusterFormationConfiguration struct
+// Role: role of the node, `seeder` or `join`, detected from `Seeder` when
+// not set.
 type ClusterFormationConfiguration struct {
 	Seeder      string `yaml:"seeder,omitempty"`
 	Token       string `yaml:"token,omitempty"`
 	AutoApprove bool   `yaml:"autoApprove,omitempty"`
+	Role        string `yaml:"role,omitempty"`
 }
 
 // OIDCConfiguration struct
@@ -55,10 +92,12 @@ type AuthConfiguration struct {
 }
 
 // CertsConfiguration struct
+// UnsafeSkipCAVerification: join without `CaHash`, trusting the CA of the seeder.
 type CertsConfiguration struct {
 	// TODO
-	Directory string `yaml:"directory,omitempty"`
-	CaHash    string `yaml:"caCrtHash,omitempty"`
+	Directory                string `yaml:"directory,omitempty"`
+	CaHash                   string `yaml:"caCrtHash,omitempty"`
+	UnsafeSkipCAVerification bool   `yaml:"unsafeSkipCAVerification,omitempty"`
 }
 
 // DNSConfiguration struct
@@ -76,9 +115,13 @@ type ProxyConfiguration struct {
 }
 
 // BindConfiguration struct
+// Address: IP address of the node, resolved from `Interface` when not set.
+// Interface: network interface of the node.
+// Family: IP family of the address resolved from `Interface`, `ipv4` or `ipv6`.
 type BindConfiguration struct {
 	Address   string `yaml:"address,omitempty"`
 	Interface string `yaml:"interface,omitempty"`
+	Family    string `yaml:"family,omitempty"`
 }
 
 // PathsConfigration struct
@@ -87,9 +130,14 @@ type PathsConfigration struct {
 }
 
 // LocalEtcdConfiguration struct
+// ServerCertSANs, PeerCertSANs: extra IP addresses or DNS names of the etcd
+// certificates.
+// AutoSANs: add the name, the bind address and the external FQDN of the node
+// to both lists.
 type LocalEtcdConfiguration struct {
 	ServerCertSANs []string `yaml:"serverCertSANs,omitempty"`
 	PeerCertSANs   []string `yaml:"peerCertSANs,omitempty"`
+	AutoSANs       bool     `yaml:"autoSANs,omitempty"`
 }
 
 // EtcdConfiguration struct
@@ -98,6 +146,9 @@ type EtcdConfiguration struct {
 }
 
 // NetworkConfiguration struct
+// PodSubnet, ServiceSubnet: subnets in CIDR notation, or an IPv4 and an IPv6
+// one separated by a comma for dual-stack clusters.
+// ExpectedNodes: number of nodes the pods subnet has to make room for.
 type NetworkConfiguration struct {
 	Bind          BindConfiguration  `yaml:"bind,omitempty"`
 	Cni           CniConfiguration   `yaml:"cni,omitempty"`
@@ -105,11 +156,17 @@ type NetworkConfiguration struct {
 	Proxy         ProxyConfiguration `yaml:"proxy,omitempty"`
 	PodSubnet     string             `yaml:"podSubnet,omitempty"`
 	ServiceSubnet string             `yaml:"serviceSubnet,omitempty"`
+	ExpectedNodes int                `yaml:"expectedNodes,omitempty"`
 }
 
 // RuntimeConfiguration struct
+// Engine: container runtime used in the node.
+// DockerCompat: flavor of docker the daemon.json file is written for,
+// `suse` for SUSE's docker with per registry mirrors, `upstream` for the
+// `registry-mirrors` of the upstream docker, or `auto` for detecting it.
 type RuntimeConfiguration struct {
-	Engine string `yaml:"engine,omitempty"`
+	Engine       string `yaml:"engine,omitempty"`
+	DockerCompat string `yaml:"dockerCompat,omitempty"`
 }
 
 // FeaturesConfiguration struct
@@ -142,14 +199,26 @@ type Registry struct {
 // Certificate: certificate content for the registry.
 // Fingerprint: fingerprint of the certificate to check validity.
 // HashAlgorithm: hash algorithm used.
+// Username: user name for authenticating in the mirror.
+// Password: password for authenticating in the mirror.
+// PasswordFile: file containing the password, instead of `Password`.
+// IdentityToken: token for authenticating in the mirror.
+// Insecure: reach the mirror over plain HTTP or without verifying its certificate.
 type Mirror struct {
 	URL           string `yaml:"url"`
 	Certificate   string `yaml:"certificate,omitempty"`
 	Fingerprint   string `yaml:"fingerprint,omitempty"`
 	HashAlgorithm string `yaml:"hashalgorithm,omitempty"`
+	Username      string `yaml:"username,omitempty"`
+	Password      string `yaml:"password,omitempty"`
+	PasswordFile  string `yaml:"passwordFile,omitempty"`
+	IdentityToken string `yaml:"identityToken,omitempty"`
+	Insecure      bool   `yaml:"insecure,omitempty"`
 }
 
 // KubicInitConfiguration The kubic-init configuration
+// Roles: sections of the configuration applied only on the nodes with a
+// role, by role name. They are removed once the one of the node is applied.
 type KubicInitConfiguration struct {
 	Network          NetworkConfiguration          `yaml:"network,omitempty"`
 	Paths            PathsConfigration             `yaml:"paths,omitempty"`
@@ -161,6 +230,7 @@ type KubicInitConfiguration struct {
 	Services         ServicesConfiguration         `yaml:"services,omitempty"`
 	Auth             AuthConfiguration             `yaml:"auth,omitempty"`
 	Bootstrap        BootstrapConfiguration        `yaml:"bootstrap,omitempty"`
+	Roles            map[string]interface{}        `yaml:"roles,omitempty"`
 }
 
 // FileAndDefaultsToKubicInitConfig Load a Kubic configuration file, setting some default values
@@ -170,7 +240,7 @@ func FileAndDefaultsToKubicInitConfig(cfgPath string) (*KubicInitConfiguration,
 	internalcfg := &KubicInitConfiguration{}
 
 	if len(cfgPath) > 0 {
-		glog.V(1).Infof("[caasp-init] loading kubic-init configuration from '%s'", cfgPath)
+		log.Debugf("loading kubic-init configuration from '%s'", cfgPath)
 		if _, err = os.Stat(cfgPath); err != nil {
 			return nil, fmt.Errorf("%q does not exist: %v", cfgPath, err)
 		}
@@ -183,7 +253,72 @@ func FileAndDefaultsToKubicInitConfig(cfgPath string) (*KubicInitConfiguration,
 		if err = yaml.Unmarshal(b, &internalcfg); err != nil {
 			return nil, fmt.Errorf("unable to decode config from bytes: %v", err)
 		}
+
+		dropIns, err := DropIns(cfgPath)
+		if err != nil {
+			return nil, err
+		}
+		for _, dropIn := range dropIns {
+			log.Debugf("loading kubic-init configuration drop-in '%s'", dropIn)
+			b, err := ioutil.ReadFile(dropIn)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read config from %q [%v]", dropIn, err)
+			}
+			if err = yaml.Unmarshal(b, internalcfg); err != nil {
+				return nil, fmt.Errorf("unable to decode config from %q: %v", dropIn, err)
+			}
+		}
 	}
 
+	setDefaults(internalcfg)
+
 	return internalcfg, nil
 }
+
+// DropInDir returns the directory of the drop-in files of the configuration
+// file at `cfgPath`. The `*.yaml` files in it are loaded in lexical order
+// after the configuration file, each one overriding the fields it sets: the
+// objects are merged field by field but the lists are replaced as a whole.
+func DropInDir(cfgPath string) string {
+	return cfgPath + ".d"
+}
+
+// DropIns returns the drop-in files of the configuration file at `cfgPath`,
+// in the order they are loaded
+func DropIns(cfgPath string) ([]string, error) {
+	return filepath.Glob(filepath.Join(DropInDir(cfgPath), "*.yaml"))
+}
+
+// setDefaults fills the fields not present in the configuration file
+func setDefaults(cfg *KubicInitConfiguration) {
+	if cfg.Runtime.Engine == "" {
+		cfg.Runtime.Engine = DefaultRuntimeEngine
+	}
+	if cfg.Runtime.DockerCompat == "" {
+		cfg.Runtime.DockerCompat = DefaultDockerCompat
+	}
+	if cfg.Network.Bind.Family == "" {
+		cfg.Network.Bind.Family = DefaultBindFamily
+	}
+	if cfg.Paths.Kubeadm == "" {
+		cfg.Paths.Kubeadm = DefaultKubeadmPath
+	}
+	if cfg.Certificates.Directory == "" {
+		cfg.Certificates.Directory = DefaultCertsDirectory
+	}
+	if cfg.Network.PodSubnet == "" {
+		cfg.Network.PodSubnet = DefaultPodSubnet
+	}
+	if cfg.Network.ServiceSubnet == "" {
+		cfg.Network.ServiceSubnet = DefaultServiceSubnet
+	}
+	if cfg.Network.DNS.Domain == "" {
+		cfg.Network.DNS.Domain = DefaultDNSDomain
+	}
+	if cfg.Network.Cni.BinDir == "" {
+		cfg.Network.Cni.BinDir = DefaultCniBinDir
+	}
+	if cfg.Network.Cni.ConfDir == "" {
+		cfg.Network.Cni.ConfDir = DefaultCniConfDir
+	}
+}
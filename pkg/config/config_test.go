@@ -20,6 +20,7 @@ package config
 import (
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -191,3 +192,105 @@ func TestFileAndDefaultsToKubicInitConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestFileAndDefaultsToKubicInitConfigDropIns(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-config")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	cfgPath := filepath.Join(tmpDir, "kubic-init.yaml")
+	files := map[string]string{
+		cfgPath: "runtime:\n  engine: crio\nnetwork:\n  podSubnet: 10.0.0.0/16\n",
+		filepath.Join(DropInDir(cfgPath), "10-mirrors.yaml"): "bootstrap:\n  registries:\n    - prefix: docker.io\n      mirrors:\n        - url: https://first.mirror.com\n",
+		filepath.Join(DropInDir(cfgPath), "20-mirrors.yaml"): "bootstrap:\n  registries:\n    - prefix: docker.io\n      mirrors:\n        - url: https://second.mirror.com\n",
+		filepath.Join(DropInDir(cfgPath), "30-ignored.conf"): "runtime:\n  engine: docker\n",
+	}
+	for path, content := range files {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("creating dir: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+	}
+
+	got, err := FileAndDefaultsToKubicInitConfig(cfgPath)
+	if err != nil {
+		t.Fatalf("FileAndDefaultsToKubicInitConfig() error = %v", err)
+	}
+	if got.Runtime.Engine != "crio" || got.Network.PodSubnet != "10.0.0.0/16" {
+		t.Errorf("FileAndDefaultsToKubicInitConfig() lost the fields of the configuration file: %+v", got)
+	}
+	want := []Registry{{Prefix: "docker.io", Mirrors: []Mirror{{URL: "https://second.mirror.com"}}}}
+	if !reflect.DeepEqual(got.Bootstrap.Registries, want) {
+		t.Errorf("FileAndDefaultsToKubicInitConfig() registries = %v, want %v", got.Bootstrap.Registries, want)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(DropInDir(cfgPath), "40-broken.yaml"), []byte("runtime: ["), 0644); err != nil {
+		t.Fatalf("writing drop-in: %s", err)
+	}
+	if _, err := FileAndDefaultsToKubicInitConfig(cfgPath); err == nil {
+		t.Errorf("FileAndDefaultsToKubicInitConfig() accepted a malformed drop-in")
+	}
+}
+
+func TestFileAndDefaultsToKubicInitConfigDefaults(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-config")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	empty := filepath.Join(tmpDir, "empty.yaml")
+	explicit := filepath.Join(tmpDir, "explicit.yaml")
+	files := map[string]string{
+		empty:    "bootstrap: {}\n",
+		explicit: "runtime:\n  engine: crio\nnetwork:\n  podSubnet: 10.0.0.0/16\n  serviceSubnet: 10.1.0.0/16\n  cni:\n    binDir: /opt/cni/bin\ncertificates:\n  directory: /srv/pki\n",
+	}
+	for path, content := range files {
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+	}
+
+	got, err := FileAndDefaultsToKubicInitConfig(empty)
+	if err != nil {
+		t.Fatalf("FileAndDefaultsToKubicInitConfig() error = %v", err)
+	}
+	// every default filled by the loader is the one documented by explain
+	defaults := map[string]string{
+		"runtime.engine":         got.Runtime.Engine,
+		"runtime.dockerCompat":   got.Runtime.DockerCompat,
+		"network.bind.family":    got.Network.Bind.Family,
+		"paths.kubeadm":          got.Paths.Kubeadm,
+		"certificates.directory": got.Certificates.Directory,
+		"network.podSubnet":      got.Network.PodSubnet,
+		"network.serviceSubnet":  got.Network.ServiceSubnet,
+		"network.dns.domain":     got.Network.DNS.Domain,
+		"network.cni.binDir":     got.Network.Cni.BinDir,
+		"network.cni.confDir":    got.Network.Cni.ConfDir,
+	}
+	for path, value := range defaults {
+		info, err := Explain(path)
+		if err != nil {
+			t.Fatalf("Explain(%s) error = %v", path, err)
+		}
+		if value == "" || value != info.Doc.Default {
+			t.Errorf("%s defaults to %q, explain documents %q", path, value, info.Doc.Default)
+		}
+	}
+
+	got, err = FileAndDefaultsToKubicInitConfig(explicit)
+	if err != nil {
+		t.Fatalf("FileAndDefaultsToKubicInitConfig() error = %v", err)
+	}
+	if got.Runtime.Engine != "crio" || got.Network.PodSubnet != "10.0.0.0/16" || got.Network.ServiceSubnet != "10.1.0.0/16" ||
+		got.Network.Cni.BinDir != "/opt/cni/bin" || got.Certificates.Directory != "/srv/pki" {
+		t.Errorf("FileAndDefaultsToKubicInitConfig() overrode the configured values: %+v", got)
+	}
+	if got.Network.Cni.ConfDir != DefaultCniConfDir {
+		t.Errorf("FileAndDefaultsToKubicInitConfig() confDir = %q, want %q", got.Network.Cni.ConfDir, DefaultCniConfDir)
+	}
+}
@@ -0,0 +1,124 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// secretFields are the fields whose values are never shown, by doc path
+var secretFields = map[string]bool{
+	"clusterFormation.token":                     true,
+	"bootstrap.registries.mirrors.password":      true,
+	"bootstrap.registries.mirrors.identityToken": true,
+}
+
+// FieldChange struct
+// A field whose value differs between two configurations
+// Path: path of the field, with the index of the list items.
+// Old: previous value, redacted for secrets.
+// New: new value, redacted for secrets.
+type FieldChange struct {
+	Path string
+	Old  string
+	New  string
+}
+
+func (c FieldChange) String() string {
+	return fmt.Sprintf("%s: %s -> %s", c.Path, c.Old, c.New)
+}
+
+// Diff returns the fields that changed from `old` to `new`. The values of
+// secrets are redacted and multi-line values are summarized. Objects behind a
+// pointer are compared field by field, a missing one as an empty object.
+func Diff(old, new *KubicInitConfiguration) []FieldChange {
+	if old == nil {
+		old = &KubicInitConfiguration{}
+	}
+	if new == nil {
+		new = &KubicInitConfiguration{}
+	}
+	var changes []FieldChange
+	diffValues("", "", reflect.ValueOf(*old), reflect.ValueOf(*new), &changes)
+	return changes
+}
+
+func diffValues(path, docPath string, old, new reflect.Value, changes *[]FieldChange) {
+	switch old.Kind() {
+	case reflect.Struct:
+		for i := 0; i < old.NumField(); i++ {
+			name := fieldName(old.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			diffValues(joinPath(path, name), joinPath(docPath, name), old.Field(i), new.Field(i), changes)
+		}
+	case reflect.Ptr:
+		if old.IsNil() && new.IsNil() {
+			return
+		}
+		zero := reflect.Zero(old.Type().Elem())
+		o, n := zero, zero
+		if !old.IsNil() {
+			o = old.Elem()
+		}
+		if !new.IsNil() {
+			n = new.Elem()
+		}
+		diffValues(path, docPath, o, n, changes)
+	case reflect.Slice:
+		if old.Type().Elem().Kind() != reflect.Struct {
+			addChange(path, docPath, old, new, changes)
+			return
+		}
+		zero := reflect.Zero(old.Type().Elem())
+		for i := 0; i < old.Len() || i < new.Len(); i++ {
+			o, n := zero, zero
+			if i < old.Len() {
+				o = old.Index(i)
+			}
+			if i < new.Len() {
+				n = new.Index(i)
+			}
+			diffValues(fmt.Sprintf("%s[%d]", path, i), docPath, o, n, changes)
+		}
+	default:
+		addChange(path, docPath, old, new, changes)
+	}
+}
+
+func addChange(path, docPath string, old, new reflect.Value, changes *[]FieldChange) {
+	if reflect.DeepEqual(old.Interface(), new.Interface()) {
+		return
+	}
+	*changes = append(*changes, FieldChange{
+		Path: path,
+		Old:  showValue(docPath, old),
+		New:  showValue(docPath, new),
+	})
+}
+
+// showValue formats a value for the logs
+func showValue(docPath string, v reflect.Value) string {
+	if reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface()) {
+		return "(unset)"
+	}
+	if secretFields[docPath] {
+		return "(redacted)"
+	}
+	if v.Kind() == reflect.String {
+		s := strings.TrimSpace(v.String())
+		if strings.Contains(s, "\n") {
+			return fmt.Sprintf("(%d lines)", strings.Count(s, "\n")+1)
+		}
+		return fmt.Sprintf("%q", s)
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
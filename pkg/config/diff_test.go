@@ -0,0 +1,59 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	mirrors := func(mirrors ...Mirror) *KubicInitConfiguration {
+		return &KubicInitConfiguration{
+			Bootstrap: BootstrapConfiguration{
+				Registries: []Registry{{Prefix: "docker.io", Mirrors: mirrors}},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		old  *KubicInitConfiguration
+		new  *KubicInitConfiguration
+		want []FieldChange
+	}{
+		{"same", mirrors(Mirror{URL: "https://a.com"}), mirrors(Mirror{URL: "https://a.com"}), nil},
+		{"url_changed", mirrors(Mirror{URL: "https://a.com"}), mirrors(Mirror{URL: "https://b.com"}), []FieldChange{
+			{"bootstrap.registries[0].mirrors[0].url", `"https://a.com"`, `"https://b.com"`},
+		}},
+		{"mirror_added", mirrors(Mirror{URL: "https://a.com"}), mirrors(Mirror{URL: "https://a.com"}, Mirror{URL: "https://b.com", Insecure: true}), []FieldChange{
+			{"bootstrap.registries[0].mirrors[1].url", "(unset)", `"https://b.com"`},
+			{"bootstrap.registries[0].mirrors[1].insecure", "(unset)", "true"},
+		}},
+		{"password_redacted", mirrors(Mirror{URL: "https://a.com", Username: "user", Password: "old"}), mirrors(Mirror{URL: "https://a.com", Username: "user", Password: "new"}), []FieldChange{
+			{"bootstrap.registries[0].mirrors[0].password", "(redacted)", "(redacted)"},
+		}},
+		{"certificate_summarized", mirrors(Mirror{URL: "https://a.com"}), mirrors(Mirror{URL: "https://a.com", Certificate: "-----BEGIN CERTIFICATE-----\nMIIB\n-----END CERTIFICATE-----\n"}), []FieldChange{
+			{"bootstrap.registries[0].mirrors[0].certificate", "(unset)", "(3 lines)"},
+		}},
+		{"token_redacted", &KubicInitConfiguration{}, &KubicInitConfiguration{ClusterFormation: ClusterFormationConfiguration{Token: "94dcda.c271f4ff502789ca"}}, []FieldChange{
+			{"clusterFormation.token", "(unset)", "(redacted)"},
+		}},
+		{"pointer", &KubicInitConfiguration{}, &KubicInitConfiguration{Etcd: EtcdConfiguration{LocalEtcd: &LocalEtcdConfiguration{AutoSANs: true}}}, []FieldChange{
+			{"etcd.local.autoSANs", "(unset)", "true"},
+		}},
+		{"pointer_field_changed",
+			&KubicInitConfiguration{Etcd: EtcdConfiguration{LocalEtcd: &LocalEtcdConfiguration{ServerCertSANs: []string{"etcd.example.com"}}}},
+			&KubicInitConfiguration{Etcd: EtcdConfiguration{LocalEtcd: &LocalEtcdConfiguration{ServerCertSANs: []string{"etcd.example.com"}, PeerCertSANs: []string{"192.168.1.10"}}}},
+			[]FieldChange{
+				{"etcd.local.peerCertSANs", "(unset)", "[192.168.1.10]"},
+			}},
+		{"nil", nil, &KubicInitConfiguration{Runtime: RuntimeConfiguration{Engine: "crio"}}, []FieldChange{
+			{"runtime.engine", "(unset)", `"crio"`},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Diff(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
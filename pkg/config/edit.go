@@ -0,0 +1,228 @@
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+)
+
+var (
+	mirrorURLLine  = regexp.MustCompile(`^(\s*)(-\s+)?url:\s*(.*?)\s*$`)
+	mirrorItemLine = regexp.MustCompile(`^(\s*-\s+)[A-Za-z0-9_]+:`)
+	mappingKey     = regexp.MustCompile(`^(\s*)([A-Za-z0-9_]+):(\s+(.*))?$`)
+)
+
+// SetMirrorCertificate sets the certificate, fingerprint and hash algorithm
+// of every mirror with `mirrorURL` in the configuration file at `cfgPath`.
+// The file is edited in place so comments and the ordering of the keys are
+// kept, and it is only written when the result can still be loaded, by
+// replacing it so that an interrupted write leaves it unchanged.
+func SetMirrorCertificate(cfgPath, mirrorURL, certificate, fingerprint, algorithm string) error {
+	info, err := os.Stat(cfgPath)
+	if err != nil {
+		return fmt.Errorf("%q does not exist: %v", cfgPath, err)
+	}
+	b, err := ioutil.ReadFile(cfgPath)
+	if err != nil {
+		return fmt.Errorf("unable to read config from %q [%v]", cfgPath, err)
+	}
+
+	lines := strings.Split(string(b), "\n")
+	found := false
+	for i := 0; i < len(lines); i++ {
+		m := mirrorURLLine.FindStringSubmatch(lines[i])
+		if m == nil || !sameURL(unquote(m[3]), mirrorURL) {
+			continue
+		}
+		found = true
+		keyCol := len(m[1]) + len(m[2])
+		lines = setMirrorKeys(lines, i, keyCol, []string{
+			"certificate: |",
+			indentBlock(strings.TrimSpace(certificate), "  "),
+			fmt.Sprintf("fingerprint: \"%s\"", fingerprint),
+			fmt.Sprintf("hashalgorithm: \"%s\"", algorithm),
+		})
+	}
+	if !found {
+		return fmt.Errorf("mirror \"%s\" is not declared in %q", mirrorURL, cfgPath)
+	}
+
+	content := strings.Join(lines, "\n")
+	cfg := &KubicInitConfiguration{}
+	if err := yaml.Unmarshal([]byte(content), cfg); err != nil {
+		return fmt.Errorf("unable to update %q: %v", cfgPath, err)
+	}
+	for _, reg := range cfg.Bootstrap.Registries {
+		for _, mirror := range reg.Mirrors {
+			if sameURL(mirror.URL, mirrorURL) && strings.TrimSpace(mirror.Certificate) != strings.TrimSpace(certificate) {
+				return fmt.Errorf("unable to update %q: the layout of the mirror \"%s\" is not supported", cfgPath, mirrorURL)
+			}
+		}
+	}
+
+	_, err = fsutil.WriteFile(cfgPath, []byte(content), info.Mode().Perm())
+	return err
+}
+
+// mappingEntry is a key of a mapping with all the lines of its value, or a
+// blank or comment line
+type mappingEntry struct {
+	key   string
+	lines []string
+}
+
+// setMirrorKeys replaces the certificate keys of the mirror whose `url` key
+// is in line `urlLine` and column `keyCol`. The new keys are placed where the
+// certificate was, or right after the url when there was none.
+func setMirrorKeys(lines []string, urlLine, keyCol int, keys []string) []string {
+	replaced := map[string]bool{"certificate": true, "fingerprint": true, "hashalgorithm": true}
+	pad := strings.Repeat(" ", keyCol)
+	start := mappingStart(lines, urlLine, keyCol)
+
+	dashPrefix := ""
+	var entries []mappingEntry
+	i := start
+	for i < len(lines) {
+		line := lines[i]
+		if i == start && indentation(line) < keyCol && len(line) >= keyCol {
+			dashPrefix = line[:keyCol]
+			line = pad + line[keyCol:]
+		}
+		if strings.TrimSpace(line) == "" {
+			entries = append(entries, mappingEntry{lines: []string{line}})
+			i++
+			continue
+		}
+		if indentation(line) < keyCol {
+			break
+		}
+		next := i + 1
+		key := ""
+		if m := mappingKey.FindStringSubmatch(line); m != nil && len(m[1]) == keyCol {
+			key = m[2]
+			next = valueEnd(lines, i, keyCol, m[4])
+		}
+		entries = append(entries, mappingEntry{key: key, lines: append([]string{line}, lines[i+1:next]...)})
+		i = next
+	}
+	end := i
+
+	newKeys := mappingEntry{key: "certificate"}
+	for _, k := range keys {
+		for _, l := range strings.Split(k, "\n") {
+			newKeys.lines = append(newKeys.lines, pad+l)
+		}
+	}
+
+	var kept []mappingEntry
+	insertAt, afterURL := -1, 0
+	for _, e := range entries {
+		if replaced[e.key] {
+			if insertAt < 0 {
+				insertAt = len(kept)
+			}
+			continue
+		}
+		if e.key == "url" {
+			afterURL = len(kept) + 1
+		}
+		kept = append(kept, e)
+	}
+	if insertAt < 0 {
+		insertAt = afterURL
+	}
+	kept = append(kept[:insertAt], append([]mappingEntry{newKeys}, kept[insertAt:]...)...)
+
+	result := append([]string{}, lines[:start]...)
+	for _, e := range kept {
+		for n, l := range e.lines {
+			if n == 0 && e.key != "" && dashPrefix != "" {
+				l = dashPrefix + l[keyCol:]
+				dashPrefix = ""
+			}
+			result = append(result, l)
+		}
+	}
+	return append(result, lines[end:]...)
+}
+
+// mappingStart returns the first line of the mapping containing the key in
+// line `keyLine` and column `keyCol`
+func mappingStart(lines []string, keyLine, keyCol int) int {
+	for start := keyLine; start >= 0; start-- {
+		line := lines[start]
+		if strings.TrimSpace(line) == "" || indentation(line) >= keyCol {
+			continue
+		}
+		if m := mirrorItemLine.FindStringSubmatch(line); m != nil && len(m[1]) == keyCol {
+			return start
+		}
+		return start + 1
+	}
+	return 0
+}
+
+// valueEnd returns the line following the value of the key in line `start`
+func valueEnd(lines []string, start, keyCol int, value string) int {
+	value = strings.TrimSpace(value)
+	if len(value) > 0 && (value[0] == '"' || value[0] == '\'') {
+		if closedQuote(value[1:], value[0]) {
+			return start + 1
+		}
+		for i := start + 1; i < len(lines); i++ {
+			if closedQuote(lines[i], value[0]) {
+				return i + 1
+			}
+		}
+		return len(lines)
+	}
+
+	i := start + 1
+	for i < len(lines) && (strings.TrimSpace(lines[i]) == "" || indentation(lines[i]) > keyCol) {
+		i++
+	}
+	// blank lines after the value belong to what follows
+	for i > start+1 && strings.TrimSpace(lines[i-1]) == "" {
+		i--
+	}
+	return i
+}
+
+func closedQuote(s string, quote byte) bool {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote == '"' && s[i] == '\\':
+			i++
+		case s[i] == quote && quote == '\'' && i+1 < len(s) && s[i+1] == '\'':
+			i++
+		case s[i] == quote:
+			return true
+		}
+	}
+	return false
+}
+
+func indentation(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+func indentBlock(s, pad string) string {
+	return pad + strings.Replace(s, "\n", "\n"+pad, -1)
+}
+
+func unquote(s string) string {
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = strings.TrimSpace(s[:i])
+	}
+	return strings.Trim(s, `"'`)
+}
+
+func sameURL(a, b string) bool {
+	return strings.TrimSuffix(a, "/") == strings.TrimSuffix(b, "/")
+}
@@ -0,0 +1,116 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	editCertificate = `-----BEGIN CERTIFICATE-----
+MIIBdzCCAR2gAwIBAgIBATAKBggqhkjOPQQDAjASMRAwDgYDVQQDEwd0ZXN0LWNh
+-----END CERTIFICATE-----
+`
+	editConfigContent = `---
+# registries mirrored in the air-gapped network
+bootstrap:
+  registries:
+    - prefix: https://mycompany.registry.com
+      mirrors:
+        # main mirror
+        - url: https://mycompany.airgapped.com
+          # pinned by hand
+          fingerprint: "00:11"
+
+        - url: "https://mycompany2.airgapped.com/"
+          certificate: "-----BEGIN CERTIFICATE-----
+MIIGJzCCBA+gAwIBAgIBATANBgkqhkiG9w0BAQUFADCBsjELMAkGA1UEBhMCRlIx
+-----END CERTIFICATE-----"
+          hashalgorithm: "SHA1"
+    - mirrors:
+        - hashalgorithm: SHA1
+          url: https://mycompany.airgapped.com
+      prefix: https://registry.io
+`
+)
+
+func TestSetMirrorCertificate(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-edit")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name      string
+		mirrorURL string
+		wantGone  string
+		wantErr   bool
+	}{
+		{"existing_keys", "https://mycompany.airgapped.com", "hashalgorithm: SHA1", false},
+		{"multiline_quoted", "https://mycompany2.airgapped.com", "MIIGJzCCBA", false},
+		{"unknown_mirror", "https://unknown.airgapped.com", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfgPath := filepath.Join(tmpDir, tt.name+".yaml")
+			if err := ioutil.WriteFile(cfgPath, []byte(editConfigContent), os.FileMode(0600)); err != nil {
+				t.Fatalf("faliled to write config file: %s", err)
+			}
+			err := SetMirrorCertificate(cfgPath, tt.mirrorURL, editCertificate, "AB:CD", "SHA256")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetMirrorCertificate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			b, _ := ioutil.ReadFile(cfgPath)
+			if tt.wantErr {
+				if string(b) != editConfigContent {
+					t.Errorf("SetMirrorCertificate() changed the file on error")
+				}
+				return
+			}
+			if strings.Contains(string(b), tt.wantGone) {
+				t.Errorf("SetMirrorCertificate() did not remove %q", tt.wantGone)
+			}
+			for _, comment := range []string{"# registries mirrored", "# main mirror", "# pinned by hand"} {
+				if !strings.Contains(string(b), comment) {
+					t.Errorf("SetMirrorCertificate() removed the comment %q", comment)
+				}
+			}
+			if info, _ := os.Stat(cfgPath); info.Mode() != os.FileMode(0600) {
+				t.Errorf("SetMirrorCertificate() changed the mode to %v", info.Mode())
+			}
+			files, _ := filepath.Glob(filepath.Join(tmpDir, "*"))
+			for _, file := range files {
+				if filepath.Ext(file) != ".yaml" {
+					t.Errorf("SetMirrorCertificate() left the temporary file %s", file)
+				}
+			}
+
+			cfg, err := FileAndDefaultsToKubicInitConfig(cfgPath)
+			if err != nil {
+				t.Fatalf("loading the updated file: %v", err)
+			}
+			updated := 0
+			for _, reg := range cfg.Bootstrap.Registries {
+				for _, mirror := range reg.Mirrors {
+					if !sameURL(mirror.URL, tt.mirrorURL) {
+						if mirror.Fingerprint == "AB:CD" {
+							t.Errorf("SetMirrorCertificate() updated the mirror %s", mirror.URL)
+						}
+						continue
+					}
+					updated++
+					if mirror.Certificate != editCertificate || mirror.Fingerprint != "AB:CD" || mirror.HashAlgorithm != "SHA256" {
+						t.Errorf("SetMirrorCertificate() mirror = %+v", mirror)
+					}
+				}
+			}
+			if updated == 0 {
+				t.Errorf("SetMirrorCertificate() did not update any mirror")
+			}
+		})
+	}
+}
@@ -0,0 +1,415 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// FieldDoc struct
+// Documentation of a configuration field
+// Description: what the field is used for.
+// Default: value used when the field is not present in the file.
+// Allowed: list of accepted values, empty when any value is accepted.
+type FieldDoc struct {
+	Description string
+	Default     string
+	Allowed     []string
+}
+
+// FieldInfo struct
+// Describes a field of the configuration found by Explain
+// Name: name of the field in the configuration file.
+// Path: full path of the field, `[]` marks the lists.
+// Type: type of the value of the field.
+// Fields: direct children of the field, only for objects.
+type FieldInfo struct {
+	Name   string
+	Path   string
+	Type   string
+	Doc    FieldDoc
+	Fields []FieldInfo
+}
+
+// fieldDocs documents every field of the KubicInitConfiguration,
+// keys are the field path without the list markers
+var fieldDocs = map[string]FieldDoc{
+	"": {
+		Description: "The kubic-init configuration, loaded from '/etc/kubic/kubic-init.yaml' by default. The '*.yaml' drop-ins of the '.d' directory next to it are loaded after it in lexical order: the objects are merged field by field, a field set in a later file overriding the previous value, while the lists, like the registries or their mirrors, are replaced as a whole. The roles are merged by name, each role replacing the previous one of that name.",
+	},
+	"network": {
+		Description: "Network settings of the node and the cluster.",
+	},
+	"network.bind": {
+		Description: "Address the cluster services will listen on.",
+	},
+	"network.bind.address": {
+		Description: "IP address the cluster services will bind to. It has to be on the node, and on the interface when both are set.",
+	},
+	"network.bind.interface": {
+		Description: "Network interface the cluster services will bind to. Its primary address of the family is used when no address is set.",
+	},
+	"network.bind.family": {
+		Description: "IP family of the address resolved from the interface.",
+		Default:     DefaultBindFamily,
+		Allowed:     []string{"ipv4", "ipv6"},
+	},
+	"network.cni": {
+		Description: "Container Network Interface settings. Subnets details are specified in the kubeadm configuration file.",
+	},
+	"network.cni.binDir": {
+		Description: "Directory containing the CNI plugin binaries.",
+		Default:     DefaultCniBinDir,
+	},
+	"network.cni.confDir": {
+		Description: "Directory containing the CNI configuration files.",
+		Default:     DefaultCniConfDir,
+	},
+	"network.cni.driver": {
+		Description: "CNI driver used for the pods network. caasp-init writes its configuration list in 'confDir', 'bridge' suits single node clusters.",
+		Allowed:     []string{"flannel", "cilium", "bridge"},
+	},
+	"network.cni.image": {
+		Description: "Container image of the CNI driver.",
+	},
+	"network.cni.allowMissingPlugins": {
+		Description: "Write the configuration list of the driver even when some of its plugin binaries are missing in 'binDir', with a warning, for the plugins the driver installs itself once running. Otherwise a missing plugin is an error.",
+		Default:     "false",
+	},
+	"network.dns": {
+		Description: "DNS settings of the cluster.",
+	},
+	"network.dns.domain": {
+		Description: "DNS domain used by the cluster services.",
+		Default:     DefaultDNSDomain,
+	},
+	"network.dns.externalFqdn": {
+		Description: "Fully qualified domain name used for reaching the cluster from the outside.",
+	},
+	"network.proxy": {
+		Description: "Proxy settings for reaching external networks.",
+	},
+	"network.proxy.http": {
+		Description: "Proxy used for HTTP connections, as 'host:port'.",
+	},
+	"network.proxy.https": {
+		Description: "Proxy used for HTTPS connections, as 'host:port'.",
+	},
+	"network.proxy.noProxy": {
+		Description: "Comma separated list of hosts and domains reached without proxy. The bind address of the node is added when a proxy is set.",
+	},
+	"network.proxy.systemWide": {
+		Description: "Use the proxy settings for the whole system, not only for the cluster. Not supported by caasp-init: setting it is an error.",
+		Default:     "false",
+	},
+	"network.podSubnet": {
+		Description: "Subnet, in CIDR notation, used for the pods addresses. Each node gets a /24 of it, a /64 for IPv6. Dual-stack clusters use an IPv4 and an IPv6 subnet separated by a comma. It must not overlap the serviceSubnet nor the networks of the node.",
+		Default:     DefaultPodSubnet,
+	},
+	"network.serviceSubnet": {
+		Description: "Subnet, in CIDR notation, used for the services addresses. Dual-stack clusters use an IPv4 and an IPv6 subnet separated by a comma. It must not be larger than a /12, a /108 for IPv6.",
+		Default:     DefaultServiceSubnet,
+	},
+	"network.expectedNodes": {
+		Description: "Number of nodes the podSubnet has to make room for. A warning is logged when it is too small.",
+		Default:     "1",
+	},
+	"paths": {
+		Description: "Paths to the tools used for setting up the cluster.",
+	},
+	"paths.kubeadm": {
+		Description: "Path to the kubeadm binary.",
+		Default:     DefaultKubeadmPath,
+	},
+	"clusterFormation": {
+		Description: "Settings for creating the cluster and joining nodes to it.",
+	},
+	"clusterFormation.seeder": {
+		Description: "Name or address of the first node of the cluster. Can also be passed with the $" + DefaultEnvVarSeeder + " environment variable.",
+	},
+	"clusterFormation.token": {
+		Description: "Bootstrap token used by the nodes for joining the cluster, in the format [a-z0-9]{6}.[a-z0-9]{16}. Can also be passed with the $" + DefaultEnvVarToken + " environment variable. When the seeder has none, a random one is generated and kept in /var/lib/caasp-init/token. It is never logged nor shown.",
+	},
+	"clusterFormation.role": {
+		Description: "Role of the node. When not set, the node is the seeder if it has the name, the FQDN or an address of the seeder, or if no seeder is set.",
+		Allowed:     []string{RoleSeeder, RoleJoin},
+	},
+	"clusterFormation.autoApprove": {
+		Description: "Approve the nodes joining the cluster without any manual intervention.",
+		Default:     "false",
+	},
+	"certificates": {
+		Description: "Cluster certificates settings.",
+	},
+	"certificates.directory": {
+		Description: "Directory containing the cluster certificates, generated by caasp-init pki init or by kubeadm.",
+		Default:     DefaultCertsDirectory,
+	},
+	"certificates.caCrtHash": {
+		Description: "Hash of the cluster CA certificate, used by the joining nodes for validating the seeder: sha256 and the hex SHA-256 of its public key, as printed by caasp-init certs ca-hash on the seeder.",
+	},
+	"certificates.unsafeSkipCAVerification": {
+		Description: "Join the cluster without certificates.caCrtHash, trusting the CA presented by the seeder without verification. Unsafe: anyone impersonating the seeder can take over the joining nodes.",
+		Default:     "false",
+	},
+	"etcd": {
+		Description: "Etcd settings.",
+	},
+	"etcd.local": {
+		Description: "Settings for the etcd instance running in the seeder.",
+	},
+	"etcd.local.serverCertSANs": {
+		Description: "Extra Subject Alternative Names for the etcd server certificate.",
+	},
+	"etcd.local.peerCertSANs": {
+		Description: "Extra Subject Alternative Names for the etcd peer certificate.",
+	},
+	"etcd.local.autoSANs": {
+		Description: "Add the name, the bind address and the external FQDN of the node to the Subject Alternative Names of the etcd certificates.",
+		Default:     "false",
+	},
+	"runtime": {
+		Description: "Container runtime settings.",
+	},
+	"runtime.engine": {
+		Description: "Container runtime used in the node.",
+		Default:     DefaultRuntimeEngine,
+		Allowed:     []string{"docker", "crio"},
+	},
+	"runtime.dockerCompat": {
+		Description: "Flavor of docker the daemon.json file is written for. 'suse' writes the per registry mirrors understood by SUSE's docker, 'upstream' writes the 'registry-mirrors' of the upstream docker, only for docker.io, and 'auto' detects it from the 'docker version' output: the versions with a '-ce' or '_ce' suffix, as the SUSE builds, are written for SUSE's docker.",
+		Default:     DefaultDockerCompat,
+		Allowed:     []string{"auto", "suse", "upstream"},
+	},
+	"features": {
+		Description: "Optional features of the cluster.",
+	},
+	"features.PSP": {
+		Description: "Enable the Pod Security Policies.",
+		Default:     "false",
+	},
+	"services": {
+		Description: "Services deployed in the cluster.",
+	},
+	"auth": {
+		Description: "Authentication settings of the cluster.",
+	},
+	"auth.OIDC": {
+		Description: "OpenID Connect settings for the API server.",
+	},
+	"auth.OIDC.issuer": {
+		Description: "URL of the OpenID issuer, it has to use https. Setting it enables OpenID Connect, which needs the client ID and the claims too.",
+	},
+	"auth.OIDC.clientID": {
+		Description: "Client ID for the OpenID Connect client.",
+	},
+	"auth.OIDC.ca": {
+		Description: "Path to the certificate of the CA that signed the OpenID issuer certificate.",
+	},
+	"auth.OIDC.username": {
+		Description: "OpenID claim used as the user name.",
+	},
+	"auth.OIDC.groups": {
+		Description: "OpenID claim used as the user groups.",
+	},
+	"roles": {
+		Description: "Sections of the configuration applied only on the nodes with a role, by role name, seeder or join. Each field they set overrides the one of the configuration, like 'roles.join.bootstrap.registries' for mirrors only on the joining nodes. They cannot set clusterFormation.",
+	},
+	"bootstrap": {
+		Description: "Configuration required for bootstrapping the node.",
+	},
+	"bootstrap.registries": {
+		Description: "Registries whose images will be pulled from a mirror.",
+	},
+	"bootstrap.registries.prefix": {
+		Description: "Registry that will be replaced by the mirrors.",
+	},
+	"bootstrap.registries.mirrors": {
+		Description: "Mirrors replacing the registry, in order of preference.",
+	},
+	"bootstrap.registries.mirrors.url": {
+		Description: "URL of the mirror registry, including the scheme.",
+	},
+	"bootstrap.registries.mirrors.certificate": {
+		Description: "PEM encoded certificate of the CA for the mirror registry.",
+	},
+	"bootstrap.registries.mirrors.fingerprint": {
+		Description: "Fingerprint of the certificate, for checking its validity.",
+	},
+	"bootstrap.registries.mirrors.hashalgorithm": {
+		Description: "Hash algorithm used for computing the fingerprint.",
+		Allowed:     []string{"SHA1", "SHA256"},
+	},
+	"bootstrap.registries.mirrors.username": {
+		Description: "User name for authenticating in the mirror, requires a password or a password file.",
+	},
+	"bootstrap.registries.mirrors.password": {
+		Description: "Password for authenticating in the mirror.",
+	},
+	"bootstrap.registries.mirrors.passwordFile": {
+		Description: "File containing the password for authenticating in the mirror, instead of the password.",
+	},
+	"bootstrap.registries.mirrors.identityToken": {
+		Description: "Identity token for authenticating in the mirror.",
+	},
+	"bootstrap.registries.mirrors.insecure": {
+		Description: "Reach the mirror over plain HTTP or without verifying its certificate. Required for 'http://' mirrors.",
+		Default:     "false",
+	},
+}
+
+// Explain looks up the field of the KubicInitConfiguration in `path`,
+// with the segments separated by dots, an empty path describes the whole
+// configuration
+func Explain(path string) (*FieldInfo, error) {
+	info := newFieldInfo("", "", reflect.TypeOf(KubicInitConfiguration{}))
+
+	path = strings.Trim(strings.TrimSpace(path), ".")
+	if path == "" {
+		return info, nil
+	}
+
+	t := reflect.TypeOf(KubicInitConfiguration{})
+	var docPath, fullPath []string
+	for _, segment := range strings.Split(path, ".") {
+		name := strings.TrimSuffix(segment, "[]")
+		if structType(t).Kind() != reflect.Struct {
+			return nil, fmt.Errorf("field %q has no field %q", strings.Join(fullPath, "."), name)
+		}
+		field, ok := fieldByName(structType(t), name)
+		if !ok {
+			if len(fullPath) == 0 {
+				return nil, fmt.Errorf("field %q does not exist", name)
+			}
+			return nil, fmt.Errorf("field %q has no field %q", strings.Join(fullPath, "."), name)
+		}
+		t = field.Type
+		docPath = append(docPath, fieldName(field))
+		fullPath = append(fullPath, fieldName(field)+listMarker(t))
+	}
+
+	return newFieldInfo(strings.Join(fullPath, "."), strings.Join(docPath, "."), t), nil
+}
+
+// Fields returns the path of every field of the KubicInitConfiguration,
+// without the list markers
+func Fields() []string {
+	var paths []string
+	var walk func(prefix string, t reflect.Type)
+	walk = func(prefix string, t reflect.Type) {
+		t = structType(t)
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		for i := 0; i < t.NumField(); i++ {
+			name := fieldName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			if prefix != "" {
+				name = prefix + "." + name
+			}
+			paths = append(paths, name)
+			walk(name, t.Field(i).Type)
+		}
+	}
+	walk("", reflect.TypeOf(KubicInitConfiguration{}))
+	return paths
+}
+
+func newFieldInfo(path, docPath string, t reflect.Type) *FieldInfo {
+	info := &FieldInfo{
+		Name: strings.TrimSuffix(path[strings.LastIndex(path, ".")+1:], "[]"),
+		Path: path,
+		Type: typeName(t),
+		Doc:  fieldDocs[docPath],
+	}
+	st := structType(t)
+	if st.Kind() != reflect.Struct {
+		return info
+	}
+	for i := 0; i < st.NumField(); i++ {
+		name := fieldName(st.Field(i))
+		if name == "" {
+			continue
+		}
+		childDoc := name
+		if docPath != "" {
+			childDoc = docPath + "." + name
+		}
+		childPath := name + listMarker(st.Field(i).Type)
+		if path != "" {
+			childPath = path + "." + childPath
+		}
+		info.Fields = append(info.Fields, FieldInfo{
+			Name: name,
+			Path: childPath,
+			Type: typeName(st.Field(i).Type),
+			Doc:  fieldDocs[childDoc],
+		})
+	}
+	return info
+}
+
+// fieldByName finds the field with the given yaml name, ignoring the case
+func fieldByName(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		if n := fieldName(t.Field(i)); n != "" && strings.EqualFold(n, name) {
+			return t.Field(i), true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// fieldName returns the name of the field in the yaml file
+func fieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// structType removes the pointers and lists around a type
+func structType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t
+}
+
+func listMarker(t reflect.Type) string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() == reflect.Slice && structType(t).Kind() == reflect.Struct {
+		return "[]"
+	}
+	return ""
+}
+
+func typeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + typeName(t.Elem())
+	case reflect.Map:
+		return "map[" + typeName(t.Key()) + "]" + typeName(t.Elem())
+	case reflect.Struct:
+		return "Object"
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	default:
+		return t.Kind().String()
+	}
+}
@@ -0,0 +1,58 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestExplain(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		wantPath   string
+		wantType   string
+		wantFields int
+		wantErr    bool
+	}{
+		{"root", "", "", "Object", 11, false},
+		{"object", "network.bind", "network.bind", "Object", 3, false},
+		{"list_markers", "bootstrap.registries[].mirrors[].hashalgorithm", "bootstrap.registries[].mirrors[].hashalgorithm", "string", 0, false},
+		{"no_list_markers", "bootstrap.registries.mirrors", "bootstrap.registries[].mirrors[]", "[]Object", 9, false},
+		{"ignore_case", "ClusterFormation.AutoApprove", "clusterFormation.autoApprove", "boolean", 0, false},
+		{"pointer", "etcd.local.serverCertSANs", "etcd.local.serverCertSANs", "[]string", 0, false},
+		{"dots", ".runtime.engine.", "runtime.engine", "string", 0, false},
+		{"unknown", "network.foo", "", "", 0, true},
+		{"not_object", "runtime.engine.foo", "", "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Explain(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Explain() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if err != nil {
+				return
+			}
+			if got.Path != tt.wantPath || got.Type != tt.wantType || len(got.Fields) != tt.wantFields {
+				t.Errorf("Explain() = %s <%s> with %d fields, want %s <%s> with %d fields",
+					got.Path, got.Type, len(got.Fields), tt.wantPath, tt.wantType, tt.wantFields)
+			}
+		})
+	}
+}
+
+// TestFieldDocs checks every field of the configuration is documented
+func TestFieldDocs(t *testing.T) {
+	fields := map[string]bool{}
+	for _, path := range Fields() {
+		fields[path] = true
+		if doc, ok := fieldDocs[path]; !ok || doc.Description == "" {
+			t.Errorf("field %q has no documentation", path)
+		}
+	}
+	for path := range fieldDocs {
+		if path != "" && !fields[path] {
+			t.Errorf("documented field %q does not exist", path)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseSubnets parses a subnet in CIDR notation, or an IPv4 and an IPv6 one
+// separated by a comma for dual-stack clusters
+func ParseSubnets(value string) ([]*net.IPNet, error) {
+	var subnets []*net.IPNet
+	families := map[bool]bool{}
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		ip, subnet, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet \"%s\", use the CIDR notation like 172.16.0.0/13", s)
+		}
+		if !ip.Equal(subnet.IP) {
+			return nil, fmt.Errorf("subnet \"%s\" has host bits set, use %s", s, subnet)
+		}
+		ipv4 := subnet.IP.To4() != nil
+		if families[ipv4] {
+			return nil, fmt.Errorf("subnets \"%s\" have the same IP family, dual-stack needs an IPv4 and an IPv6 one", value)
+		}
+		families[ipv4] = true
+		subnets = append(subnets, subnet)
+	}
+	return subnets, nil
+}
+
+// Overlap tells whether two subnets share addresses
+func Overlap(a, b *net.IPNet) bool {
+	return a.Contains(b.IP) || b.Contains(a.IP)
+}
+
+// maxServiceSubnetBits is the largest number of host bits of a services
+// subnet accepted by the API server
+const maxServiceSubnetBits = 20
+
+// validateSubnets checks the pods and services subnets can be parsed, are
+// not too large for the API server and do not overlap
+func validateSubnets(network NetworkConfiguration) []string {
+	var problems []string
+	parse := func(name, value string) []*net.IPNet {
+		if value == "" {
+			return nil
+		}
+		subnets, err := ParseSubnets(value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", name, err))
+		}
+		return subnets
+	}
+	pods := parse("podSubnet", network.PodSubnet)
+	services := parse("serviceSubnet", network.ServiceSubnet)
+
+	for _, s := range services {
+		ones, bits := s.Mask.Size()
+		if bits-ones > maxServiceSubnetBits {
+			problems = append(problems, fmt.Sprintf("serviceSubnet: subnet %s is too large, use a /%d or smaller one", s, bits-maxServiceSubnetBits))
+		}
+	}
+	for _, p := range pods {
+		for _, s := range services {
+			if Overlap(p, s) {
+				problems = append(problems, fmt.Sprintf("podSubnet %s overlaps serviceSubnet %s", p, s))
+			}
+		}
+	}
+	return problems
+}
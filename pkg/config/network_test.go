@@ -0,0 +1,64 @@
+package config
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseSubnets(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"ipv4", "172.16.0.0/13", []string{"172.16.0.0/13"}, false},
+		{"ipv6", "fd00:10::/56", []string{"fd00:10::/56"}, false},
+		{"dual_stack", "172.16.0.0/13, fd00:10::/56", []string{"172.16.0.0/13", "fd00:10::/56"}, false},
+		{"no_mask", "172.16.0.0", nil, true},
+		{"host_bits", "172.16.0.1/13", nil, true},
+		{"same_family", "172.16.0.0/13,10.0.0.0/8", nil, true},
+		{"three", "172.16.0.0/13,fd00:10::/56,10.0.0.0/8", nil, true},
+		{"empty", "", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseSubnets(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSubnets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("ParseSubnets() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i].String() != tt.want[i] {
+					t.Errorf("ParseSubnets() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestOverlap(t *testing.T) {
+	cidr := func(s string) *net.IPNet {
+		_, n, _ := net.ParseCIDR(s)
+		return n
+	}
+	tests := []struct {
+		name string
+		a, b string
+		want bool
+	}{
+		{"disjoint", "172.16.0.0/13", "172.24.0.0/16", false},
+		{"contained", "10.0.0.0/8", "10.96.0.0/12", true},
+		{"containing", "10.96.0.0/12", "10.0.0.0/8", true},
+		{"families", "10.0.0.0/8", "fd00::/8", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Overlap(cidr(tt.a), cidr(tt.b)); got != tt.want {
+				t.Errorf("Overlap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
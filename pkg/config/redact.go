@@ -0,0 +1,97 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+// Redact returns a copy of the configuration where the value of every secret
+// is replaced by `mask(value)`. Unset secrets are left unset.
+func Redact(cfg *KubicInitConfiguration, mask func(value string) string) (*KubicInitConfiguration, error) {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return nil, err
+	}
+	redacted := &KubicInitConfiguration{}
+	if err := yaml.Unmarshal(b, redacted); err != nil {
+		return nil, err
+	}
+	redactValue("", reflect.ValueOf(redacted).Elem(), mask)
+	return redacted, nil
+}
+
+func redactValue(docPath string, v reflect.Value, mask func(string) string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name := fieldName(v.Type().Field(i))
+			if name == "" {
+				continue
+			}
+			redactValue(joinPath(docPath, name), v.Field(i), mask)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			redactValue(docPath, v.Index(i), mask)
+		}
+	case reflect.Map:
+		// the sections of `roles` are configurations of their own: their
+		// fields have the doc path they have in the configuration
+		for _, key := range v.MapKeys() {
+			path := joinPath(docPath, fmt.Sprint(key.Interface()))
+			if docPath == "roles" {
+				path = ""
+			}
+			elem := reflect.New(v.Type().Elem()).Elem()
+			elem.Set(v.MapIndex(key))
+			redactValue(path, elem, mask)
+			v.SetMapIndex(key, elem)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		redactValue(docPath, elem, mask)
+		v.Set(elem)
+	case reflect.String:
+		if secretFields[docPath] && v.String() != "" {
+			v.SetString(mask(v.String()))
+		}
+	}
+}
+
+// Secrets returns the values of the secrets set in the configuration
+func Secrets(cfg *KubicInitConfiguration) []string {
+	var secrets []string
+	_, err := Redact(cfg, func(value string) string {
+		secrets = append(secrets, value)
+		return value
+	})
+	if err != nil {
+		return nil
+	}
+	return secrets
+}
+
+// HashSecret masks a secret with its checksum, so that a change can be told
+// without keeping the secret
+func HashSecret(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// Checksum returns the sha256 checksum of the effective configuration
+func Checksum(cfg *KubicInitConfiguration) (string, error) {
+	b, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", fmt.Errorf("unable to encode the configuration: %v", err)
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
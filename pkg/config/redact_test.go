@@ -0,0 +1,107 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+func TestRedact(t *testing.T) {
+	cfg := &KubicInitConfiguration{
+		ClusterFormation: ClusterFormationConfiguration{Seeder: "seeder.local", Token: "94dcda.c271f4ff502789ca"},
+		Bootstrap: BootstrapConfiguration{
+			Registries: []Registry{{Prefix: "docker.io", Mirrors: []Mirror{
+				{URL: "https://a.com", Username: "user", Password: "secret"},
+				{URL: "https://b.com"},
+			}}},
+		},
+	}
+	redacted, err := Redact(cfg, func(string) string { return "***" })
+	if err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+
+	tests := []struct {
+		name string
+		got  string
+		want string
+	}{
+		{"token", redacted.ClusterFormation.Token, "***"},
+		{"seeder", redacted.ClusterFormation.Seeder, "seeder.local"},
+		{"password", redacted.Bootstrap.Registries[0].Mirrors[0].Password, "***"},
+		{"username", redacted.Bootstrap.Registries[0].Mirrors[0].Username, "user"},
+		{"unset_password", redacted.Bootstrap.Registries[0].Mirrors[1].Password, ""},
+		{"original_untouched", cfg.Bootstrap.Registries[0].Mirrors[0].Password, "secret"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("Redact() %s = %q, want %q", tt.name, tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	cfg := &KubicInitConfiguration{
+		ClusterFormation: ClusterFormationConfiguration{Seeder: "seeder.local", Token: "94dcda.c271f4ff502789ca"},
+		Bootstrap: BootstrapConfiguration{
+			Registries: []Registry{{Prefix: "docker.io", Mirrors: []Mirror{
+				{URL: "https://a.com", Username: "user", Password: "secret"},
+				{URL: "https://b.com", IdentityToken: "identity"},
+			}}},
+		},
+	}
+	got := Secrets(cfg)
+	want := []string{"94dcda.c271f4ff502789ca", "secret", "identity"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Secrets() = %q, want %q", got, want)
+	}
+	roles := &KubicInitConfiguration{Roles: map[string]interface{}{
+		RoleJoin: map[interface{}]interface{}{
+			"clusterFormation": map[interface{}]interface{}{"seeder": "seeder.local"},
+			"bootstrap": map[interface{}]interface{}{"registries": []interface{}{
+				map[interface{}]interface{}{"prefix": "docker.io", "mirrors": []interface{}{
+					map[interface{}]interface{}{"url": "https://a.com", "password": "role-secret"},
+				}},
+			}},
+		},
+	}}
+	if got := Secrets(roles); !reflect.DeepEqual(got, []string{"role-secret"}) {
+		t.Errorf("Secrets() = %q, want the secret of the roles section", got)
+	}
+	redacted, err := Redact(roles, func(string) string { return "***" })
+	if err != nil {
+		t.Fatalf("Redact() error = %v", err)
+	}
+	b, _ := yaml.Marshal(redacted)
+	if strings.Contains(string(b), "role-secret") || !strings.Contains(string(b), "seeder.local") {
+		t.Errorf("Redact() =\n%s\nwant the password of the roles section masked", b)
+	}
+	if got := Secrets(&KubicInitConfiguration{}); len(got) != 0 {
+		t.Errorf("Secrets() = %q for a configuration without secrets", got)
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	a := &KubicInitConfiguration{Runtime: RuntimeConfiguration{Engine: "crio"}}
+	b := &KubicInitConfiguration{Runtime: RuntimeConfiguration{Engine: "docker"}}
+
+	sumA, err := Checksum(a)
+	if err != nil {
+		t.Fatalf("Checksum() error = %v", err)
+	}
+	sumB, _ := Checksum(b)
+	again, _ := Checksum(&KubicInitConfiguration{Runtime: RuntimeConfiguration{Engine: "crio"}})
+	if sumA == sumB {
+		t.Errorf("Checksum() is the same for different configurations")
+	}
+	if sumA != again {
+		t.Errorf("Checksum() = %s, then %s for the same configuration", sumA, again)
+	}
+	if HashSecret("a") == HashSecret("b") || HashSecret("a")[:7] != "sha256:" {
+		t.Errorf("HashSecret() = %s", HashSecret("a"))
+	}
+}
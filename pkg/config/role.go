@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+
+	yaml "gopkg.in/yaml.v2"
+)
+
+const (
+	// RoleSeeder the node creates the cluster
+	RoleSeeder = "seeder"
+
+	// RoleJoin the node joins the cluster created by the seeder
+	RoleJoin = "join"
+)
+
+// ApplyRole sets the role of the node and applies the section of the
+// configuration of that role over the rest, each field it sets overriding
+// the one of the configuration. The role sections are removed afterwards.
+func ApplyRole(cfg *KubicInitConfiguration, role string) error {
+	if err := validateRole(role); err != nil {
+		return err
+	}
+	for name, section := range cfg.Roles {
+		if err := validateRole(name); err != nil {
+			return fmt.Errorf("roles.%s: %v", name, err)
+		}
+		if keys, ok := section.(map[interface{}]interface{}); ok {
+			if _, ok := keys["clusterFormation"]; ok {
+				return fmt.Errorf("roles.%s: clusterFormation cannot be set by role", name)
+			}
+			if _, ok := keys["roles"]; ok {
+				return fmt.Errorf("roles.%s: roles cannot be nested", name)
+			}
+		}
+	}
+
+	section := cfg.Roles[role]
+	cfg.Roles = nil
+	cfg.ClusterFormation.Role = role
+	if section == nil {
+		return nil
+	}
+	b, err := yaml.Marshal(section)
+	if err != nil {
+		return err
+	}
+	if err := yaml.Unmarshal(b, cfg); err != nil {
+		return fmt.Errorf("unable to decode roles.%s: %v", role, err)
+	}
+	return nil
+}
+
+func validateRole(role string) error {
+	if role != RoleSeeder && role != RoleJoin {
+		return fmt.Errorf("unknown role \"%s\", use %s or %s", role, RoleSeeder, RoleJoin)
+	}
+	return nil
+}
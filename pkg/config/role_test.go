@@ -0,0 +1,89 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestApplyRole(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-role")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	load := func(content string) *KubicInitConfiguration {
+		path := filepath.Join(tmpDir, "kubic-init.yaml")
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("writing %s: %s", path, err)
+		}
+		cfg, err := FileAndDefaultsToKubicInitConfig(path)
+		if err != nil {
+			t.Fatalf("FileAndDefaultsToKubicInitConfig() error = %v", err)
+		}
+		return cfg
+	}
+	content := `runtime:
+  engine: crio
+network:
+  podSubnet: 10.0.0.0/16
+roles:
+  join:
+    network:
+      cni:
+        driver: flannel
+    bootstrap:
+      registries:
+      - prefix: https://registry.suse.com
+        mirrors:
+        - url: https://mirror.example.com
+  seeder:
+    runtime:
+      engine: docker
+`
+
+	tests := []struct {
+		name       string
+		content    string
+		role       string
+		wantEngine string
+		wantDriver string
+		wantMirror bool
+		wantErr    bool
+	}{
+		{"join", content, RoleJoin, "crio", "flannel", true, false},
+		{"seeder", content, RoleSeeder, "docker", "", false, false},
+		{"no_sections", "runtime:\n  engine: crio\nnetwork:\n  podSubnet: 10.0.0.0/16\n", RoleJoin, "crio", "", false, false},
+		{"unknown_role", content, "master", "", "", false, true},
+		{"unknown_section", "roles:\n  worker:\n    runtime:\n      engine: docker\n", RoleJoin, "", "", false, true},
+		{"cluster_formation", "roles:\n  join:\n    clusterFormation:\n      seeder: other\n", RoleJoin, "", "", false, true},
+		{"nested", "roles:\n  join:\n    roles:\n      seeder: {}\n", RoleJoin, "", "", false, true},
+		{"not_an_object", "roles:\n  join: [1]\n", RoleJoin, "", "", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := load(tt.content)
+			err := ApplyRole(cfg, tt.role)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyRole() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if cfg.ClusterFormation.Role != tt.role || cfg.Roles != nil {
+				t.Errorf("ApplyRole() role = %q, sections = %v", cfg.ClusterFormation.Role, cfg.Roles)
+			}
+			if cfg.Runtime.Engine != tt.wantEngine || cfg.Network.Cni.Driver != tt.wantDriver {
+				t.Errorf("ApplyRole() engine = %q, driver = %q, want %q, %q", cfg.Runtime.Engine, cfg.Network.Cni.Driver, tt.wantEngine, tt.wantDriver)
+			}
+			if cfg.Network.PodSubnet != "10.0.0.0/16" {
+				t.Errorf("ApplyRole() reset the podSubnet to %q", cfg.Network.PodSubnet)
+			}
+			if (len(cfg.Bootstrap.Registries) > 0) != tt.wantMirror {
+				t.Errorf("ApplyRole() registries = %+v", cfg.Bootstrap.Registries)
+			}
+		})
+	}
+}
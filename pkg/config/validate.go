@@ -0,0 +1,166 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// tokenFormat is the format of the bootstrap tokens, `<id>.<secret>`
+var tokenFormat = regexp.MustCompile(`^[a-z0-9]{6}\.[a-z0-9]{16}$`)
+
+// caHashFormat is the format of the CA certificate hashes, as kubeadm prints
+// them: the SHA-256 of the public key of the CA
+var caHashFormat = regexp.MustCompile(`^sha256:[0-9a-f]{64}$`)
+
+// dnsName is the format of a DNS name: dot separated labels of letters,
+// digits and inner dashes
+var dnsName = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?)*$`)
+
+// Validate checks the configuration is consistent, returning all the
+// problems found
+func Validate(cfg *KubicInitConfiguration) error {
+	if cfg == nil {
+		return errors.New("configuration is nil")
+	}
+
+	var problems []string
+	switch cfg.Runtime.DockerCompat {
+	case "", "auto", "suse", "upstream":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown docker compatibility mode \"%s\"", cfg.Runtime.DockerCompat))
+	}
+	switch cfg.Network.Bind.Family {
+	case "", "ipv4", "ipv6":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown IP family \"%s\", use ipv4 or ipv6", cfg.Network.Bind.Family))
+	}
+	if cfg.Network.Bind.Address != "" && net.ParseIP(cfg.Network.Bind.Address) == nil {
+		problems = append(problems, fmt.Sprintf("invalid bind address \"%s\"", cfg.Network.Bind.Address))
+	}
+	switch cfg.Network.Cni.Driver {
+	case "", "bridge", "cilium", "flannel":
+	default:
+		problems = append(problems, fmt.Sprintf("unknown CNI driver \"%s\", use one of bridge, cilium, flannel", cfg.Network.Cni.Driver))
+	}
+	if cfg.Network.Proxy.SystemWide {
+		problems = append(problems, "network.proxy.systemWide is not supported")
+	}
+	problems = append(problems, validateSubnets(cfg.Network)...)
+	problems = append(problems, validateOIDC(cfg.Auth.OIDC)...)
+	problems = append(problems, validateEtcd(cfg.Etcd)...)
+	if cfg.ClusterFormation.Role != "" {
+		if err := validateRole(cfg.ClusterFormation.Role); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if cfg.ClusterFormation.Token != "" {
+		if err := ValidateToken(cfg.ClusterFormation.Token); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	if cfg.Certificates.CaHash != "" && !caHashFormat.MatchString(cfg.Certificates.CaHash) {
+		problems = append(problems, fmt.Sprintf("invalid CA certificate hash \"%s\", use the format sha256:<64 hex digits>", cfg.Certificates.CaHash))
+	}
+	for _, reg := range cfg.Bootstrap.Registries {
+		for _, mirror := range reg.Mirrors {
+			if err := validateMirror(mirror); err != nil {
+				problems = append(problems, err.Error())
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("Error in configuration file: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// ValidateToken checks `token` has the format of a bootstrap token, six and
+// sixteen lowercase letters or digits separated by a dot. The token is not
+// part of the error.
+func ValidateToken(token string) error {
+	if !tokenFormat.MatchString(token) {
+		return errors.New("invalid bootstrap token, use the format [a-z0-9]{6}.[a-z0-9]{16}")
+	}
+	return nil
+}
+
+func validateMirror(mirror Mirror) error {
+	u, err := url.Parse(mirror.URL)
+	if err != nil {
+		return fmt.Errorf("malformed Mirror URL \"%s\": %v", mirror.URL, err)
+	}
+	if u.Scheme == "http" && !mirror.Insecure {
+		return fmt.Errorf("mirror \"%s\" uses plain HTTP, set 'insecure: true' to allow it", mirror.URL)
+	}
+	return nil
+}
+
+// validateEtcd checks the Subject Alternative Names of the etcd certificates
+// are IP addresses or DNS names
+func validateEtcd(etcd EtcdConfiguration) []string {
+	if etcd.LocalEtcd == nil {
+		return nil
+	}
+	var problems []string
+	for field, sans := range map[string][]string{
+		"serverCertSANs": etcd.LocalEtcd.ServerCertSANs,
+		"peerCertSANs":   etcd.LocalEtcd.PeerCertSANs,
+	} {
+		for _, san := range sans {
+			if !ValidSAN(san) {
+				problems = append(problems, fmt.Sprintf("invalid etcd.local.%s entry \"%s\", use an IP address or a DNS name", field, san))
+			}
+		}
+	}
+	sort.Strings(problems)
+	return problems
+}
+
+// ValidSAN tells whether `san` can be a Subject Alternative Name of a
+// certificate: an IP address or a DNS name, whose first label can be a `*`
+// wildcard
+func ValidSAN(san string) bool {
+	if net.ParseIP(san) != nil {
+		return true
+	}
+	name := strings.TrimPrefix(strings.TrimSuffix(san, "."), "*.")
+	return len(name) <= 253 && dnsName.MatchString(name)
+}
+
+// validateOIDC checks the issuer of the OpenID Connect settings is an https
+// URL and the client ID and the claims are set. The CA file is checked by
+// the oidc package, on the nodes running the API server.
+func validateOIDC(oidc OIDCConfiguration) []string {
+	if oidc == (OIDCConfiguration{}) {
+		return nil
+	}
+
+	var problems []string
+	u, err := url.Parse(oidc.Issuer)
+	switch {
+	case oidc.Issuer == "":
+		problems = append(problems, "auth.OIDC.issuer is needed for OpenID Connect")
+	case err != nil:
+		problems = append(problems, fmt.Sprintf("malformed OpenID issuer \"%s\": %v", oidc.Issuer, err))
+	case u.Scheme != "https" || u.Host == "":
+		problems = append(problems, fmt.Sprintf("OpenID issuer \"%s\" has to be an https URL", oidc.Issuer))
+	case u.RawQuery != "" || u.Fragment != "":
+		problems = append(problems, fmt.Sprintf("OpenID issuer \"%s\" cannot have a query or a fragment", oidc.Issuer))
+	}
+	for _, field := range []struct{ name, value string }{
+		{"clientID", oidc.ClientID},
+		{"username", oidc.Username},
+		{"groups", oidc.Groups},
+	} {
+		if strings.TrimSpace(field.value) == "" {
+			problems = append(problems, fmt.Sprintf("auth.OIDC.%s is needed for OpenID Connect", field.name))
+		}
+	}
+	return problems
+}
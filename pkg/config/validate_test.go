@@ -0,0 +1,73 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	withMirror := func(mirror Mirror) *KubicInitConfiguration {
+		return &KubicInitConfiguration{
+			Bootstrap: BootstrapConfiguration{
+				Registries: []Registry{
+					{Prefix: "mycompany.registry.com", Mirrors: []Mirror{mirror}},
+				},
+			},
+		}
+	}
+	withOIDC := func(oidc OIDCConfiguration) *KubicInitConfiguration {
+		return &KubicInitConfiguration{Auth: AuthConfiguration{OIDC: oidc}}
+	}
+	tests := []struct {
+		name    string
+		config  *KubicInitConfiguration
+		wantErr bool
+	}{
+		{"nil", nil, true},
+		{"empty", &KubicInitConfiguration{}, false},
+		{"https", withMirror(Mirror{URL: "https://first.mirror.com"}), false},
+		{"http_insecure", withMirror(Mirror{URL: "http://first.mirror.com", Insecure: true}), false},
+		{"https_insecure", withMirror(Mirror{URL: "https://first.mirror.com", Insecure: true}), false},
+		{"http", withMirror(Mirror{URL: "http://first.mirror.com"}), true},
+		{"malformed", withMirror(Mirror{URL: ":"}), true},
+		{"docker_compat", &KubicInitConfiguration{Runtime: RuntimeConfiguration{DockerCompat: "upstream"}}, false},
+		{"unknown_docker_compat", &KubicInitConfiguration{Runtime: RuntimeConfiguration{DockerCompat: "moby"}}, true},
+		{"proxy_system_wide", &KubicInitConfiguration{Network: NetworkConfiguration{Proxy: ProxyConfiguration{HTTP: "proxy:3128", SystemWide: true}}}, true},
+		{"cni_driver", &KubicInitConfiguration{Network: NetworkConfiguration{Cni: CniConfiguration{Driver: "bridge"}}}, false},
+		{"dual_stack", &KubicInitConfiguration{Network: NetworkConfiguration{PodSubnet: "172.16.0.0/13,fd00:10::/56", ServiceSubnet: "172.24.0.0/16, fd00:20::/112"}}, false},
+		{"invalid_subnet", &KubicInitConfiguration{Network: NetworkConfiguration{PodSubnet: "172.16.0.0"}}, true},
+		{"overlapping_subnets", &KubicInitConfiguration{Network: NetworkConfiguration{PodSubnet: "10.0.0.0/8", ServiceSubnet: "10.96.0.0/12"}}, true},
+		{"large_service_subnet", &KubicInitConfiguration{Network: NetworkConfiguration{ServiceSubnet: "10.0.0.0/8"}}, true},
+		{"bind_ipv6", &KubicInitConfiguration{Network: NetworkConfiguration{Bind: BindConfiguration{Interface: "eth0", Family: "ipv6"}}}, false},
+		{"unknown_bind_family", &KubicInitConfiguration{Network: NetworkConfiguration{Bind: BindConfiguration{Interface: "eth0", Family: "inet"}}}, true},
+		{"invalid_bind_address", &KubicInitConfiguration{Network: NetworkConfiguration{Bind: BindConfiguration{Address: "eth0"}}}, true},
+		{"unknown_cni_driver", &KubicInitConfiguration{Network: NetworkConfiguration{Cni: CniConfiguration{Driver: "weave"}}}, true},
+		{"role", &KubicInitConfiguration{ClusterFormation: ClusterFormationConfiguration{Role: "join"}}, false},
+		{"unknown_role", &KubicInitConfiguration{ClusterFormation: ClusterFormationConfiguration{Role: "master"}}, true},
+		{"token", &KubicInitConfiguration{ClusterFormation: ClusterFormationConfiguration{Token: "94dcda.c271f4ff502789ca"}}, false},
+		{"invalid_token", &KubicInitConfiguration{ClusterFormation: ClusterFormationConfiguration{Token: "94DCDA.c271f4ff502789ca"}}, true},
+		{"short_token", &KubicInitConfiguration{ClusterFormation: ClusterFormationConfiguration{Token: "94dcda.c271f4ff"}}, true},
+		{"etcd_sans", &KubicInitConfiguration{Etcd: EtcdConfiguration{LocalEtcd: &LocalEtcdConfiguration{
+			ServerCertSANs: []string{"etcd.example.com", "192.168.1.10", "fd00::10", "*.etcd.example.com", "etcd-0."},
+			PeerCertSANs:   []string{"etcd-0"},
+		}}}, false},
+		{"invalid_server_san", &KubicInitConfiguration{Etcd: EtcdConfiguration{LocalEtcd: &LocalEtcdConfiguration{ServerCertSANs: []string{"etcd_0.example.com"}}}}, true},
+		{"invalid_peer_san", &KubicInitConfiguration{Etcd: EtcdConfiguration{LocalEtcd: &LocalEtcdConfiguration{PeerCertSANs: []string{"https://etcd.example.com"}}}}, true},
+		{"empty_san", &KubicInitConfiguration{Etcd: EtcdConfiguration{LocalEtcd: &LocalEtcdConfiguration{PeerCertSANs: []string{""}}}}, true},
+		{"ca_hash", &KubicInitConfiguration{Certificates: CertsConfiguration{CaHash: "sha256:0a3c12d45c2c0e4a2b4b8d0d40b8a8c6d7b8e3f3f3c8f2a8c1b64d30c0e3b5a1"}}, false},
+		{"uppercase_ca_hash", &KubicInitConfiguration{Certificates: CertsConfiguration{CaHash: "sha256:0A3C12D45C2C0E4A2B4B8D0D40B8A8C6D7B8E3F3F3C8F2A8C1B64D30C0E3B5A1"}}, true},
+		{"unprefixed_ca_hash", &KubicInitConfiguration{Certificates: CertsConfiguration{CaHash: "0a3c12d45c2c0e4a2b4b8d0d40b8a8c6d7b8e3f3f3c8f2a8c1b64d30c0e3b5a1"}}, true},
+		{"oidc", withOIDC(OIDCConfiguration{Issuer: "https://dex.example.com/dex", ClientID: "kubernetes", Username: "email", Groups: "groups"}), false},
+		{"oidc_http_issuer", withOIDC(OIDCConfiguration{Issuer: "http://dex.example.com", ClientID: "kubernetes", Username: "email", Groups: "groups"}), true},
+		{"oidc_issuer_query", withOIDC(OIDCConfiguration{Issuer: "https://dex.example.com/?realm=k8s", ClientID: "kubernetes", Username: "email", Groups: "groups"}), true},
+		{"oidc_no_issuer", withOIDC(OIDCConfiguration{ClientID: "kubernetes"}), true},
+		{"oidc_no_client_id", withOIDC(OIDCConfiguration{Issuer: "https://dex.example.com", Username: "email", Groups: "groups"}), true},
+		{"oidc_blank_claim", withOIDC(OIDCConfiguration{Issuer: "https://dex.example.com", ClientID: "kubernetes", Username: " ", Groups: "groups"}), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Validate(tt.config); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,273 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+)
+
+var (
+	dockerAuthFile = "/root/.docker/config.json"
+	crioAuthFile   = "/etc/containers/auth.json"
+)
+
+// authEntry struct
+// Credentials of a registry in the auth file
+type authEntry struct {
+	Auth          string `json:"auth,omitempty"`
+	IdentityToken string `json:"identitytoken,omitempty"`
+}
+
+// WriteCredentials writes the credentials of the mirrors in the auth file
+// of the configured runtime. The entries already present in the file for
+// other registries are kept.
+func WriteCredentials(config *config.KubicInitConfiguration) error {
+	if config == nil {
+		return errors.New("configuration is nil")
+	}
+
+	entries, err := mirrorEntries(config)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		return nil
+	}
+
+	authFile, err := AuthFile(config.Runtime.Engine)
+	if err != nil {
+		return err
+	}
+
+	content, err := readAuthFile(authFile)
+	if err != nil {
+		return err
+	}
+	auths, _ := content["auths"].(map[string]interface{})
+	if auths == nil {
+		auths = map[string]interface{}{}
+	}
+	for host, entry := range entries {
+		auths[host] = entry
+	}
+	content["auths"] = auths
+	return writeAuthFile(authFile, content)
+}
+
+// RemoveStale removes from the auth file the entries caasp-init wrote for
+// the mirrors of the `previous` configuration that `config` no longer has
+// credentials for. The entries of other registries are kept.
+func RemoveStale(config, previous *config.KubicInitConfiguration) error {
+	if config == nil {
+		return errors.New("configuration is nil")
+	}
+	if previous == nil {
+		return nil
+	}
+	// the previous configuration was applied, its engine is valid
+	authFile, err := AuthFile(previous.Runtime.Engine)
+	if err != nil {
+		return err
+	}
+	current := map[string]bool{}
+	if currentFile, err := AuthFile(config.Runtime.Engine); err == nil && currentFile == authFile {
+		current = mirrorHosts(config)
+	}
+
+	content, err := readAuthFile(authFile)
+	if err != nil {
+		return err
+	}
+	auths, _ := content["auths"].(map[string]interface{})
+	removed := false
+	for host := range mirrorHosts(previous) {
+		if _, ok := auths[host]; ok && !current[host] {
+			delete(auths, host)
+			removed = true
+		}
+	}
+	if !removed {
+		return nil
+	}
+	return writeAuthFile(authFile, content)
+}
+
+// readAuthFile returns the content of the auth file, empty when it does not
+// exist
+func readAuthFile(authFile string) (map[string]interface{}, error) {
+	content := map[string]interface{}{}
+	b, err := ioutil.ReadFile(authFile)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, &content); err != nil {
+			return nil, fmt.Errorf("unable to decode %q: %v", authFile, err)
+		}
+	case !os.IsNotExist(err):
+		return nil, err
+	}
+	return content, nil
+}
+
+// writeAuthFile writes the content of the auth file, readable only by root
+func writeAuthFile(authFile string, content map[string]interface{}) error {
+	b, err := json.MarshalIndent(content, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	err = fsutil.MkdirAll(filepath.Dir(authFile), 0700)
+	if err != nil {
+		return err
+	}
+	_, err = fsutil.WriteFile(authFile, append(b, '\n'), os.FileMode(0600))
+	return err
+}
+
+// Drift compares the entries of the mirrors in the auth file with their
+// credentials. The entries of other registries are kept by caasp-init, they
+// are not reported.
+func Drift(config *config.KubicInitConfiguration) ([]drift.Finding, error) {
+	if config == nil {
+		return nil, errors.New("configuration is nil")
+	}
+
+	entries, err := mirrorEntries(config)
+	if err != nil || len(entries) == 0 {
+		return nil, err
+	}
+	authFile, err := AuthFile(config.Runtime.Engine)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := ioutil.ReadFile(authFile)
+	if os.IsNotExist(err) {
+		return []drift.Finding{{Path: authFile, Owner: drift.Owned, Kind: drift.Missing}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var content struct {
+		Auths map[string]json.RawMessage `json:"auths"`
+	}
+	if err := json.Unmarshal(b, &content); err != nil {
+		return []drift.Finding{{Path: authFile, Owner: drift.Owned, Kind: drift.Modified}}, nil
+	}
+
+	want := map[string]json.RawMessage{}
+	for host, entry := range entries {
+		want[host], err = json.Marshal(entry)
+		if err != nil {
+			return nil, err
+		}
+	}
+	var findings []drift.Finding
+	mirror := func(host string) bool { _, ok := entries[host]; return ok }
+	for _, f := range drift.Keys(authFile, "auths.", want, content.Auths, mirror) {
+		if f.Owner == drift.Owned {
+			findings = append(findings, f)
+		}
+	}
+	return findings, nil
+}
+
+// AuthFile returns the path of the registries auth file used by `engine`
+func AuthFile(engine string) (string, error) {
+	switch engine {
+	case "", "docker":
+		return dockerAuthFile, nil
+	case "crio":
+		return crioAuthFile, nil
+	default:
+		return "", fmt.Errorf("unsupported runtime engine \"%s\"", engine)
+	}
+}
+
+// mirrorEntries returns the auth entries of the mirrors with credentials
+func mirrorEntries(config *config.KubicInitConfiguration) (map[string]authEntry, error) {
+	entries := map[string]authEntry{}
+	for _, reg := range config.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		for _, mirror := range reg.Mirrors {
+			entry, err := mirrorEntry(mirror)
+			if err != nil {
+				return nil, err
+			}
+			if entry == nil {
+				continue
+			}
+			u, err := url.Parse(mirror.URL)
+			if err != nil {
+				return nil, err
+			}
+			if u.Host == "" {
+				return nil, fmt.Errorf("Error in configuration file: malformed Mirror URL \"%s\"", mirror.URL)
+			}
+			entries[u.Host] = *entry
+		}
+	}
+	return entries, nil
+}
+
+// mirrorHosts returns the hosts of the mirrors with credentials, the
+// malformed ones are skipped
+func mirrorHosts(config *config.KubicInitConfiguration) map[string]bool {
+	hosts := map[string]bool{}
+	for _, reg := range config.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		for _, mirror := range reg.Mirrors {
+			if mirror.Username == "" && mirror.IdentityToken == "" {
+				continue
+			}
+			if u, err := url.Parse(mirror.URL); err == nil && u.Host != "" {
+				hosts[u.Host] = true
+			}
+		}
+	}
+	return hosts
+}
+
+// mirrorEntry returns the auth entry of the mirror, nil if the mirror has no credentials
+func mirrorEntry(mirror config.Mirror) (*authEntry, error) {
+	if mirror.Password != "" && mirror.PasswordFile != "" {
+		return nil, fmt.Errorf("Error in configuration file: mirror \"%s\" has both password and passwordFile", mirror.URL)
+	}
+	if mirror.Username == "" && (mirror.Password != "" || mirror.PasswordFile != "") {
+		return nil, fmt.Errorf("Error in configuration file: mirror \"%s\" has a password but no username", mirror.URL)
+	}
+
+	password := mirror.Password
+	if mirror.PasswordFile != "" {
+		b, err := ioutil.ReadFile(mirror.PasswordFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read the password of mirror \"%s\": %v", mirror.URL, err)
+		}
+		password = strings.TrimRight(string(b), "\r\n")
+	}
+	if mirror.Username != "" && password == "" {
+		return nil, fmt.Errorf("Error in configuration file: mirror \"%s\" has a username but no password", mirror.URL)
+	}
+
+	if mirror.Username == "" && mirror.IdentityToken == "" {
+		return nil, nil
+	}
+	entry := &authEntry{IdentityToken: mirror.IdentityToken}
+	if mirror.Username != "" {
+		entry.Auth = base64.StdEncoding.EncodeToString([]byte(mirror.Username + ":" + password))
+	}
+	return entry, nil
+}
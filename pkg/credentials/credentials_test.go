@@ -0,0 +1,223 @@
+package credentials
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+)
+
+func newConfig(engine string, mirrors ...config.Mirror) *config.KubicInitConfiguration {
+	return &config.KubicInitConfiguration{
+		Runtime: config.RuntimeConfiguration{Engine: engine},
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "mycompany.registry.com", Mirrors: mirrors},
+			},
+		},
+	}
+}
+
+func TestWriteCredentials(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-credentials")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	passwordFile := filepath.Join(tmpDir, "password")
+	if err := ioutil.WriteFile(passwordFile, []byte("s3cr3t\n"), 0600); err != nil {
+		t.Fatalf("writing password file: %s", err)
+	}
+	existing := filepath.Join(tmpDir, "existing.json")
+	if err := ioutil.WriteFile(existing, []byte(`{"auths":{"other.registry.com":{"auth":"b3RoZXI6b3RoZXI="}},"credsStore":"secretservice"}`), 0644); err != nil {
+		t.Fatalf("writing auth file: %s", err)
+	}
+
+	tests := []struct {
+		name     string
+		config   *config.KubicInitConfiguration
+		authFile string
+		want     map[string]authEntry
+		wantErr  bool
+	}{
+		{"nil", nil, filepath.Join(tmpDir, "nil.json"), nil, true},
+		{"no_credentials", newConfig("docker", config.Mirror{URL: "https://first.mirror.com"}), filepath.Join(tmpDir, "none.json"), nil, false},
+		{"password", newConfig("docker", config.Mirror{URL: "https://first.mirror.com:5000", Username: "user", Password: "pass"}),
+			filepath.Join(tmpDir, "docker", "config.json"),
+			map[string]authEntry{"first.mirror.com:5000": {Auth: base64.StdEncoding.EncodeToString([]byte("user:pass"))}}, false},
+		{"password_file", newConfig("crio", config.Mirror{URL: "https://first.mirror.com", Username: "user", PasswordFile: passwordFile}),
+			filepath.Join(tmpDir, "containers", "auth.json"),
+			map[string]authEntry{"first.mirror.com": {Auth: base64.StdEncoding.EncodeToString([]byte("user:s3cr3t"))}}, false},
+		{"identity_token_merge", newConfig("docker", config.Mirror{URL: "https://first.mirror.com", IdentityToken: "token"}),
+			existing,
+			map[string]authEntry{
+				"first.mirror.com":   {IdentityToken: "token"},
+				"other.registry.com": {Auth: "b3RoZXI6b3RoZXI="},
+			}, false},
+		{"password_and_file", newConfig("docker", config.Mirror{URL: "https://first.mirror.com", Username: "user", Password: "pass", PasswordFile: passwordFile}),
+			filepath.Join(tmpDir, "error.json"), nil, true},
+		{"no_username", newConfig("docker", config.Mirror{URL: "https://first.mirror.com", Password: "pass"}),
+			filepath.Join(tmpDir, "error.json"), nil, true},
+		{"no_password", newConfig("docker", config.Mirror{URL: "https://first.mirror.com", Username: "user"}),
+			filepath.Join(tmpDir, "error.json"), nil, true},
+		{"missing_password_file", newConfig("docker", config.Mirror{URL: "https://first.mirror.com", Username: "user", PasswordFile: filepath.Join(tmpDir, "missing")}),
+			filepath.Join(tmpDir, "error.json"), nil, true},
+		{"no_scheme", newConfig("docker", config.Mirror{URL: "first.mirror.com", Username: "user", Password: "pass"}),
+			filepath.Join(tmpDir, "error.json"), nil, true},
+		{"unknown_engine", newConfig("rkt", config.Mirror{URL: "https://first.mirror.com", Username: "user", Password: "pass"}),
+			filepath.Join(tmpDir, "error.json"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dockerAuthFile = tt.authFile
+			crioAuthFile = tt.authFile
+			if err := WriteCredentials(tt.config); (err != nil) != tt.wantErr {
+				t.Fatalf("WriteCredentials() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			info, err := os.Stat(tt.authFile)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("WriteCredentials() wrote %s", tt.authFile)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("WriteCredentials() did not write %s: %v", tt.authFile, err)
+			}
+			if info.Mode().Perm() != 0600 {
+				t.Errorf("WriteCredentials() permissions = %v, want 0600", info.Mode().Perm())
+			}
+
+			b, _ := ioutil.ReadFile(tt.authFile)
+			got := struct {
+				Auths map[string]authEntry `json:"auths"`
+			}{}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("decoding %s: %v", tt.authFile, err)
+			}
+			if len(got.Auths) != len(tt.want) {
+				t.Errorf("WriteCredentials() auths = %v, want %v", got.Auths, tt.want)
+			}
+			for host, entry := range tt.want {
+				if got.Auths[host] != entry {
+					t.Errorf("WriteCredentials() auths[%s] = %v, want %v", host, got.Auths[host], entry)
+				}
+			}
+		})
+	}
+	if b, _ := ioutil.ReadFile(existing); !strings.Contains(string(b), "secretservice") {
+		t.Errorf("WriteCredentials() removed the other settings of the auth file")
+	}
+}
+
+func TestDrift(t *testing.T) {
+	defer func(f string) { dockerAuthFile = f }(dockerAuthFile)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-credentials")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dockerAuthFile = filepath.Join(tmpDir, "config.json")
+
+	cfg := newConfig("docker", config.Mirror{URL: "https://first.mirror.com", Username: "user", Password: "pass"})
+	tests := []struct {
+		name    string
+		content string
+		want    []drift.Finding
+	}{
+		{"missing", "", []drift.Finding{{Path: dockerAuthFile, Owner: drift.Owned, Kind: drift.Missing}}},
+		{"malformed", "{", []drift.Finding{{Path: dockerAuthFile, Owner: drift.Owned, Kind: drift.Modified}}},
+		{"same", `{"auths": {"first.mirror.com": {"auth": "dXNlcjpwYXNz"}, "other.registry.com": {"auth": "b3RoZXI6b3RoZXI="}}}`, nil},
+		{"modified", `{"auths": {"first.mirror.com": {"auth": "b3RoZXI6b3RoZXI="}}}`, []drift.Finding{
+			{Path: dockerAuthFile, Key: "auths.first.mirror.com", Owner: drift.Owned, Kind: drift.Modified},
+		}},
+		{"removed", `{"auths": {}}`, []drift.Finding{
+			{Path: dockerAuthFile, Key: "auths.first.mirror.com", Owner: drift.Owned, Kind: drift.Missing},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(dockerAuthFile)
+			if tt.content != "" {
+				if err := ioutil.WriteFile(dockerAuthFile, []byte(tt.content), 0600); err != nil {
+					t.Fatalf("writing %s: %s", dockerAuthFile, err)
+				}
+			}
+			got, err := Drift(cfg)
+			if err != nil {
+				t.Fatalf("Drift() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Drift() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRemoveStale(t *testing.T) {
+	defer func(docker, crio string) { dockerAuthFile, crioAuthFile = docker, crio }(dockerAuthFile, crioAuthFile)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-credentials")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dockerAuthFile = filepath.Join(tmpDir, "config.json")
+	crioAuthFile = filepath.Join(tmpDir, "auth.json")
+
+	first := config.Mirror{URL: "https://first.mirror.com", Username: "user", Password: "pass"}
+	second := config.Mirror{URL: "https://second.mirror.com:5000", IdentityToken: "token"}
+	content := `{"auths": {"first.mirror.com": {"auth": "dXNlcjpwYXNz"}, "second.mirror.com:5000": {"identitytoken": "token"}, "other.registry.com": {"auth": "b3RoZXI6b3RoZXI="}}}`
+	tests := []struct {
+		name     string
+		config   *config.KubicInitConfiguration
+		previous *config.KubicInitConfiguration
+		want     []string
+		wantErr  bool
+	}{
+		{"nil", nil, newConfig("docker", first), nil, true},
+		{"never_applied", newConfig("docker"), nil, []string{"first.mirror.com", "other.registry.com", "second.mirror.com:5000"}, false},
+		{"same", newConfig("docker", first, second), newConfig("docker", first, second), []string{"first.mirror.com", "other.registry.com", "second.mirror.com:5000"}, false},
+		{"mirror_removed", newConfig("docker", first), newConfig("docker", first, second), []string{"first.mirror.com", "other.registry.com"}, false},
+		{"credentials_removed", newConfig("docker", config.Mirror{URL: first.URL}), newConfig("docker", first), []string{"other.registry.com", "second.mirror.com:5000"}, false},
+		{"engine_changed", newConfig("crio", first, second), newConfig("docker", first, second), []string{"other.registry.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(dockerAuthFile, []byte(content), 0600); err != nil {
+				t.Fatalf("writing %s: %s", dockerAuthFile, err)
+			}
+			err := RemoveStale(tt.config, tt.previous)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RemoveStale() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			b, _ := ioutil.ReadFile(dockerAuthFile)
+			var got struct {
+				Auths map[string]json.RawMessage `json:"auths"`
+			}
+			if err := json.Unmarshal(b, &got); err != nil {
+				t.Fatalf("decoding %s: %s", dockerAuthFile, err)
+			}
+			var hosts []string
+			for host := range got.Auths {
+				hosts = append(hosts, host)
+			}
+			sort.Strings(hosts)
+			if !reflect.DeepEqual(hosts, tt.want) {
+				t.Errorf("RemoveStale() kept %q, want %q", hosts, tt.want)
+			}
+		})
+	}
+}
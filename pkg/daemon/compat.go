@@ -0,0 +1,83 @@
+package daemon
+
+import (
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+const (
+	// CompatSUSE writes the per registry mirrors understood by SUSE's docker
+	CompatSUSE = "suse"
+
+	// CompatUpstream writes the `registry-mirrors` of the upstream docker
+	CompatUpstream = "upstream"
+
+	// CompatAuto detects the docker flavor from the `docker version` output
+	CompatAuto = "auto"
+)
+
+var (
+	// dockerVersion returns the output of `docker version`
+	dockerVersion = func() (string, error) {
+		out, err := exec.Command("docker", "version").CombinedOutput()
+		return string(out), err
+	}
+
+	versionLine = regexp.MustCompile(`(?m)^\s*Version:\s*\d+\.\d+\S*?([-_]ce)?\s*$`)
+)
+
+// ResolveCompat returns the compatibility mode daemon.json is written for:
+// the configured one, or the detected one with `auto`. Docker is only run
+// for detecting it when it is the runtime engine.
+func ResolveCompat(config *config.KubicInitConfiguration) string {
+	compat := config.Runtime.DockerCompat
+	switch {
+	case compat == "":
+		return CompatSUSE
+	case compat != CompatAuto:
+		return compat
+	case config.Runtime.Engine != "" && config.Runtime.Engine != "docker":
+		return CompatSUSE
+	}
+	compat = DetectCompat()
+	log.Debugf("detected %s docker", compat)
+	return compat
+}
+
+// DetectCompat guesses the docker flavor installed in the node. SUSE builds
+// mention SUSE in their version output or keep the `-ce` or `_ce` suffix,
+// like the 17.09.1_ce shipped by CaaSP, that upstream only used before
+// 18.09. Upstream releases older than 18.09 are thus detected as SUSE ones
+// and need `upstream` to be configured. SUSE's flavor is assumed when docker
+// cannot be run, as it was the only one supported by caasp-init.
+func DetectCompat() string {
+	out, err := dockerVersion()
+	if err != nil && out == "" {
+		return CompatSUSE
+	}
+	if strings.Contains(strings.ToLower(out), "suse") {
+		return CompatSUSE
+	}
+	m := versionLine.FindStringSubmatch(out)
+	if m == nil || m[1] != "" {
+		return CompatSUSE
+	}
+	return CompatUpstream
+}
+
+// isDockerHub tells whether the registry prefix is Docker Hub, the only
+// registry upstream docker can mirror
+func isDockerHub(prefix string) bool {
+	if i := strings.Index(prefix, "://"); i >= 0 {
+		prefix = prefix[i+3:]
+	}
+	switch strings.TrimSuffix(prefix, "/") {
+	case "docker.io", "index.docker.io", "registry-1.docker.io", "registry.hub.docker.com":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package daemon
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+func TestDetectCompat(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		err    error
+		want   string
+	}{
+		{"no_docker", "", errors.New("executable file not found in $PATH"), CompatSUSE},
+		{"suse_name", "Client:\n Version:           19.03.1\n Git commit:        suse-19.03.1\n", nil, CompatSUSE},
+		{"suse_ce_suffix", "Client:\n Version:           19.03.15-ce\n API version:       1.40\n", nil, CompatSUSE},
+		{"upstream", "Client: Docker Engine - Community\n Version:           20.10.7\n API version:       1.41\n", nil, CompatUpstream},
+		{"caasp_ce", "Client:\n Version:           17.09.1_ce\n API version:       1.32\n", nil, CompatSUSE},
+		{"old_upstream_ce", "Client:\n Version:      18.06.1-ce\n API version:  1.38\n", nil, CompatSUSE},
+		{"daemon_down", "Client:\n Version:           20.10.7\nCannot connect to the Docker daemon\n", errors.New("exit status 1"), CompatUpstream},
+		{"unknown_output", "something else", nil, CompatSUSE},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dockerVersion = func() (string, error) {
+				return tt.output, tt.err
+			}
+			if got := DetectCompat(); got != tt.want {
+				t.Errorf("DetectCompat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveCompat(t *testing.T) {
+	defer func(f func() (string, error)) { dockerVersion = f }(dockerVersion)
+	ran := false
+	dockerVersion = func() (string, error) {
+		ran = true
+		return "Client: Docker Engine - Community\n Version:           20.10.7\n", nil
+	}
+
+	tests := []struct {
+		name    string
+		runtime config.RuntimeConfiguration
+		want    string
+		wantRun bool
+	}{
+		{"default", config.RuntimeConfiguration{}, CompatSUSE, false},
+		{"suse", config.RuntimeConfiguration{Engine: "docker", DockerCompat: CompatSUSE}, CompatSUSE, false},
+		{"upstream", config.RuntimeConfiguration{Engine: "docker", DockerCompat: CompatUpstream}, CompatUpstream, false},
+		{"auto_docker", config.RuntimeConfiguration{Engine: "docker", DockerCompat: CompatAuto}, CompatUpstream, true},
+		{"auto_crio", config.RuntimeConfiguration{Engine: "crio", DockerCompat: CompatAuto}, CompatSUSE, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ran = false
+			if got := ResolveCompat(&config.KubicInitConfiguration{Runtime: tt.runtime}); got != tt.want {
+				t.Errorf("ResolveCompat() = %v, want %v", got, tt.want)
+			}
+			if ran != tt.wantRun {
+				t.Errorf("ResolveCompat() ran docker = %v, want %v", ran, tt.wantRun)
+			}
+		})
+	}
+}
+
+func Test_isDockerHub(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   bool
+	}{
+		{"docker.io", true},
+		{"https://docker.io/", true},
+		{"https://registry-1.docker.io", true},
+		{"index.docker.io", true},
+		{"https://mycompany.registry.com", false},
+		{"docker.io.example.com", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			if got := isDockerHub(tt.prefix); got != tt.want {
+				t.Errorf("isDockerHub() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
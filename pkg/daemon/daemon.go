@@ -1,53 +1,118 @@
 package daemon
 
 import (
-	"html/template"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
 	"os"
 
 	"github.com/kubic-project/caasp-init/pkg/config"
-)
-
-const (
-	daemonTemplate = `{ {{if .Bootstrap.Registries }} {{ $first := index .Bootstrap.Registries 0 }}
-  {{ if ne $first.Prefix ""}}"registries": [  {{range $r, $registry := .Bootstrap.Registries}}   {{ if $r }},{{end}}
-    {
-    "Mirrors": [ {{range $m, $mirror := $registry.Mirrors}} {{if $m}},{{end}}
-      {
-        "URL": "{{$mirror.URL}}"
-      }
-      {{end}}],
-      "Prefix": "{{$registry.Prefix}}"
-    }
-  {{end}}],
-  {{end}}{{end}}"iptables":false,
-  "log-level": "warn"
-}
-`
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/log"
 )
 
 var (
 	daemonFile = "/etc/docker/daemon.json"
 )
 
+// daemonConfig struct
+// Content of the docker daemon.json file
+// Registries: mirrors of each registry, understood by SUSE's docker.
+// RegistryMirrors: mirrors of Docker Hub, understood by the upstream docker.
+// InsecureRegistries: registries reached over plain HTTP or without verifying their certificate.
+type daemonConfig struct {
+	Registries         []registry `json:"registries,omitempty"`
+	RegistryMirrors    []string   `json:"registry-mirrors,omitempty"`
+	InsecureRegistries []string   `json:"insecure-registries,omitempty"`
+	IPTables           bool       `json:"iptables"`
+	LogLevel           string     `json:"log-level"`
+}
+
+type registry struct {
+	Mirrors []mirror `json:"Mirrors"`
+	Prefix  string   `json:"Prefix"`
+}
+
+type mirror struct {
+	URL string `json:"URL"`
+}
+
 // WriteConfigFile writes the daemon config file
-// will be generated from a basic template
+// will be generated from the default settings
 // and will include any mirror specified in the configuration
-func WriteConfigFile(config *config.KubicInitConfiguration) error {
-	tmpl, err := template.New("kubic").Parse(daemonTemplate)
+// for the docker flavor `compat`. The keys added by hand to the
+// file are kept.
+func WriteConfigFile(config *config.KubicInitConfiguration, compat string) error {
+	content, err := Render(config, compat)
 	if err != nil {
 		return err
 	}
 
-	file, err := os.Create(daemonFile)
+	content, err = keepForeignKeys(content)
 	if err != nil {
 		return err
 	}
-	err = tmpl.Execute(file, config)
+	_, err = fsutil.WriteFile(daemonFile, content, os.FileMode(0644))
+	return err
+}
+
+// Render returns the content of the daemon config file for the configuration.
+// With the upstream compatibility mode only the mirrors of Docker Hub are
+// written, as `registry-mirrors`, and the other registries are skipped with
+// a warning. `compat` is the mode returned by ResolveCompat, any other value
+// than the upstream one is rendered as the SUSE one.
+func Render(config *config.KubicInitConfiguration, compat string) ([]byte, error) {
+	if config == nil {
+		return nil, errors.New("configuration is nil")
+	}
+
+	if compat != CompatUpstream {
+		compat = CompatSUSE
+	}
+
+	daemon := daemonConfig{
+		LogLevel: "warn",
+	}
+	insecure := map[string]bool{}
+	for _, reg := range config.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		upstreamMirrors := compat == CompatUpstream && isDockerHub(reg.Prefix)
+		if compat == CompatUpstream && !upstreamMirrors {
+			log.Warnf("upstream docker can only mirror docker.io, skipping the mirrors of %s", reg.Prefix)
+			continue
+		}
+		r := registry{Prefix: reg.Prefix, Mirrors: []mirror{}}
+		for _, m := range reg.Mirrors {
+			r.Mirrors = append(r.Mirrors, mirror{URL: m.URL})
+			if upstreamMirrors {
+				daemon.RegistryMirrors = append(daemon.RegistryMirrors, m.URL)
+			}
+			if !m.Insecure {
+				continue
+			}
+			u, err := url.Parse(m.URL)
+			if err != nil {
+				return nil, err
+			}
+			if u.Host == "" {
+				return nil, fmt.Errorf("Error in configuration file: malformed Mirror URL \"%s\"", m.URL)
+			}
+			if !insecure[u.Host] {
+				insecure[u.Host] = true
+				daemon.InsecureRegistries = append(daemon.InsecureRegistries, u.Host)
+			}
+		}
+		if compat != CompatUpstream {
+			daemon.Registries = append(daemon.Registries, r)
+		}
+	}
+
+	b, err := json.MarshalIndent(daemon, "", "  ")
 	if err != nil {
-		os.RemoveAll(daemonFile)
-		return err
+		return nil, err
 	}
-	file.Sync()
-	file.Close()
-	return nil
+	return append(b, '\n'), nil
 }
@@ -1,7 +1,9 @@
 package daemon
 
 import (
+	"errors"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/kubic-project/caasp-init/pkg/config"
@@ -44,6 +46,10 @@ var (
 	}
 )
 
+func noDocker() (string, error) {
+	return "", errors.New("executable file not found in $PATH")
+}
+
 func TestWriteConfigFile(t *testing.T) {
 	type args struct {
 		config     *config.KubicInitConfiguration
@@ -62,7 +68,7 @@ func TestWriteConfigFile(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			daemonFile = tt.args.daemonFile
-			if err := WriteConfigFile(tt.args.config); (err != nil) != tt.wantErr {
+			if err := WriteConfigFile(tt.args.config, CompatSUSE); (err != nil) != tt.wantErr {
 				t.Errorf("WriteConfigFile() error = %v, wantErr %v", err, tt.wantErr)
 			}
 		})
@@ -70,3 +76,84 @@ func TestWriteConfigFile(t *testing.T) {
 	os.RemoveAll("daemon.json")
 	os.RemoveAll("/daemon.json")
 }
+
+func TestRender(t *testing.T) {
+	defer func(f func() (string, error)) { dockerVersion = f }(dockerVersion)
+	dockerVersion = func() (string, error) {
+		t.Fatalf("Render() ran docker")
+		return "", nil
+	}
+	insecureRegistries := &config.KubicInitConfiguration{
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "mycompany.registry.com",
+					Mirrors: []config.Mirror{
+						{URL: "http://first.mirror.com:5000", Insecure: true},
+						{URL: "https://second.mirror.com"},
+					},
+				},
+				{Prefix: "somewhere.io",
+					Mirrors: []config.Mirror{
+						{URL: "http://first.mirror.com:5000", Insecure: true},
+					},
+				},
+			},
+		},
+	}
+	malformedInsecure := &config.KubicInitConfiguration{
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "mycompany.registry.com",
+					Mirrors: []config.Mirror{
+						{URL: "first.mirror.com", Insecure: true},
+					},
+				},
+			},
+		},
+	}
+	upstreamRegistries := &config.KubicInitConfiguration{
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "https://docker.io",
+					Mirrors: []config.Mirror{
+						{URL: "https://first.mirror.com"},
+						{URL: "https://second.mirror.com"},
+					},
+				},
+				{Prefix: "somewhere.io",
+					Mirrors: []config.Mirror{
+						{URL: "https://local.lan.mirror.com"},
+						{URL: "http://insecure.lan.mirror.com", Insecure: true},
+					},
+				},
+			},
+		},
+	}
+	tests := []struct {
+		name    string
+		config  *config.KubicInitConfiguration
+		compat  string
+		want    string
+		wantErr bool
+	}{
+		{"nil", nil, CompatSUSE, "", true},
+		{"suse", twoRegistries, CompatSUSE, "\"registries\": [", false},
+		{"unresolved_auto", twoRegistries, CompatAuto, "\"registries\": [", false},
+		{"upstream", upstreamRegistries, CompatUpstream, "{\n  \"registry-mirrors\": [\n    \"https://first.mirror.com\",\n    \"https://second.mirror.com\"\n  ],\n  \"iptables\": false,\n  \"log-level\": \"warn\"\n}\n", false},
+		{"empty", emptyConfig, CompatSUSE, "{\n  \"iptables\": false,\n  \"log-level\": \"warn\"\n}\n", false},
+		{"insecure", insecureRegistries, CompatSUSE, "\"insecure-registries\": [\n    \"first.mirror.com:5000\"\n  ],", false},
+		{"malformed_insecure", malformedInsecure, CompatSUSE, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.config, tt.compat)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Render() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !strings.Contains(string(got), tt.want) {
+				t.Errorf("Render() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+// ownedKeys are the daemon.json keys written by caasp-init
+var ownedKeys = map[string]bool{
+	"registries":          true,
+	"registry-mirrors":    true,
+	"insecure-registries": true,
+	"iptables":            true,
+	"log-level":           true,
+}
+
+func owned(key string) bool {
+	return ownedKeys[key]
+}
+
+// Drift compares daemon.json with what caasp-init renders for the
+// configuration and the docker flavor `compat`. The keys added by hand are
+// foreign findings.
+func Drift(config *config.KubicInitConfiguration, compat string) ([]drift.Finding, error) {
+	want, got, err := keys(config, compat)
+	if err != nil {
+		return nil, err
+	}
+	if got == nil {
+		// missing, or modified when it cannot be decoded
+		return drift.File(daemonFile, nil)
+	}
+	return drift.Keys(daemonFile, "", want, got, owned), nil
+}
+
+// keys returns the keys rendered for the configuration and the ones of
+// daemon.json, nil when it is missing or cannot be decoded
+func keys(config *config.KubicInitConfiguration, compat string) (want, got map[string]json.RawMessage, err error) {
+	content, err := Render(config, compat)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := json.Unmarshal(content, &want); err != nil {
+		return nil, nil, err
+	}
+	return want, readKeys(), nil
+}
+
+// readKeys decodes daemon.json, nil when it is missing or cannot be
+// decoded
+func readKeys() map[string]json.RawMessage {
+	b, err := ioutil.ReadFile(daemonFile)
+	if err != nil {
+		return nil
+	}
+	got := map[string]json.RawMessage{}
+	if json.Unmarshal(b, &got) != nil {
+		return nil
+	}
+	return got
+}
+
+// keepForeignKeys returns the rendered `content` along with the keys added
+// by hand to daemon.json, the content is returned as is when there are none
+func keepForeignKeys(content []byte) ([]byte, error) {
+	got := readKeys()
+	var foreign []string
+	for _, f := range drift.Keys(daemonFile, "", nil, got, owned) {
+		if f.Owner == drift.Foreign {
+			foreign = append(foreign, f.Key)
+		}
+	}
+	if len(foreign) == 0 {
+		return content, nil
+	}
+
+	want := map[string]json.RawMessage{}
+	if err := json.Unmarshal(content, &want); err != nil {
+		return nil, err
+	}
+	for _, key := range foreign {
+		log.WithFields(log.Fields{"path": daemonFile, "key": key}).Infof("keeping key \"%s\" added by hand", key)
+	}
+	b, err := json.MarshalIndent(drift.Merge(want, got, owned), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
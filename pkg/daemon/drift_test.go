@@ -0,0 +1,126 @@
+package daemon
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+func TestDriftAndWriteConfigFile(t *testing.T) {
+	defer func(f string) { daemonFile = f }(daemonFile)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-daemon")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	rendered, err := Render(twoRegistries, CompatSUSE)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	tests := []struct {
+		name      string
+		content   string
+		want      []drift.Finding
+		wantAfter []drift.Finding
+	}{
+		{"same", string(rendered), nil, nil},
+		{"missing", "", []drift.Finding{{Path: "daemon.json", Owner: drift.Owned, Kind: drift.Missing}}, nil},
+		{"malformed", "{", []drift.Finding{{Path: "daemon.json", Owner: drift.Owned, Kind: drift.Modified}}, nil},
+		{"edited", `{"log-level": "debug", "iptables": false, "debug": true, "registry-mirrors": ["https://a.com"]}`, []drift.Finding{
+			{Path: "daemon.json", Key: "debug", Owner: drift.Foreign, Kind: drift.Extra},
+			{Path: "daemon.json", Key: "log-level", Owner: drift.Owned, Kind: drift.Modified},
+			{Path: "daemon.json", Key: "registries", Owner: drift.Owned, Kind: drift.Missing},
+			{Path: "daemon.json", Key: "registry-mirrors", Owner: drift.Owned, Kind: drift.Extra},
+		}, []drift.Finding{
+			{Path: "daemon.json", Key: "debug", Owner: drift.Foreign, Kind: drift.Extra},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			daemonFile = filepath.Join(tmpDir, tt.name, "daemon.json")
+			os.MkdirAll(filepath.Dir(daemonFile), 0755)
+			if tt.content != "" {
+				if err := ioutil.WriteFile(daemonFile, []byte(tt.content), 0644); err != nil {
+					t.Fatalf("writing %s: %s", daemonFile, err)
+				}
+			}
+			relative := func(findings []drift.Finding) []drift.Finding {
+				for i := range findings {
+					findings[i].Path = filepath.Base(findings[i].Path)
+				}
+				return findings
+			}
+
+			got, err := Drift(twoRegistries, CompatSUSE)
+			if err != nil {
+				t.Fatalf("Drift() error = %v", err)
+			}
+			if !reflect.DeepEqual(relative(got), tt.want) {
+				t.Errorf("Drift() = %v, want %v", got, tt.want)
+			}
+
+			if err := WriteConfigFile(twoRegistries, CompatSUSE); err != nil {
+				t.Fatalf("WriteConfigFile() error = %v", err)
+			}
+			got, _ = Drift(twoRegistries, CompatSUSE)
+			if !reflect.DeepEqual(relative(got), tt.wantAfter) {
+				t.Errorf("Drift() = %v after WriteConfigFile(), want %v", got, tt.wantAfter)
+			}
+			b, _ := ioutil.ReadFile(daemonFile)
+			if err := json.Unmarshal(b, &map[string]interface{}{}); err != nil {
+				t.Errorf("WriteConfigFile() wrote %s: %v", b, err)
+			}
+		})
+	}
+}
+
+func TestWriteConfigFileKeepsForeignKeys(t *testing.T) {
+	defer func(f string) { daemonFile = f }(daemonFile)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-daemon")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	daemonFile = filepath.Join(tmpDir, "daemon.json")
+	if err := ioutil.WriteFile(daemonFile, []byte(`{"log-level": "debug", "data-root": "/srv/docker", "registry-mirrors": ["https://a.com"]}`), 0644); err != nil {
+		t.Fatalf("writing %s: %s", daemonFile, err)
+	}
+
+	log.ResetWarnings()
+	defer log.ResetWarnings()
+	log.SetOutput(ioutil.Discard)
+	defer log.SetOutput(os.Stderr)
+	if err := WriteConfigFile(twoRegistries, CompatSUSE); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v", err)
+	}
+	if warnings := log.Warnings(); len(warnings) != 0 {
+		t.Errorf("WriteConfigFile() warnings = %v", warnings)
+	}
+
+	got := map[string]interface{}{}
+	b, _ := ioutil.ReadFile(daemonFile)
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("WriteConfigFile() wrote %s: %v", b, err)
+	}
+	if got["data-root"] != "/srv/docker" {
+		t.Errorf("WriteConfigFile() dropped the key added by hand: %s", b)
+	}
+	if _, ok := got["registry-mirrors"]; ok || got["log-level"] != "warn" || got["registries"] == nil {
+		t.Errorf("WriteConfigFile() did not set the owned keys: %s", b)
+	}
+
+	// written again, the content is the same
+	if err := WriteConfigFile(twoRegistries, CompatSUSE); err != nil {
+		t.Fatalf("WriteConfigFile() error = %v", err)
+	}
+	if again, _ := ioutil.ReadFile(daemonFile); string(again) != string(b) {
+		t.Errorf("WriteConfigFile() is not stable: %s, then %s", b, again)
+	}
+}
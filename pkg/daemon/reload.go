@@ -0,0 +1,80 @@
+package daemon
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/reload"
+)
+
+// Unit is the systemd unit of the docker daemon
+const Unit = "docker.service"
+
+// reloadableKeys are the daemon.json keys applied by docker on SIGHUP
+var reloadableKeys = map[string]bool{
+	"debug":                    true,
+	"labels":                   true,
+	"live-restore":             true,
+	"max-concurrent-downloads": true,
+	"max-concurrent-uploads":   true,
+	"max-download-attempts":    true,
+	"default-runtime":          true,
+	"runtimes":                 true,
+	"authorization-plugins":    true,
+	"insecure-registries":      true,
+	"registry-mirrors":         true,
+	"shutdown-timeout":         true,
+	"features":                 true,
+}
+
+// ReloadAction returns what docker needs to do to apply the changes made to
+// daemon.json: a reload when only reloadable keys changed, a restart when
+// any other key changed or the file was created.
+func ReloadAction(changes []fsutil.Change) reload.Action {
+	action := reload.None
+	for _, change := range changes {
+		if change.Path != daemonFile || !change.Changed() {
+			continue
+		}
+		action = reload.Max(action, keysAction(change.Previous, change.Content))
+	}
+	return action
+}
+
+func keysAction(previous, content []byte) reload.Action {
+	if previous == nil {
+		return reload.Restart
+	}
+	before := map[string]json.RawMessage{}
+	after := map[string]json.RawMessage{}
+	if json.Unmarshal(previous, &before) != nil || json.Unmarshal(content, &after) != nil {
+		return reload.Restart
+	}
+
+	action := reload.None
+	for _, keys := range []map[string]json.RawMessage{before, after} {
+		for key := range keys {
+			if sameValue(before[key], after[key]) {
+				continue
+			}
+			if !reloadableKeys[key] {
+				return reload.Restart
+			}
+			action = reload.Reload
+		}
+	}
+	return action
+}
+
+// sameValue compares two JSON values ignoring the formatting
+func sameValue(a, b json.RawMessage) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	var ca, cb bytes.Buffer
+	if json.Compact(&ca, a) != nil || json.Compact(&cb, b) != nil {
+		return false
+	}
+	return bytes.Equal(ca.Bytes(), cb.Bytes())
+}
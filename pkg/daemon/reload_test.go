@@ -0,0 +1,53 @@
+package daemon
+
+import (
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/reload"
+)
+
+func TestReloadAction(t *testing.T) {
+	defer func(f string) { daemonFile = f }(daemonFile)
+	daemonFile = "/etc/docker/daemon.json"
+
+	base := `{"iptables": false, "log-level": "warn"}`
+	tests := []struct {
+		name    string
+		changes []fsutil.Change
+		want    reload.Action
+	}{
+		{"no_changes", nil, reload.None},
+		{"unchanged", []fsutil.Change{
+			{Path: daemonFile, Action: fsutil.Unchanged, Previous: []byte(base), Content: []byte(base)},
+		}, reload.None},
+		{"created", []fsutil.Change{
+			{Path: daemonFile, Action: fsutil.Created, Content: []byte(base)},
+		}, reload.Restart},
+		{"formatting", []fsutil.Change{
+			{Path: daemonFile, Action: fsutil.Updated, Previous: []byte(base), Content: []byte("{\n  \"iptables\": false,\n  \"log-level\": \"warn\"\n}\n")},
+		}, reload.None},
+		{"mirrors_added", []fsutil.Change{
+			{Path: daemonFile, Action: fsutil.Updated, Previous: []byte(base), Content: []byte(`{"registry-mirrors": ["https://mirror.com"], "insecure-registries": ["mirror.com"], "iptables": false, "log-level": "warn"}`)},
+		}, reload.Reload},
+		{"mirrors_removed", []fsutil.Change{
+			{Path: daemonFile, Action: fsutil.Updated, Previous: []byte(`{"registry-mirrors": ["https://mirror.com"], "iptables": false, "log-level": "warn"}`), Content: []byte(base)},
+		}, reload.Reload},
+		{"registries_changed", []fsutil.Change{
+			{Path: daemonFile, Action: fsutil.Updated, Previous: []byte(base), Content: []byte(`{"registries": [], "iptables": false, "log-level": "warn"}`)},
+		}, reload.Restart},
+		{"malformed_previous", []fsutil.Change{
+			{Path: daemonFile, Action: fsutil.Updated, Previous: []byte("{"), Content: []byte(base)},
+		}, reload.Restart},
+		{"other_file", []fsutil.Change{
+			{Path: "/etc/containers/auth.json", Action: fsutil.Created, Content: []byte("{}")},
+		}, reload.None},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReloadAction(tt.changes); got != tt.want {
+				t.Errorf("ReloadAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package drift
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sort"
+)
+
+const (
+	// Owned the file or key is written by caasp-init
+	Owned = "owned"
+
+	// Foreign the key was added by someone else, caasp-init keeps it when
+	// it writes the file
+	Foreign = "foreign"
+)
+
+const (
+	// Missing the file or key caasp-init writes is absent
+	Missing = "missing"
+
+	// Modified the file or key has another value than caasp-init writes
+	Modified = "modified"
+
+	// Extra the key is present but caasp-init does not write it
+	Extra = "extra"
+)
+
+// Finding struct
+// Difference between a file on disk and what caasp-init renders
+// Path: path of the file.
+// Key: key of the file, empty when the whole file differs.
+// Owner: owned or foreign.
+// Kind: missing, modified or extra.
+type Finding struct {
+	Path  string
+	Key   string
+	Owner string
+	Kind  string
+}
+
+func (f Finding) String() string {
+	if f.Key == "" {
+		return fmt.Sprintf("%s %s %s", f.Owner, f.Kind, f.Path)
+	}
+	return fmt.Sprintf("%s %s %s %s", f.Owner, f.Kind, f.Path, f.Key)
+}
+
+// Status of a drift check, its value is the exit code of a monitoring plugin
+type Status int
+
+const (
+	// OK the files have the content caasp-init renders
+	OK Status = iota
+
+	// Warning only foreign keys were added
+	Warning
+
+	// Critical files or keys owned by caasp-init differ
+	Critical
+
+	// Unknown the check could not be done
+	Unknown
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Warning:
+		return "WARNING"
+	case Critical:
+		return "CRITICAL"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// Check returns the status of the findings: critical when anything owned by
+// caasp-init differs, warning when only foreign keys were added
+func Check(findings []Finding) Status {
+	status := OK
+	for _, f := range findings {
+		if f.Owner == Owned {
+			return Critical
+		}
+		status = Warning
+	}
+	return status
+}
+
+// File compares the whole content of the file at `path` with `want`
+func File(path string, want []byte) ([]Finding, error) {
+	got, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return []Finding{{Path: path, Owner: Owned, Kind: Missing}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(got, want) {
+		return []Finding{{Path: path, Owner: Owned, Kind: Modified}}, nil
+	}
+	return nil, nil
+}
+
+// Keys compares the keys of the JSON object `got` of the file at `path`
+// with the ones caasp-init renders in `want`. The keys `owned` by caasp-init
+// but not rendered are owned extras, the others are foreign. `prefix` is
+// prepended to the keys of the findings.
+func Keys(path, prefix string, want, got map[string]json.RawMessage, owned func(key string) bool) []Finding {
+	var findings []Finding
+	for _, key := range sortedKeys(want, got) {
+		w, inWant := want[key]
+		g, inGot := got[key]
+		f := Finding{Path: path, Key: prefix + key, Owner: Owned}
+		switch {
+		case !inGot:
+			f.Kind = Missing
+		case !inWant:
+			f.Kind = Extra
+			if !owned(key) {
+				f.Owner = Foreign
+			}
+		case !sameValue(w, g):
+			f.Kind = Modified
+		default:
+			continue
+		}
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+// Merge returns `got` with the keys `owned` by caasp-init replaced by the
+// ones of `want`, keeping the foreign keys
+func Merge(want, got map[string]json.RawMessage, owned func(key string) bool) map[string]json.RawMessage {
+	merged := map[string]json.RawMessage{}
+	for key, value := range got {
+		if _, inWant := want[key]; !inWant && owned(key) {
+			continue
+		}
+		merged[key] = value
+	}
+	for key, value := range want {
+		merged[key] = value
+	}
+	return merged
+}
+
+func sortedKeys(maps ...map[string]json.RawMessage) []string {
+	seen := map[string]bool{}
+	var keys []string
+	for _, m := range maps {
+		for key := range m {
+			if !seen[key] {
+				seen[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sameValue compares two JSON values, ignoring the formatting
+func sameValue(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return bytes.Equal(a, b)
+	}
+	return reflect.DeepEqual(va, vb)
+}
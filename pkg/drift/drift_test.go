@@ -0,0 +1,117 @@
+package drift
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func decode(t *testing.T, s string) map[string]json.RawMessage {
+	m := map[string]json.RawMessage{}
+	if err := json.Unmarshal([]byte(s), &m); err != nil {
+		t.Fatalf("decoding %s: %v", s, err)
+	}
+	return m
+}
+
+func owned(key string) bool {
+	return key == "log-level" || key == "registry-mirrors" || key == "iptables"
+}
+
+func TestKeys(t *testing.T) {
+	want := `{"log-level": "warn", "iptables": false}`
+	tests := []struct {
+		name string
+		got  string
+		want []Finding
+	}{
+		{"same", `{"iptables":false,"log-level":"warn"}`, nil},
+		{"modified", `{"log-level": "debug", "iptables": false}`, []Finding{
+			{Path: "daemon.json", Key: "log-level", Owner: Owned, Kind: Modified},
+		}},
+		{"missing", `{"log-level": "warn"}`, []Finding{
+			{Path: "daemon.json", Key: "iptables", Owner: Owned, Kind: Missing},
+		}},
+		{"owned_extra", `{"log-level": "warn", "iptables": false, "registry-mirrors": ["https://a.com"]}`, []Finding{
+			{Path: "daemon.json", Key: "registry-mirrors", Owner: Owned, Kind: Extra},
+		}},
+		{"foreign", `{"log-level": "warn", "iptables": false, "debug": true, "data-root": "/srv"}`, []Finding{
+			{Path: "daemon.json", Key: "data-root", Owner: Foreign, Kind: Extra},
+			{Path: "daemon.json", Key: "debug", Owner: Foreign, Kind: Extra},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Keys("daemon.json", "", decode(t, want), decode(t, tt.got), owned); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Keys() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerge(t *testing.T) {
+	want := decode(t, `{"log-level": "warn", "iptables": false}`)
+	got := decode(t, `{"log-level": "debug", "registry-mirrors": ["https://a.com"], "debug": true}`)
+
+	merged := Merge(want, got, owned)
+	if f := Keys("daemon.json", "", decode(t, `{"log-level": "warn", "iptables": false, "debug": true}`), merged, owned); f != nil {
+		t.Errorf("Merge() = %s, differences %v", merged, f)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name     string
+		findings []Finding
+		want     Status
+	}{
+		{"none", nil, OK},
+		{"foreign", []Finding{{Owner: Foreign}}, Warning},
+		{"owned", []Finding{{Owner: Foreign}, {Owner: Owned}}, Critical},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(tt.findings); got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-drift")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "ca.crt")
+
+	tests := []struct {
+		name    string
+		content string
+		want    []Finding
+	}{
+		{"missing", "", []Finding{{Path: path, Owner: Owned, Kind: Missing}}},
+		{"same", "cert", nil},
+		{"modified", "edited", []Finding{{Path: path, Owner: Owned, Kind: Modified}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.content != "" {
+				if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+					t.Fatalf("writing %s: %s", path, err)
+				}
+			}
+			got, err := File(path, []byte("cert"))
+			if err != nil {
+				t.Fatalf("File() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("File() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
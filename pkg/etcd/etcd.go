@@ -0,0 +1,89 @@
+package etcd
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+// hostname returns the name of the node
+var hostname = os.Hostname
+
+// SANs struct
+// Subject Alternative Names of the certificates of the local etcd
+// Server: names of the server certificate, used by the clients.
+// Peer: names of the peer certificate, used by the other members.
+type SANs struct {
+	Server []string `yaml:"serverCertSANs,omitempty"`
+	Peer   []string `yaml:"peerCertSANs,omitempty"`
+}
+
+// Compute returns the Subject Alternative Names of the etcd certificates:
+// the configured ones followed, with `etcd.local.autoSANs`, by the name, the
+// bind address and the external FQDN of the node. The IP addresses are
+// normalized, the DNS names lowercased and the duplicates removed.
+func Compute(cfg *config.KubicInitConfiguration) (*SANs, error) {
+	local := cfg.Etcd.LocalEtcd
+	if local == nil {
+		return &SANs{}, nil
+	}
+	var auto []string
+	if local.AutoSANs {
+		var err error
+		auto, err = nodeNames(cfg)
+		if err != nil {
+			return nil, err
+		}
+	}
+	server, err := normalize(append(append([]string{}, local.ServerCertSANs...), auto...))
+	if err != nil {
+		return nil, err
+	}
+	peer, err := normalize(append(append([]string{}, local.PeerCertSANs...), auto...))
+	if err != nil {
+		return nil, err
+	}
+	return &SANs{Server: server, Peer: peer}, nil
+}
+
+// nodeNames returns the name, the bind address and the external FQDN of
+// the node, the ones that are set
+func nodeNames(cfg *config.KubicInitConfiguration) ([]string, error) {
+	name, err := hostname()
+	if err != nil {
+		return nil, fmt.Errorf("unable to get the name of the node: %v", err)
+	}
+	names := []string{name}
+	if ip := net.ParseIP(cfg.Network.Bind.Address); ip != nil && !ip.IsUnspecified() {
+		names = append(names, ip.String())
+	}
+	if fqdn := cfg.Network.DNS.ExternalFqdn; fqdn != "" {
+		names = append(names, fqdn)
+	}
+	return names, nil
+}
+
+// normalize checks every entry is an IP address or a DNS name and returns
+// them in the same order without the duplicates
+func normalize(sans []string) ([]string, error) {
+	var result []string
+	seen := map[string]bool{}
+	for _, san := range sans {
+		if !config.ValidSAN(san) {
+			return nil, fmt.Errorf("invalid Subject Alternative Name \"%s\", use an IP address or a DNS name", san)
+		}
+		if ip := net.ParseIP(san); ip != nil {
+			san = ip.String()
+		} else {
+			san = strings.ToLower(strings.TrimSuffix(san, "."))
+		}
+		if !seen[san] {
+			seen[san] = true
+			result = append(result, san)
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,76 @@
+package etcd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+func TestCompute(t *testing.T) {
+	defer func(h func() (string, error)) { hostname = h }(hostname)
+	hostname = func() (string, error) { return "Node1", nil }
+
+	withEtcd := func(local *config.LocalEtcdConfiguration) *config.KubicInitConfiguration {
+		cfg := &config.KubicInitConfiguration{Etcd: config.EtcdConfiguration{LocalEtcd: local}}
+		cfg.Network.Bind.Address = "192.168.1.10"
+		cfg.Network.DNS.ExternalFqdn = "k8s.example.com"
+		return cfg
+	}
+
+	tests := []struct {
+		name    string
+		cfg     *config.KubicInitConfiguration
+		want    *SANs
+		wantErr bool
+	}{
+		{"no_local", withEtcd(nil), &SANs{}, false},
+		{"configured", withEtcd(&config.LocalEtcdConfiguration{
+			ServerCertSANs: []string{"etcd.example.com", "ETCD.example.com.", "fd00:0::10", "fd00::10"},
+			PeerCertSANs:   []string{"192.168.1.11"},
+		}), &SANs{
+			Server: []string{"etcd.example.com", "fd00::10"},
+			Peer:   []string{"192.168.1.11"},
+		}, false},
+		{"auto", withEtcd(&config.LocalEtcdConfiguration{
+			ServerCertSANs: []string{"k8s.example.com", "etcd.example.com"},
+			AutoSANs:       true,
+		}), &SANs{
+			Server: []string{"k8s.example.com", "etcd.example.com", "node1", "192.168.1.10"},
+			Peer:   []string{"node1", "192.168.1.10", "k8s.example.com"},
+		}, false},
+		{"invalid", withEtcd(&config.LocalEtcdConfiguration{PeerCertSANs: []string{"etcd_0"}}), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Compute(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Compute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Compute() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("unspecified_bind", func(t *testing.T) {
+		cfg := withEtcd(&config.LocalEtcdConfiguration{AutoSANs: true})
+		cfg.Network.Bind.Address = "0.0.0.0"
+		cfg.Network.DNS.ExternalFqdn = ""
+		got, err := Compute(cfg)
+		if err != nil {
+			t.Fatalf("Compute() error = %v", err)
+		}
+		if want := []string{"node1"}; !reflect.DeepEqual(got.Server, want) {
+			t.Errorf("Compute() server = %q, want %q", got.Server, want)
+		}
+	})
+
+	t.Run("hostname_error", func(t *testing.T) {
+		hostname = func() (string, error) { return "", errors.New("no name") }
+		if _, err := Compute(withEtcd(&config.LocalEtcdConfiguration{AutoSANs: true})); err == nil {
+			t.Errorf("Compute() should fail without the name of the node")
+		}
+	})
+}
@@ -0,0 +1,246 @@
+package fsutil
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+// Action performed on a managed file
+type Action string
+
+const (
+	// Created the file did not exist
+	Created Action = "created"
+
+	// Updated the content of the file changed
+	Updated Action = "updated"
+
+	// Unchanged the file already had the same content
+	Unchanged Action = "unchanged"
+
+	// Removed the file was removed
+	Removed Action = "removed"
+)
+
+// Change struct
+// Records a file written by caasp-init
+// Path: path of the file.
+// Action: what happened to the file.
+// Mode: permissions of the file.
+// Previous: content of the file before writing it, nil when it did not exist.
+// PreviousMode: permissions of the file before writing it.
+// Content: content written to the file, nil when it was removed.
+type Change struct {
+	Path         string
+	Action       Action
+	Mode         os.FileMode
+	Previous     []byte
+	PreviousMode os.FileMode
+	Content      []byte
+}
+
+// Changed tells whether the content of the file changed
+func (c Change) Changed() bool {
+	return c.Action != Unchanged
+}
+
+var (
+	mu      sync.Mutex
+	changes []Change
+	dirs    []string
+)
+
+// WriteFile writes `data` to the file at `path`, setting its permissions to
+// `perm` even when it already exists, and records the change. A file with
+// the same content is not written again, so its modification time is kept,
+// only its permissions are fixed if needed. The content is written to a
+// temporary file with the final permissions which then replaces the file,
+// so that a secret is never readable with the permissions of the previous
+// file, nor half written.
+func WriteFile(path string, data []byte, perm os.FileMode) (*Change, error) {
+	change := Change{Path: path, Action: Created, Mode: perm, Content: data}
+
+	previous, previousMode, err := readFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if previous != nil {
+		change.Previous = previous
+		change.PreviousMode = previousMode
+		change.Action = Updated
+		if bytes.Equal(previous, data) {
+			change.Action = Unchanged
+		}
+	}
+
+	if change.Action != Unchanged {
+		err = replaceFile(path, data, perm)
+		if err != nil {
+			return nil, err
+		}
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.Mode().Perm() != perm {
+		err = os.Chmod(path, perm)
+		if err != nil {
+			return nil, err
+		}
+		if change.Action == Unchanged {
+			change.Action = Updated
+		}
+	}
+
+	entry := log.WithFields(log.Fields{"path": path, "action": change.Action, "mode": fmt.Sprintf("%04o", perm)})
+	if change.Changed() {
+		entry.Infof("file written")
+	} else {
+		entry.Debugf("file unchanged, skipped")
+	}
+
+	record(change)
+	return &change, nil
+}
+
+// replaceFile atomically replaces the file at `path` with `data`, through a
+// temporary file in the same directory created with the permissions `perm`
+func replaceFile(path string, data []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "."+filepath.Base(path)+".")
+	if err != nil {
+		return err
+	}
+	// the temporary file is removed unless it was renamed
+	defer os.Remove(tmp.Name())
+
+	err = tmp.Chmod(perm)
+	if err == nil {
+		_, err = tmp.Write(data)
+	}
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// RemoveFile removes the file at `path`, if it exists, and records the change
+func RemoveFile(path string) (*Change, error) {
+	previous, previousMode, err := readFile(path)
+	if err != nil || previous == nil {
+		return nil, err
+	}
+	err = os.Remove(path)
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{"path": path, "action": Removed}).Infof("file removed")
+
+	change := Change{Path: path, Action: Removed, Mode: previousMode, Previous: previous, PreviousMode: previousMode}
+	record(change)
+	return &change, nil
+}
+
+// MkdirAll creates the directory at `path` with the permissions `perm`,
+// along with its missing parents, and records the directories it created,
+// parents first
+func MkdirAll(path string, perm os.FileMode) error {
+	var missing []string
+	for dir := filepath.Clean(path); ; dir = filepath.Dir(dir) {
+		if _, err := os.Stat(dir); err == nil {
+			break
+		} else if !os.IsNotExist(err) {
+			return err
+		}
+		missing = append([]string{dir}, missing...)
+		if dir == filepath.Dir(dir) {
+			break
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	err := os.MkdirAll(path, perm)
+	if err != nil {
+		return err
+	}
+	for _, dir := range missing {
+		log.WithFields(log.Fields{"path": dir, "action": Created, "mode": fmt.Sprintf("%04o", perm)}).Infof("directory created")
+	}
+
+	mu.Lock()
+	dirs = append(dirs, missing...)
+	mu.Unlock()
+	return nil
+}
+
+// readFile returns the content and permissions of a file, a nil content
+// when it does not exist
+func readFile(path string) ([]byte, os.FileMode, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	if content == nil {
+		content = []byte{}
+	}
+	return content, info.Mode().Perm(), nil
+}
+
+func record(change Change) {
+	mu.Lock()
+	changes = append(changes, change)
+	mu.Unlock()
+}
+
+// Changes returns the files written since the last ResetChanges
+func Changes() []Change {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]Change{}, changes...)
+}
+
+// Dirs returns the directories created since the last ResetChanges, parents
+// first
+func Dirs() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]string{}, dirs...)
+}
+
+// Changed tells whether any file changed since the last ResetChanges
+func Changed() bool {
+	for _, change := range Changes() {
+		if change.Changed() {
+			return true
+		}
+	}
+	return false
+}
+
+// ResetChanges forgets the files written and the directories created so far
+func ResetChanges() {
+	mu.Lock()
+	changes = nil
+	dirs = nil
+	mu.Unlock()
+}
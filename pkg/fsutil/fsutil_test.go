@@ -0,0 +1,203 @@
+package fsutil
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestWriteFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-fsutil")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	file := filepath.Join(tmpDir, "daemon.json")
+
+	ResetChanges()
+	tests := []struct {
+		name       string
+		path       string
+		data       string
+		perm       os.FileMode
+		wantAction Action
+		wantErr    bool
+	}{
+		{"create", file, "{}", 0644, Created, false},
+		{"same_content", file, "{}", 0644, Unchanged, false},
+		{"update", file, "{\"iptables\":false}", 0644, Updated, false},
+		{"permissions", file, "{\"iptables\":false}", 0600, Updated, false},
+		{"same_permissions", file, "{\"iptables\":false}", 0600, Unchanged, false},
+		{"missing_dir", filepath.Join(tmpDir, "missing", "daemon.json"), "{}", 0644, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := WriteFile(tt.path, []byte(tt.data), tt.perm)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("WriteFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.Action != tt.wantAction {
+				t.Errorf("WriteFile() action = %v, want %v", got.Action, tt.wantAction)
+			}
+			b, _ := ioutil.ReadFile(tt.path)
+			if string(b) != tt.data {
+				t.Errorf("WriteFile() content = %s, want %s", b, tt.data)
+			}
+			if info, _ := os.Stat(tt.path); info.Mode().Perm() != tt.perm {
+				t.Errorf("WriteFile() permissions = %v, want %v", info.Mode().Perm(), tt.perm)
+			}
+		})
+	}
+
+	changes := Changes()
+	if len(changes) != 5 {
+		t.Fatalf("Changes() returned %d changes, want 5", len(changes))
+	}
+	if string(changes[2].Previous) != "{}" || !changes[2].Changed() || changes[1].Changed() {
+		t.Errorf("Changes() = %+v", changes)
+	}
+	if !Changed() {
+		t.Errorf("Changed() = false, want true")
+	}
+	ResetChanges()
+	if len(Changes()) != 0 || Changed() {
+		t.Errorf("ResetChanges() did not forget the changes")
+	}
+}
+
+func TestWriteFileReplacesFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-fsutil")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	defer ResetChanges()
+
+	// a public file rewritten with a secret
+	file := filepath.Join(tmpDir, "auth.json")
+	if err := ioutil.WriteFile(file, []byte("{}"), 0644); err != nil {
+		t.Fatalf("writing %s: %s", file, err)
+	}
+	got, err := WriteFile(file, []byte(`{"auths":{}}`), 0600)
+	if err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if got.Action != Updated || got.PreviousMode != 0644 {
+		t.Errorf("WriteFile() = %+v", got)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+		t.Errorf("WriteFile() permissions = %v, want 0600", info.Mode().Perm())
+	}
+	if b, _ := ioutil.ReadFile(file); string(b) != `{"auths":{}}` {
+		t.Errorf("WriteFile() content = %s", b)
+	}
+	entries, err := ioutil.ReadDir(tmpDir)
+	if err != nil {
+		t.Fatalf("reading %s: %s", tmpDir, err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("WriteFile() left %d files in the directory, want 1", len(entries))
+	}
+}
+
+func TestWriteFileKeepsModificationTime(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-fsutil")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	file := filepath.Join(tmpDir, "ca.crt")
+
+	if err := ioutil.WriteFile(file, []byte("cert"), 0644); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(file, past, past); err != nil {
+		t.Fatalf("setting the modification time: %s", err)
+	}
+
+	ResetChanges()
+	defer ResetChanges()
+	if _, err := WriteFile(file, []byte("cert"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		t.Fatalf("stat: %s", err)
+	}
+	if !info.ModTime().Equal(past) {
+		t.Errorf("WriteFile() modification time = %v, want %v", info.ModTime(), past)
+	}
+	if Changed() {
+		t.Errorf("Changed() = true for an unchanged file")
+	}
+}
+
+func TestRemoveFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-fsutil")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	file := filepath.Join(tmpDir, "ca.crt")
+	if err := ioutil.WriteFile(file, []byte("cert"), 0600); err != nil {
+		t.Fatalf("writing file: %s", err)
+	}
+
+	ResetChanges()
+	defer ResetChanges()
+	got, err := RemoveFile(file)
+	if err != nil {
+		t.Fatalf("RemoveFile() error = %v", err)
+	}
+	if got == nil || got.Action != Removed || string(got.Previous) != "cert" || got.PreviousMode != 0600 {
+		t.Errorf("RemoveFile() = %+v", got)
+	}
+	if _, err := os.Stat(file); !os.IsNotExist(err) {
+		t.Errorf("RemoveFile() did not remove the file")
+	}
+
+	got, err = RemoveFile(file)
+	if err != nil || got != nil {
+		t.Errorf("RemoveFile() = %+v, %v for a missing file", got, err)
+	}
+	if len(Changes()) != 1 {
+		t.Errorf("Changes() = %+v", Changes())
+	}
+}
+
+func TestMkdirAll(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-fsutil")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	dir := filepath.Join(tmpDir, "certs.d", "mirror.com")
+
+	ResetChanges()
+	defer ResetChanges()
+	if err := MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("MkdirAll() did not create %s: %v", dir, err)
+	}
+	if err := MkdirAll(dir, 0755); err != nil {
+		t.Fatalf("MkdirAll() of an existing directory error = %v", err)
+	}
+	want := []string{filepath.Join(tmpDir, "certs.d"), dir}
+	if got := Dirs(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Dirs() = %q, want %q", got, want)
+	}
+
+	ResetChanges()
+	if got := Dirs(); len(got) != 0 {
+		t.Errorf("Dirs() = %q after ResetChanges()", got)
+	}
+}
@@ -0,0 +1,255 @@
+package kubeadm
+
+import (
+	"errors"
+	"net"
+	"strconv"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/etcd"
+	"github.com/kubic-project/caasp-init/pkg/log"
+	"github.com/kubic-project/caasp-init/pkg/oidc"
+)
+
+// APIVersion is the version of the kubeadm configuration API rendered
+const APIVersion = "kubeadm.k8s.io/v1beta1"
+
+// criSockets are the sockets of the container runtime engines
+var criSockets = map[string]string{
+	"docker": "/var/run/dockershim.sock",
+	"crio":   "/var/run/crio/crio.sock",
+}
+
+// TypeMeta struct
+// Type of a kubeadm configuration document
+type TypeMeta struct {
+	APIVersion string `yaml:"apiVersion"`
+	Kind       string `yaml:"kind"`
+}
+
+// BootstrapToken struct
+// Token used by the joining nodes to authenticate against the seeder
+type BootstrapToken struct {
+	Token  string   `yaml:"token"`
+	Usages []string `yaml:"usages,omitempty"`
+	Groups []string `yaml:"groups,omitempty"`
+}
+
+// NodeRegistration struct
+// How the node registers in the cluster
+// CRISocket: socket of the container runtime engine.
+type NodeRegistration struct {
+	CRISocket string `yaml:"criSocket,omitempty"`
+}
+
+// APIEndpoint struct
+// Address and port the API server of the node listens on
+type APIEndpoint struct {
+	AdvertiseAddress string `yaml:"advertiseAddress,omitempty"`
+	BindPort         int    `yaml:"bindPort,omitempty"`
+}
+
+// InitConfiguration struct
+// Configuration of `kubeadm init` specific to the seeder
+type InitConfiguration struct {
+	TypeMeta         `yaml:",inline"`
+	BootstrapTokens  []BootstrapToken `yaml:"bootstrapTokens,omitempty"`
+	NodeRegistration NodeRegistration `yaml:"nodeRegistration,omitempty"`
+	LocalAPIEndpoint APIEndpoint      `yaml:"localAPIEndpoint,omitempty"`
+}
+
+// LocalEtcd struct
+// Etcd instance run by kubeadm on the control plane nodes
+type LocalEtcd struct {
+	ServerCertSANs []string `yaml:"serverCertSANs,omitempty"`
+	PeerCertSANs   []string `yaml:"peerCertSANs,omitempty"`
+}
+
+// Etcd struct
+// Etcd of the cluster
+type Etcd struct {
+	Local *LocalEtcd `yaml:"local,omitempty"`
+}
+
+// Networking struct
+// Networks of the cluster
+type Networking struct {
+	ServiceSubnet string `yaml:"serviceSubnet,omitempty"`
+	PodSubnet     string `yaml:"podSubnet,omitempty"`
+	DNSDomain     string `yaml:"dnsDomain,omitempty"`
+}
+
+// APIServer struct
+// Settings of the API server
+// ExtraArgs: flags of kube-apiserver, without the leading dashes.
+// CertSANs: extra names of the API server certificate.
+type APIServer struct {
+	ExtraArgs map[string]string `yaml:"extraArgs,omitempty"`
+	CertSANs  []string          `yaml:"certSANs,omitempty"`
+}
+
+// ClusterConfiguration struct
+// Configuration of the cluster shared by all the control plane nodes
+type ClusterConfiguration struct {
+	TypeMeta             `yaml:",inline"`
+	Etcd                 Etcd       `yaml:"etcd,omitempty"`
+	Networking           Networking `yaml:"networking,omitempty"`
+	APIServer            APIServer  `yaml:"apiServer,omitempty"`
+	ControlPlaneEndpoint string     `yaml:"controlPlaneEndpoint,omitempty"`
+	CertificatesDir      string     `yaml:"certificatesDir,omitempty"`
+}
+
+// BootstrapTokenDiscovery struct
+// How a joining node finds and trusts the seeder
+// CACertHashes: hashes of the public key of the cluster CA, `sha256:<hex>`.
+// UnsafeSkipCAVerification: trust the seeder without checking the CA hash.
+type BootstrapTokenDiscovery struct {
+	Token                    string   `yaml:"token"`
+	APIServerEndpoint        string   `yaml:"apiServerEndpoint"`
+	CACertHashes             []string `yaml:"caCertHashes,omitempty"`
+	UnsafeSkipCAVerification bool     `yaml:"unsafeSkipCAVerification,omitempty"`
+}
+
+// Discovery struct
+// Discovery of the cluster by a joining node
+type Discovery struct {
+	BootstrapToken *BootstrapTokenDiscovery `yaml:"bootstrapToken,omitempty"`
+}
+
+// JoinConfiguration struct
+// Configuration of `kubeadm join` for the joining nodes
+type JoinConfiguration struct {
+	TypeMeta         `yaml:",inline"`
+	NodeRegistration NodeRegistration `yaml:"nodeRegistration,omitempty"`
+	Discovery        Discovery        `yaml:"discovery"`
+}
+
+// Render returns the kubeadm configuration of the node: the
+// InitConfiguration and ClusterConfiguration documents for the seeder, the
+// JoinConfiguration one for the joining nodes
+func Render(cfg *config.KubicInitConfiguration, seeder bool) ([]byte, error) {
+	if cfg == nil {
+		return nil, errors.New("configuration is nil")
+	}
+
+	var docs []interface{}
+	if seeder {
+		cluster, err := Cluster(cfg)
+		if err != nil {
+			return nil, err
+		}
+		docs = []interface{}{Init(cfg), cluster}
+	} else {
+		join, err := Join(cfg)
+		if err != nil {
+			return nil, err
+		}
+		docs = []interface{}{join}
+	}
+
+	var content []byte
+	for _, doc := range docs {
+		b, err := yaml.Marshal(doc)
+		if err != nil {
+			return nil, err
+		}
+		content = append(content, "---\n"...)
+		content = append(content, b...)
+	}
+	return content, nil
+}
+
+// Init returns the InitConfiguration of the seeder
+func Init(cfg *config.KubicInitConfiguration) *InitConfiguration {
+	initCfg := &InitConfiguration{
+		TypeMeta:         TypeMeta{APIVersion: APIVersion, Kind: "InitConfiguration"},
+		NodeRegistration: nodeRegistration(cfg),
+		LocalAPIEndpoint: APIEndpoint{BindPort: config.DefaultAPIServerPort},
+	}
+	if ip := net.ParseIP(cfg.Network.Bind.Address); ip != nil && !ip.IsUnspecified() {
+		initCfg.LocalAPIEndpoint.AdvertiseAddress = ip.String()
+	}
+	if cfg.ClusterFormation.Token != "" {
+		initCfg.BootstrapTokens = []BootstrapToken{{
+			Token:  cfg.ClusterFormation.Token,
+			Usages: []string{"signing", "authentication"},
+			Groups: []string{"system:bootstrappers:kubeadm:default-node-token"},
+		}}
+	}
+	return initCfg
+}
+
+// Cluster returns the ClusterConfiguration of the cluster
+func Cluster(cfg *config.KubicInitConfiguration) (*ClusterConfiguration, error) {
+	cluster := &ClusterConfiguration{
+		TypeMeta: TypeMeta{APIVersion: APIVersion, Kind: "ClusterConfiguration"},
+		Networking: Networking{
+			ServiceSubnet: cfg.Network.ServiceSubnet,
+			PodSubnet:     cfg.Network.PodSubnet,
+			DNSDomain:     cfg.Network.DNS.Domain,
+		},
+		APIServer:       APIServer{ExtraArgs: oidc.Args(cfg.Auth.OIDC)},
+		CertificatesDir: cfg.Certificates.Directory,
+	}
+	sans, err := etcd.Compute(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if len(sans.Server) > 0 || len(sans.Peer) > 0 {
+		cluster.Etcd.Local = &LocalEtcd{
+			ServerCertSANs: sans.Server,
+			PeerCertSANs:   sans.Peer,
+		}
+	}
+	if fqdn := cfg.Network.DNS.ExternalFqdn; fqdn != "" {
+		cluster.ControlPlaneEndpoint = endpoint(fqdn)
+		cluster.APIServer.CertSANs = []string{fqdn}
+	}
+	return cluster, nil
+}
+
+// Join returns the JoinConfiguration of a joining node. The seeder, the
+// token and `certificates.caCrtHash` are needed: the CA of the seeder is only
+// trusted without verification when `certificates.unsafeSkipCAVerification`
+// is set, which is logged as a warning.
+func Join(cfg *config.KubicInitConfiguration) (*JoinConfiguration, error) {
+	if cfg.ClusterFormation.Seeder == "" {
+		return nil, errors.New("clusterFormation.seeder is needed to join a cluster")
+	}
+	if cfg.ClusterFormation.Token == "" {
+		return nil, errors.New("clusterFormation.token is needed to join a cluster")
+	}
+
+	discovery := &BootstrapTokenDiscovery{
+		Token:             cfg.ClusterFormation.Token,
+		APIServerEndpoint: endpoint(cfg.ClusterFormation.Seeder),
+	}
+	switch {
+	case cfg.Certificates.CaHash != "":
+		discovery.CACertHashes = []string{cfg.Certificates.CaHash}
+	case cfg.Certificates.UnsafeSkipCAVerification:
+		log.WithFields(log.Fields{"seeder": cfg.ClusterFormation.Seeder}).Warnf("certificates.unsafeSkipCAVerification is set, the CA of the seeder is not verified")
+		discovery.UnsafeSkipCAVerification = true
+	default:
+		return nil, errors.New("certificates.caCrtHash is needed to join a cluster, or certificates.unsafeSkipCAVerification to trust the CA of the seeder without verification")
+	}
+	return &JoinConfiguration{
+		TypeMeta:         TypeMeta{APIVersion: APIVersion, Kind: "JoinConfiguration"},
+		NodeRegistration: nodeRegistration(cfg),
+		Discovery:        Discovery{BootstrapToken: discovery},
+	}, nil
+}
+
+func nodeRegistration(cfg *config.KubicInitConfiguration) NodeRegistration {
+	return NodeRegistration{CRISocket: criSockets[cfg.Runtime.Engine]}
+}
+
+// endpoint adds the API server port to `host` when it has none
+func endpoint(host string) string {
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(host, strconv.Itoa(config.DefaultAPIServerPort))
+}
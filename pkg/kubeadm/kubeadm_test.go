@@ -0,0 +1,160 @@
+package kubeadm
+
+import (
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+func TestRender(t *testing.T) {
+	cluster := func() *config.KubicInitConfiguration {
+		return &config.KubicInitConfiguration{
+			Network: config.NetworkConfiguration{
+				Bind:          config.BindConfiguration{Address: "192.168.1.10"},
+				DNS:           config.DNSConfiguration{Domain: "cluster.local", ExternalFqdn: "k8s.example.com"},
+				PodSubnet:     "172.16.0.0/13",
+				ServiceSubnet: "172.24.0.0/16",
+			},
+			ClusterFormation: config.ClusterFormationConfiguration{Seeder: "seeder.example.com", Token: "94dcda.c271f4ff502789ca"},
+			Certificates:     config.CertsConfiguration{Directory: "/etc/kubernetes/pki"},
+			Etcd:             config.EtcdConfiguration{LocalEtcd: &config.LocalEtcdConfiguration{ServerCertSANs: []string{"etcd.example.com"}}},
+			Runtime:          config.RuntimeConfiguration{Engine: "crio"},
+			Auth: config.AuthConfiguration{OIDC: config.OIDCConfiguration{
+				Issuer:   "https://dex.example.com",
+				ClientID: "kubernetes",
+				Username: "email",
+			}},
+		}
+	}
+	minimal := &config.KubicInitConfiguration{
+		Network:          config.NetworkConfiguration{Bind: config.BindConfiguration{Address: "0.0.0.0"}, PodSubnet: "172.16.0.0/13"},
+		ClusterFormation: config.ClusterFormationConfiguration{Seeder: "10.0.0.1:8443", Token: "94dcda.c271f4ff502789ca"},
+		Certificates:     config.CertsConfiguration{UnsafeSkipCAVerification: true},
+		Runtime:          config.RuntimeConfiguration{Engine: "docker"},
+	}
+	withHash := cluster()
+	withHash.Certificates.CaHash = "sha256:0123456789abcdef"
+	unsafeSkip := cluster()
+	unsafeSkip.Certificates.UnsafeSkipCAVerification = true
+	noSeeder := cluster()
+	noSeeder.ClusterFormation.Seeder = ""
+	noToken := cluster()
+	noToken.ClusterFormation.Token = ""
+	invalidSAN := cluster()
+	invalidSAN.Etcd.LocalEtcd.PeerCertSANs = []string{"etcd_0"}
+
+	tests := []struct {
+		name    string
+		config  *config.KubicInitConfiguration
+		seeder  bool
+		want    string
+		wantErr bool
+	}{
+		{"seeder", cluster(), true, `---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: InitConfiguration
+bootstrapTokens:
+- token: 94dcda.c271f4ff502789ca
+  usages:
+  - signing
+  - authentication
+  groups:
+  - system:bootstrappers:kubeadm:default-node-token
+nodeRegistration:
+  criSocket: /var/run/crio/crio.sock
+localAPIEndpoint:
+  advertiseAddress: 192.168.1.10
+  bindPort: 6443
+---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: ClusterConfiguration
+etcd:
+  local:
+    serverCertSANs:
+    - etcd.example.com
+networking:
+  serviceSubnet: 172.24.0.0/16
+  podSubnet: 172.16.0.0/13
+  dnsDomain: cluster.local
+apiServer:
+  extraArgs:
+    oidc-client-id: kubernetes
+    oidc-issuer-url: https://dex.example.com
+    oidc-username-claim: email
+  certSANs:
+  - k8s.example.com
+controlPlaneEndpoint: k8s.example.com:6443
+certificatesDir: /etc/kubernetes/pki
+`, false},
+		{"seeder_minimal", minimal, true, `---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: InitConfiguration
+bootstrapTokens:
+- token: 94dcda.c271f4ff502789ca
+  usages:
+  - signing
+  - authentication
+  groups:
+  - system:bootstrappers:kubeadm:default-node-token
+nodeRegistration:
+  criSocket: /var/run/dockershim.sock
+localAPIEndpoint:
+  bindPort: 6443
+---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: ClusterConfiguration
+networking:
+  podSubnet: 172.16.0.0/13
+`, false},
+		{"join_unsafe_skip", unsafeSkip, false, `---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: JoinConfiguration
+nodeRegistration:
+  criSocket: /var/run/crio/crio.sock
+discovery:
+  bootstrapToken:
+    token: 94dcda.c271f4ff502789ca
+    apiServerEndpoint: seeder.example.com:6443
+    unsafeSkipCAVerification: true
+`, false},
+		{"join_ca_hash", withHash, false, `---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: JoinConfiguration
+nodeRegistration:
+  criSocket: /var/run/crio/crio.sock
+discovery:
+  bootstrapToken:
+    token: 94dcda.c271f4ff502789ca
+    apiServerEndpoint: seeder.example.com:6443
+    caCertHashes:
+    - sha256:0123456789abcdef
+`, false},
+		{"join_seeder_port", minimal, false, `---
+apiVersion: kubeadm.k8s.io/v1beta1
+kind: JoinConfiguration
+nodeRegistration:
+  criSocket: /var/run/dockershim.sock
+discovery:
+  bootstrapToken:
+    token: 94dcda.c271f4ff502789ca
+    apiServerEndpoint: 10.0.0.1:8443
+    unsafeSkipCAVerification: true
+`, false},
+		{"invalid_etcd_san", invalidSAN, true, "", true},
+		{"join_no_ca_hash", cluster(), false, "", true},
+		{"join_no_seeder", noSeeder, false, "", true},
+		{"join_no_token", noToken, false, "", true},
+		{"nil", nil, true, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.config, tt.seeder)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Render() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Render() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level of importance of a log message
+type Level int
+
+const (
+	// DebugLevel details useful when troubleshooting
+	DebugLevel Level = iota
+
+	// InfoLevel what caasp-init did
+	InfoLevel
+
+	// WarnLevel something unexpected that did not stop caasp-init
+	WarnLevel
+
+	// ErrorLevel something that stopped caasp-init
+	ErrorLevel
+)
+
+var levelNames = []string{"debug", "info", "warn", "error"}
+
+func (l Level) String() string {
+	if l < DebugLevel || l > ErrorLevel {
+		return "unknown"
+	}
+	return levelNames[l]
+}
+
+// ParseLevel returns the level named `name`
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return WarnLevel, nil
+	}
+	return InfoLevel, fmt.Errorf("unknown log level \"%s\", use one of %s", name, strings.Join(levelNames, ", "))
+}
+
+const (
+	// TextFormat writes `key=value` pairs
+	TextFormat = "text"
+
+	// JSONFormat writes a JSON object per line
+	JSONFormat = "json"
+)
+
+// Fields struct
+// Structured data attached to a log message
+type Fields map[string]interface{}
+
+var (
+	mu       sync.Mutex
+	out      io.Writer = os.Stderr
+	level              = InfoLevel
+	format             = TextFormat
+	warnings []string
+	secrets  []string
+
+	// now returns the time of the messages
+	now = time.Now
+)
+
+// SetLevel hides the messages below the level named `name`
+func SetLevel(name string) error {
+	l, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	level = l
+	mu.Unlock()
+	return nil
+}
+
+// SetFormat sets the format of the messages, `text` or `json`
+func SetFormat(name string) error {
+	switch name {
+	case TextFormat, JSONFormat:
+	default:
+		return fmt.Errorf("unknown log format \"%s\", use %s or %s", name, TextFormat, JSONFormat)
+	}
+	mu.Lock()
+	format = name
+	mu.Unlock()
+	return nil
+}
+
+// SetOutput sets where the messages are written, stderr by default
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	out = w
+	mu.Unlock()
+}
+
+// Warnings returns the warnings logged since the last ResetWarnings, even
+// the ones hidden by the level
+func Warnings() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	return append([]string{}, warnings...)
+}
+
+// ResetWarnings forgets the warnings logged so far
+func ResetWarnings() {
+	mu.Lock()
+	warnings = nil
+	mu.Unlock()
+}
+
+// AddSecret hides `value` in the messages and fields logged from now on,
+// and in the warnings returned by Warnings
+func AddSecret(value string) {
+	if value == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	for _, s := range secrets {
+		if s == value {
+			return
+		}
+	}
+	secrets = append(secrets, value)
+}
+
+// ResetSecrets forgets the secrets added so far
+func ResetSecrets() {
+	mu.Lock()
+	secrets = nil
+	mu.Unlock()
+}
+
+// Redact returns `s` with the secrets replaced by "(redacted)"
+func Redact(s string) string {
+	mu.Lock()
+	defer mu.Unlock()
+	return redact(s)
+}
+
+func redact(s string) string {
+	for _, secret := range secrets {
+		s = strings.Replace(s, secret, "(redacted)", -1)
+	}
+	return s
+}
+
+// Entry is a message being built with its fields
+type Entry struct {
+	fields Fields
+}
+
+// WithFields returns an entry logging `fields` along its message
+func WithFields(fields Fields) *Entry {
+	return &Entry{fields: fields}
+}
+
+// Debugf logs a message at DebugLevel
+func (e *Entry) Debugf(f string, args ...interface{}) { e.log(DebugLevel, f, args...) }
+
+// Infof logs a message at InfoLevel
+func (e *Entry) Infof(f string, args ...interface{}) { e.log(InfoLevel, f, args...) }
+
+// Warnf logs a message at WarnLevel
+func (e *Entry) Warnf(f string, args ...interface{}) { e.log(WarnLevel, f, args...) }
+
+// Errorf logs a message at ErrorLevel
+func (e *Entry) Errorf(f string, args ...interface{}) { e.log(ErrorLevel, f, args...) }
+
+// Debugf logs a message at DebugLevel
+func Debugf(f string, args ...interface{}) { WithFields(nil).log(DebugLevel, f, args...) }
+
+// Infof logs a message at InfoLevel
+func Infof(f string, args ...interface{}) { WithFields(nil).log(InfoLevel, f, args...) }
+
+// Warnf logs a message at WarnLevel
+func Warnf(f string, args ...interface{}) { WithFields(nil).log(WarnLevel, f, args...) }
+
+// Errorf logs a message at ErrorLevel
+func Errorf(f string, args ...interface{}) { WithFields(nil).log(ErrorLevel, f, args...) }
+
+func (e *Entry) log(l Level, f string, args ...interface{}) {
+	mu.Lock()
+	defer mu.Unlock()
+	msg := redact(fmt.Sprintf(f, args...))
+	if l == WarnLevel {
+		warnings = append(warnings, msg)
+	}
+	if l < level {
+		return
+	}
+
+	t := now().UTC().Format(time.RFC3339)
+	var line []byte
+	if format == JSONFormat {
+		record := map[string]interface{}{}
+		for k, v := range e.fields {
+			if s := fmt.Sprint(v); redact(s) != s {
+				v = redact(s)
+			}
+			record[k] = v
+		}
+		record["time"] = t
+		record["level"] = l.String()
+		record["msg"] = msg
+		b, err := json.Marshal(record)
+		if err != nil {
+			b, _ = json.Marshal(map[string]interface{}{"time": t, "level": l.String(), "msg": msg})
+		}
+		line = b
+	} else {
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "time=%s level=%s msg=%s", t, l, quote(msg))
+		keys := make([]string, 0, len(e.fields))
+		for k := range e.fields {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			fmt.Fprintf(&b, " %s=%s", k, quote(redact(fmt.Sprint(e.fields[k]))))
+		}
+		line = b.Bytes()
+	}
+	out.Write(append(line, '\n'))
+}
+
+// quote quotes the values with spaces, quotes or control characters
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " =\"\\") || strconv.Quote(s) != `"`+s+`"` {
+		return strconv.Quote(s)
+	}
+	return s
+}
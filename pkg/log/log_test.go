@@ -0,0 +1,155 @@
+package log
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestLog(t *testing.T) {
+	defer func() {
+		SetOutput(os.Stderr)
+		now = time.Now
+		level = InfoLevel
+		format = TextFormat
+	}()
+	now = func() time.Time { return time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name   string
+		level  string
+		format string
+		log    func()
+		want   string
+	}{
+		{"text", "info", "text", func() { Infof("detected %s docker", "upstream") },
+			"time=2026-10-19T10:00:00Z level=info msg=\"detected upstream docker\"\n"},
+		{"text_fields", "info", "text", func() {
+			WithFields(Fields{"path": "/etc/docker/daemon.json", "action": "created"}).Infof("file written")
+		}, "time=2026-10-19T10:00:00Z level=info msg=\"file written\" action=created path=/etc/docker/daemon.json\n"},
+		{"text_quoted_field", "debug", "text", func() {
+			WithFields(Fields{"error": "no such file"}).Debugf("skipped")
+		}, "time=2026-10-19T10:00:00Z level=debug msg=skipped error=\"no such file\"\n"},
+		{"hidden_level", "warn", "text", func() { Infof("file written") }, ""},
+		{"json", "debug", "json", func() {
+			WithFields(Fields{"path": "/etc/docker/daemon.json"}).Warnf("skipped")
+		}, `{"level":"warn","msg":"skipped","path":"/etc/docker/daemon.json","time":"2026-10-19T10:00:00Z"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			SetOutput(&out)
+			if err := SetLevel(tt.level); err != nil {
+				t.Fatalf("SetLevel() error = %v", err)
+			}
+			if err := SetFormat(tt.format); err != nil {
+				t.Fatalf("SetFormat() error = %v", err)
+			}
+			tt.log()
+			if out.String() != tt.want {
+				t.Errorf("log = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestParseLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Level
+		wantErr bool
+	}{
+		{"debug", DebugLevel, false},
+		{"INFO", InfoLevel, false},
+		{"warning", WarnLevel, false},
+		{"error", ErrorLevel, false},
+		{"trace", InfoLevel, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLevel(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetFormat(t *testing.T) {
+	defer SetFormat(TextFormat)
+	if err := SetFormat("xml"); err == nil {
+		t.Errorf("SetFormat() accepted an unknown format")
+	}
+}
+
+func TestWarnings(t *testing.T) {
+	defer func() {
+		SetOutput(os.Stderr)
+		level = InfoLevel
+	}()
+	SetOutput(&bytes.Buffer{})
+	level = ErrorLevel
+
+	ResetWarnings()
+	Infof("file written")
+	Warnf("skipping the mirrors of %s", "quay.io")
+	WithFields(Fields{"unit": "docker.service"}).Warnf("reload failed")
+	Errorf("unable to write")
+	got := Warnings()
+	if len(got) != 2 || got[0] != "skipping the mirrors of quay.io" || got[1] != "reload failed" {
+		t.Errorf("Warnings() = %q", got)
+	}
+	ResetWarnings()
+	if len(Warnings()) != 0 {
+		t.Errorf("ResetWarnings() did not forget the warnings")
+	}
+}
+
+func TestAddSecret(t *testing.T) {
+	defer func() {
+		SetOutput(os.Stderr)
+		now = time.Now
+		format = TextFormat
+		ResetSecrets()
+		ResetWarnings()
+	}()
+	now = func() time.Time { return time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC) }
+	token := "94dcda.c271f4ff502789ca"
+	AddSecret(token)
+	AddSecret("")
+
+	tests := []struct {
+		name   string
+		format string
+		want   string
+	}{
+		{"text", TextFormat, "time=2026-10-19T10:00:00Z level=warn msg=\"token (redacted) expires\" token=(redacted)\n"},
+		{"json", JSONFormat, `{"level":"warn","msg":"token (redacted) expires","time":"2026-10-19T10:00:00Z","token":"(redacted)"}` + "\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			SetOutput(&out)
+			format = tt.format
+			WithFields(Fields{"token": token}).Warnf("token %s expires", token)
+			if out.String() != tt.want {
+				t.Errorf("log = %q, want %q", out.String(), tt.want)
+			}
+		})
+	}
+
+	if got := Warnings(); len(got) != 2 || got[0] != "token (redacted) expires" {
+		t.Errorf("Warnings() = %q", got)
+	}
+	if got := Redact("TOKEN=" + token); got != "TOKEN=(redacted)" {
+		t.Errorf("Redact() = %q", got)
+	}
+	ResetSecrets()
+	if got := Redact(token); got != token {
+		t.Errorf("ResetSecrets() did not forget the secrets, Redact() = %q", got)
+	}
+}
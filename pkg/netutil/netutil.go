@@ -0,0 +1,274 @@
+package netutil
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+const (
+	// nodeMaskIPv4 is the size of the IPv4 pods subnet given to each node
+	nodeMaskIPv4 = 24
+
+	// nodeMaskIPv6 is the size of the IPv6 pods subnet given to each node
+	nodeMaskIPv6 = 64
+)
+
+// podNetworkInterfaces are the prefixes of the interfaces created by the CNI
+// drivers, whose addresses are in the pods subnet, and of the bridge of the
+// container runtime, docker0 being in the default pods subnet and not used by
+// the pods
+var podNetworkInterfaces = []string{"cni", "flannel", "cilium_", "lxc", "veth", "docker"}
+
+// Interface struct
+// Network interface of the node
+// Name: name of the interface.
+// Addrs: addresses of the interface, with the mask of their network.
+// Loopback: whether it is a loopback interface.
+type Interface struct {
+	Name     string
+	Addrs    []*net.IPNet
+	Loopback bool
+}
+
+// InterfaceLister lists the network interfaces of the node
+type InterfaceLister interface {
+	Interfaces() ([]Interface, error)
+}
+
+// SystemLister lists the network interfaces of the node from the kernel
+type SystemLister struct{}
+
+// Interfaces returns the network interfaces of the node
+func (SystemLister) Interfaces() ([]Interface, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	var result []Interface
+	for _, iface := range ifaces {
+		addrs, err := iface.Addrs()
+		if err != nil {
+			return nil, fmt.Errorf("unable to list the addresses of %s: %v", iface.Name, err)
+		}
+		i := Interface{Name: iface.Name, Loopback: iface.Flags&net.FlagLoopback != 0}
+		for _, addr := range addrs {
+			if ipNet, ok := addr.(*net.IPNet); ok {
+				i.Addrs = append(i.Addrs, ipNet)
+			}
+		}
+		result = append(result, i)
+	}
+	return result, nil
+}
+
+// StaticLister returns a fixed list of network interfaces
+type StaticLister []Interface
+
+// Interfaces returns the network interfaces of the list
+func (l StaticLister) Interfaces() ([]Interface, error) {
+	return l, nil
+}
+
+// ResolveBind resolves the bind address of the node and sets it in the
+// configuration, so every writer uses the same one. The primary address of
+// the family of the bind interface is used when no address, or an
+// unspecified one like 0.0.0.0, is set. A given address has to be on the
+// node, and on the interface when both are set. Nothing is done when neither
+// is set. With a proxy set, the bind address is added to the hosts reached
+// without proxy.
+func ResolveBind(cfg *config.KubicInitConfiguration, lister InterfaceLister) error {
+	bind := &cfg.Network.Bind
+	var ip net.IP
+	if bind.Address != "" {
+		ip = net.ParseIP(bind.Address)
+		if ip == nil {
+			return fmt.Errorf("invalid bind address \"%s\"", bind.Address)
+		}
+		if ip.IsUnspecified() {
+			ip = nil
+		}
+	}
+	if ip == nil && bind.Interface == "" {
+		return nil
+	}
+	ifaces, err := lister.Interfaces()
+	if err != nil {
+		return fmt.Errorf("unable to list the network interfaces: %v", err)
+	}
+
+	if ip != nil {
+		for _, iface := range ifaces {
+			if !hasAddress(iface, ip) {
+				continue
+			}
+			if bind.Interface != "" && bind.Interface != iface.Name {
+				return fmt.Errorf("bind address %s is on %s, not on the bind interface %s", ip, iface.Name, bind.Interface)
+			}
+			addNoProxy(cfg, ip)
+			return nil
+		}
+		return fmt.Errorf("bind address %s is not on any interface of the node", ip)
+	}
+
+	for _, iface := range ifaces {
+		if iface.Name != bind.Interface {
+			continue
+		}
+		ip = primaryAddress(iface, bind.Family == "ipv6")
+		if ip == nil {
+			return fmt.Errorf("bind interface %s has no %s address", iface.Name, family(bind.Family))
+		}
+		bind.Address = ip.String()
+		addNoProxy(cfg, ip)
+		return nil
+	}
+	var names []string
+	for _, iface := range ifaces {
+		names = append(names, iface.Name)
+	}
+	return fmt.Errorf("bind interface %s not found, the node has %s", bind.Interface, strings.Join(names, ", "))
+}
+
+// addNoProxy adds the bind address `ip` to `network.proxy.noProxy` when a
+// proxy is set, so that the node is reached without it
+func addNoProxy(cfg *config.KubicInitConfiguration, ip net.IP) {
+	proxy := &cfg.Network.Proxy
+	if proxy.HTTP == "" && proxy.HTTPS == "" {
+		return
+	}
+	for _, host := range strings.Split(proxy.NoProxy, ",") {
+		if strings.TrimSpace(host) == ip.String() {
+			return
+		}
+	}
+	if proxy.NoProxy != "" {
+		proxy.NoProxy += ","
+	}
+	proxy.NoProxy += ip.String()
+}
+
+func hasAddress(iface Interface, ip net.IP) bool {
+	for _, addr := range iface.Addrs {
+		if addr.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// primaryAddress returns the first global address of the family of the
+// interface
+func primaryAddress(iface Interface, ipv6 bool) net.IP {
+	for _, addr := range iface.Addrs {
+		if !addr.IP.IsGlobalUnicast() || (addr.IP.To4() == nil) != ipv6 {
+			continue
+		}
+		return addr.IP
+	}
+	return nil
+}
+
+func family(name string) string {
+	if name == "" {
+		return config.DefaultBindFamily
+	}
+	return name
+}
+
+// CheckSubnets checks the pods and services subnets against the networks of
+// the node and the expected number of nodes. It returns warnings for the
+// overlaps with the networks of the node and the subnets too small, and an
+// error when the bind address is in a subnet.
+func CheckSubnets(cfg *config.KubicInitConfiguration, lister InterfaceLister) ([]string, error) {
+	var warnings []string
+	subnets := map[string][]*net.IPNet{}
+	for _, name := range []string{"podSubnet", "serviceSubnet"} {
+		value := cfg.Network.PodSubnet
+		if name == "serviceSubnet" {
+			value = cfg.Network.ServiceSubnet
+		}
+		s, err := config.ParseSubnets(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		subnets[name] = s
+	}
+
+	if ip := net.ParseIP(cfg.Network.Bind.Address); ip != nil {
+		for _, name := range []string{"podSubnet", "serviceSubnet"} {
+			for _, s := range subnets[name] {
+				if s.Contains(ip) {
+					return nil, fmt.Errorf("bind address %s is in the %s %s", ip, name, s)
+				}
+			}
+		}
+	}
+
+	ifaces, err := lister.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the network interfaces: %v", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Loopback || podNetworkInterface(iface.Name) {
+			continue
+		}
+		label := iface.Name
+		if iface.Name == cfg.Network.Bind.Interface {
+			label += ", the bind interface"
+		}
+		for _, addr := range iface.Addrs {
+			if addr.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			network := &net.IPNet{IP: addr.IP.Mask(addr.Mask), Mask: addr.Mask}
+			for _, name := range []string{"podSubnet", "serviceSubnet"} {
+				for _, s := range subnets[name] {
+					if config.Overlap(s, network) {
+						warnings = append(warnings, fmt.Sprintf("%s %s overlaps the network %s of %s (%s)", name, s, network, addr.IP, label))
+					}
+				}
+			}
+		}
+	}
+
+	expected := cfg.Network.ExpectedNodes
+	if expected < 1 {
+		expected = 1
+	}
+	for _, s := range subnets["podSubnet"] {
+		ones, bits := s.Mask.Size()
+		nodeMask := nodeMaskIPv4
+		if bits == 8*net.IPv6len {
+			nodeMask = nodeMaskIPv6
+		}
+		if ones > nodeMask {
+			warnings = append(warnings, fmt.Sprintf("podSubnet %s is smaller than the /%d given to each node", s, nodeMask))
+			continue
+		}
+		if room := nodes(nodeMask - ones); room < expected {
+			warnings = append(warnings, fmt.Sprintf("podSubnet %s has room for %d nodes, %d expected", s, room, expected))
+		}
+	}
+	return warnings, nil
+}
+
+// nodes returns the number of node subnets in `bits` bits, capped to avoid
+// overflows
+func nodes(bits int) int {
+	if bits > 30 {
+		bits = 30
+	}
+	return 1 << uint(bits)
+}
+
+func podNetworkInterface(name string) bool {
+	for _, prefix := range podNetworkInterfaces {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package netutil
+
+import (
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+// failingLister fails to list the interfaces
+type failingLister struct{}
+
+func (failingLister) Interfaces() ([]Interface, error) {
+	return nil, errors.New("permission denied")
+}
+
+func addr(s string) *net.IPNet {
+	ip, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	n.IP = ip
+	return n
+}
+
+func TestCheckSubnets(t *testing.T) {
+	node := StaticLister{
+		{Name: "lo", Loopback: true, Addrs: []*net.IPNet{addr("127.0.0.1/8"), addr("::1/128")}},
+		{Name: "eth0", Addrs: []*net.IPNet{addr("192.168.1.10/24"), addr("fe80::1/64")}},
+		{Name: "eth1", Addrs: []*net.IPNet{addr("172.24.10.5/24")}},
+		{Name: "cni0", Addrs: []*net.IPNet{addr("172.16.0.1/24")}},
+	}
+	// a node running docker with its default bridge
+	dockerNode := StaticLister{
+		{Name: "eth0", Addrs: []*net.IPNet{addr("192.168.1.10/24")}},
+		{Name: "docker0", Addrs: []*net.IPNet{addr("172.17.0.1/16")}},
+	}
+	network := func(pods, services string, expected int, bind config.BindConfiguration) *config.KubicInitConfiguration {
+		return &config.KubicInitConfiguration{Network: config.NetworkConfiguration{
+			PodSubnet:     pods,
+			ServiceSubnet: services,
+			ExpectedNodes: expected,
+			Bind:          bind,
+		}}
+	}
+
+	tests := []struct {
+		name    string
+		config  *config.KubicInitConfiguration
+		lister  InterfaceLister
+		want    []string
+		wantErr bool
+	}{
+		{"ok", network("172.16.0.0/13", "10.96.0.0/12", 100, config.BindConfiguration{}), node, nil, false},
+		{"default_subnets_docker0", network(config.DefaultPodSubnet, config.DefaultServiceSubnet, 0, config.BindConfiguration{}), dockerNode, nil, false},
+		{"node_overlap", network("172.16.0.0/13", "172.24.0.0/16", 0, config.BindConfiguration{Interface: "eth1"}), node, []string{
+			"serviceSubnet 172.24.0.0/16 overlaps the network 172.24.10.0/24 of 172.24.10.5 (eth1, the bind interface)",
+		}, false},
+		{"too_small", network("172.16.0.0/25,fd00:10::/63", "10.96.0.0/12", 3, config.BindConfiguration{}), node, []string{
+			"podSubnet 172.16.0.0/25 is smaller than the /24 given to each node",
+			"podSubnet fd00:10::/63 has room for 2 nodes, 3 expected",
+		}, false},
+		{"large_ipv6", network("fd00::/8", "fd01::/112", 1000, config.BindConfiguration{}), node, nil, false},
+		{"bind_in_subnet", network("172.16.0.0/13", "10.96.0.0/12", 0, config.BindConfiguration{Address: "172.17.0.4"}), node, nil, true},
+		{"invalid_subnet", network("172.16.0.0", "10.96.0.0/12", 0, config.BindConfiguration{}), node, nil, true},
+		{"lister_error", network("172.16.0.0/13", "10.96.0.0/12", 0, config.BindConfiguration{}), failingLister{}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CheckSubnets(tt.config, tt.lister)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CheckSubnets() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("CheckSubnets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveBind(t *testing.T) {
+	node := StaticLister{
+		{Name: "lo", Loopback: true, Addrs: []*net.IPNet{addr("127.0.0.1/8"), addr("::1/128")}},
+		{Name: "eth0", Addrs: []*net.IPNet{addr("fe80::1/64"), addr("192.168.1.10/24"), addr("2001:db8::10/64")}},
+		{Name: "eth1", Addrs: []*net.IPNet{addr("fe80::2/64")}},
+	}
+
+	tests := []struct {
+		name    string
+		bind    config.BindConfiguration
+		lister  InterfaceLister
+		want    string
+		wantErr bool
+	}{
+		{"none", config.BindConfiguration{}, failingLister{}, "", false},
+		{"unspecified", config.BindConfiguration{Address: "0.0.0.0"}, failingLister{}, "0.0.0.0", false},
+		{"address", config.BindConfiguration{Address: "192.168.1.10"}, node, "192.168.1.10", false},
+		{"address_on_interface", config.BindConfiguration{Address: "2001:db8::10", Interface: "eth0"}, node, "2001:db8::10", false},
+		{"address_not_on_node", config.BindConfiguration{Address: "192.168.1.11"}, node, "", true},
+		{"address_on_other_interface", config.BindConfiguration{Address: "192.168.1.10", Interface: "eth1"}, node, "", true},
+		{"interface_ipv4", config.BindConfiguration{Interface: "eth0", Family: "ipv4"}, node, "192.168.1.10", false},
+		{"interface_ipv6", config.BindConfiguration{Interface: "eth0", Family: "ipv6"}, node, "2001:db8::10", false},
+		{"interface_unspecified_address", config.BindConfiguration{Address: "0.0.0.0", Interface: "eth0"}, node, "192.168.1.10", false},
+		{"interface_link_local_only", config.BindConfiguration{Interface: "eth1", Family: "ipv6"}, node, "", true},
+		{"interface_not_found", config.BindConfiguration{Interface: "eth2"}, node, "", true},
+		{"lister_error", config.BindConfiguration{Interface: "eth0"}, failingLister{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.KubicInitConfiguration{Network: config.NetworkConfiguration{Bind: tt.bind}}
+			err := ResolveBind(cfg, tt.lister)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResolveBind() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && cfg.Network.Bind.Address != tt.want {
+				t.Errorf("ResolveBind() address = %q, want %q", cfg.Network.Bind.Address, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveBindNoProxy(t *testing.T) {
+	node := StaticLister{{Name: "eth0", Addrs: []*net.IPNet{addr("192.168.1.10/24")}}}
+
+	tests := []struct {
+		name  string
+		bind  config.BindConfiguration
+		proxy config.ProxyConfiguration
+		want  string
+	}{
+		{"no_proxy", config.BindConfiguration{Interface: "eth0"}, config.ProxyConfiguration{NoProxy: "example.com"}, "example.com"},
+		{"resolved", config.BindConfiguration{Interface: "eth0"}, config.ProxyConfiguration{HTTP: "proxy:3128", NoProxy: "example.com"}, "example.com,192.168.1.10"},
+		{"given", config.BindConfiguration{Address: "192.168.1.10"}, config.ProxyConfiguration{HTTPS: "proxy:3128"}, "192.168.1.10"},
+		{"listed", config.BindConfiguration{Address: "192.168.1.10"}, config.ProxyConfiguration{HTTP: "proxy:3128", NoProxy: "192.168.1.10, example.com"}, "192.168.1.10, example.com"},
+		{"unspecified", config.BindConfiguration{Address: "0.0.0.0"}, config.ProxyConfiguration{HTTP: "proxy:3128"}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.KubicInitConfiguration{Network: config.NetworkConfiguration{Bind: tt.bind, Proxy: tt.proxy}}
+			if err := ResolveBind(cfg, node); err != nil {
+				t.Fatalf("ResolveBind() error = %v", err)
+			}
+			if got := cfg.Network.Proxy.NoProxy; got != tt.want {
+				t.Errorf("ResolveBind() noProxy = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSystemLister(t *testing.T) {
+	ifaces, err := SystemLister{}.Interfaces()
+	if err != nil {
+		t.Fatalf("Interfaces() error = %v", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Name == "" {
+			t.Errorf("Interfaces() returned an interface without name: %+v", iface)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package notify
+
+import (
+	"fmt"
+
+	"github.com/coreos/go-systemd/daemon"
+
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+// Ready tells systemd the service finished starting, along with its status.
+// Nothing is sent when caasp-init does not run in a `Type=notify` unit.
+func Ready(status string) {
+	send(fmt.Sprintf("%s\nSTATUS=%s", daemon.SdNotifyReady, status))
+}
+
+// Status tells systemd what the service is doing
+func Status(format string, args ...interface{}) {
+	send("STATUS=" + fmt.Sprintf(format, args...))
+}
+
+// Reloading tells systemd the service is applying a new configuration,
+// until the next Ready
+func Reloading(status string) {
+	send(fmt.Sprintf("%s\nSTATUS=%s", daemon.SdNotifyReloading, status))
+}
+
+// Stopping tells systemd the service is shutting down
+func Stopping() {
+	send(daemon.SdNotifyStopping)
+}
+
+func send(state string) {
+	sent, err := daemon.SdNotify(false, state)
+	if err != nil {
+		log.Warnf("unable to notify systemd: %v", err)
+		return
+	}
+	if sent {
+		log.Debugf("notified systemd: %q", state)
+	}
+}
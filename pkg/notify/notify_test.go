@@ -0,0 +1,59 @@
+package notify
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-notify")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	socket := filepath.Join(tmpDir, "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatalf("listening on %s: %s", socket, err)
+	}
+	defer conn.Close()
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	os.Setenv("NOTIFY_SOCKET", socket)
+
+	tests := []struct {
+		name   string
+		notify func()
+		want   string
+	}{
+		{"ready", func() { Ready("watching /etc/kubic/kubic-init.yaml") }, "READY=1\nSTATUS=watching /etc/kubic/kubic-init.yaml"},
+		{"status", func() { Status("applied %d files", 3) }, "STATUS=applied 3 files"},
+		{"reloading", func() { Reloading("applying the new configuration") }, "RELOADING=1\nSTATUS=applying the new configuration"},
+		{"stopping", Stopping, "STOPPING=1"},
+	}
+	buf := make([]byte, 1024)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.notify()
+			conn.SetReadDeadline(time.Now().Add(time.Second))
+			n, err := conn.Read(buf)
+			if err != nil {
+				t.Fatalf("reading the notification: %s", err)
+			}
+			if got := string(buf[:n]); got != tt.want {
+				t.Errorf("notification = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifyWithoutSocket(t *testing.T) {
+	os.Unsetenv("NOTIFY_SOCKET")
+	// nothing is sent and nothing fails
+	Ready("ready")
+	Stopping()
+}
@@ -0,0 +1,134 @@
+package oidc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+// discoveryPath is the path of the OpenID provider metadata under the issuer
+const discoveryPath = "/.well-known/openid-configuration"
+
+// discoveryTimeout is the time given to the issuer to answer
+var discoveryTimeout = 10 * time.Second
+
+// Enabled tells whether OpenID Connect is configured
+func Enabled(oidc config.OIDCConfiguration) bool {
+	return oidc.Issuer != ""
+}
+
+// Args returns the kube-apiserver flags of the OpenID Connect settings,
+// without the leading dashes, nil when no issuer is set
+func Args(oidc config.OIDCConfiguration) map[string]string {
+	if !Enabled(oidc) {
+		return nil
+	}
+	args := map[string]string{"oidc-issuer-url": oidc.Issuer}
+	for flag, value := range map[string]string{
+		"oidc-client-id":      oidc.ClientID,
+		"oidc-ca-file":        oidc.CA,
+		"oidc-username-claim": oidc.Username,
+		"oidc-groups-claim":   oidc.Groups,
+	} {
+		if value != "" {
+			args[flag] = value
+		}
+	}
+	return args
+}
+
+// Flags returns the kube-apiserver flags of the OpenID Connect settings,
+// sorted by name
+func Flags(oidc config.OIDCConfiguration) []string {
+	var flags []string
+	for name, value := range Args(oidc) {
+		flags = append(flags, fmt.Sprintf("--%s=%s", name, value))
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// CheckCA checks the CA file of the OpenID Connect settings exists and
+// contains PEM encoded certificates. Nothing is checked when no CA is set.
+func CheckCA(oidc config.OIDCConfiguration) error {
+	_, err := certPool(oidc)
+	return err
+}
+
+// Metadata struct
+// OpenID provider metadata published by the issuer
+// Issuer: URL of the issuer, it has to be the configured one.
+// JwksURI: URL of the keys signing the tokens.
+type Metadata struct {
+	Issuer  string `json:"issuer"`
+	JwksURI string `json:"jwks_uri"`
+}
+
+// Discover fetches the OpenID provider metadata of the issuer, trusting the
+// configured CA, and checks it matches the configuration
+func Discover(oidc config.OIDCConfiguration) (*Metadata, error) {
+	if !Enabled(oidc) {
+		return nil, errors.New("auth.OIDC.issuer is not set")
+	}
+	pool, err := certPool(oidc)
+	if err != nil {
+		return nil, err
+	}
+	client := &http.Client{
+		Timeout:   discoveryTimeout,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}},
+	}
+
+	url := strings.TrimSuffix(oidc.Issuer, "/") + discoveryPath
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to reach the OpenID issuer: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s answered %s", url, resp.Status)
+	}
+
+	metadata := &Metadata{}
+	if err := json.NewDecoder(resp.Body).Decode(metadata); err != nil {
+		return nil, fmt.Errorf("unable to decode %s: %v", url, err)
+	}
+	if metadata.Issuer != oidc.Issuer {
+		return nil, fmt.Errorf("issuer \"%s\" published in %s is not the configured one", metadata.Issuer, url)
+	}
+	if metadata.JwksURI == "" {
+		return nil, fmt.Errorf("%s has no jwks_uri", url)
+	}
+	return metadata, nil
+}
+
+// certPool returns the certificates of the CA file, nil to use the system
+// ones when no CA is set
+func certPool(oidc config.OIDCConfiguration) (*x509.CertPool, error) {
+	if oidc.CA == "" {
+		return nil, nil
+	}
+	data, err := ioutil.ReadFile(oidc.CA)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the OpenID CA: %v", err)
+	}
+	cas, err := certs.ParseCertificates(data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid OpenID CA %s: %v", oidc.CA, err)
+	}
+	pool := x509.NewCertPool()
+	for _, ca := range cas {
+		pool.AddCert(ca)
+	}
+	return pool, nil
+}
@@ -0,0 +1,154 @@
+package oidc
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+func TestFlags(t *testing.T) {
+	tests := []struct {
+		name string
+		oidc config.OIDCConfiguration
+		want []string
+	}{
+		{"disabled", config.OIDCConfiguration{ClientID: "kubernetes"}, nil},
+		{"full", config.OIDCConfiguration{
+			Issuer:   "https://dex.example.com",
+			ClientID: "kubernetes",
+			CA:       "/etc/kubernetes/pki/dex.crt",
+			Username: "email",
+			Groups:   "groups",
+		}, []string{
+			"--oidc-ca-file=/etc/kubernetes/pki/dex.crt",
+			"--oidc-client-id=kubernetes",
+			"--oidc-groups-claim=groups",
+			"--oidc-issuer-url=https://dex.example.com",
+			"--oidc-username-claim=email",
+		}},
+		{"no_ca", config.OIDCConfiguration{Issuer: "https://dex.example.com", ClientID: "kubernetes"}, []string{
+			"--oidc-client-id=kubernetes",
+			"--oidc-issuer-url=https://dex.example.com",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Flags(tt.oidc); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Flags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-oidc")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// the metadata of each issuer, %s is replaced by its URL
+	metadata := map[string]string{
+		"/dex":     `{"issuer": "%s", "jwks_uri": "https://dex.example.com/keys"}`,
+		"/other":   `{"issuer": "https://dex.example.com", "jwks_uri": "https://dex.example.com/keys"}`,
+		"/nokeys":  `{"issuer": "%s"}`,
+		"/garbage": `<html>%s</html>`,
+	}
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimSuffix(r.URL.Path, discoveryPath)
+		body, ok := metadata[path]
+		if !ok || path == r.URL.Path {
+			http.NotFound(w, r)
+			return
+		}
+		if strings.Contains(body, "%s") {
+			body = fmt.Sprintf(body, "https://"+r.Host+path)
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	ca := filepath.Join(tmpDir, "ca.crt")
+	if err := ioutil.WriteFile(ca, certs.EncodeCertificate(srv.Certificate()), 0644); err != nil {
+		t.Fatalf("writing %s: %s", ca, err)
+	}
+	invalid := filepath.Join(tmpDir, "invalid.crt")
+	if err := ioutil.WriteFile(invalid, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("writing %s: %s", invalid, err)
+	}
+
+	tests := []struct {
+		name    string
+		oidc    config.OIDCConfiguration
+		wantErr bool
+	}{
+		{"ok", config.OIDCConfiguration{Issuer: srv.URL + "/dex", CA: ca}, false},
+		{"trailing_slash", config.OIDCConfiguration{Issuer: srv.URL + "/dex/", CA: ca}, true},
+		{"other_issuer", config.OIDCConfiguration{Issuer: srv.URL + "/other", CA: ca}, true},
+		{"no_keys", config.OIDCConfiguration{Issuer: srv.URL + "/nokeys", CA: ca}, true},
+		{"garbage", config.OIDCConfiguration{Issuer: srv.URL + "/garbage", CA: ca}, true},
+		{"not_found", config.OIDCConfiguration{Issuer: srv.URL + "/missing", CA: ca}, true},
+		{"untrusted", config.OIDCConfiguration{Issuer: srv.URL + "/dex"}, true},
+		{"invalid_ca", config.OIDCConfiguration{Issuer: srv.URL + "/dex", CA: invalid}, true},
+		{"missing_ca", config.OIDCConfiguration{Issuer: srv.URL + "/dex", CA: filepath.Join(tmpDir, "missing.crt")}, true},
+		{"disabled", config.OIDCConfiguration{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Discover(tt.oidc)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Discover() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.JwksURI != "https://dex.example.com/keys" {
+				t.Errorf("Discover() jwks_uri = %q", got.JwksURI)
+			}
+		})
+	}
+}
+
+func TestCheckCA(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-oidc")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	srv := httptest.NewTLSServer(http.NotFoundHandler())
+	srv.Close()
+	ca := filepath.Join(tmpDir, "ca.crt")
+	if err := ioutil.WriteFile(ca, certs.EncodeCertificate(srv.Certificate()), 0644); err != nil {
+		t.Fatalf("writing %s: %s", ca, err)
+	}
+	invalid := filepath.Join(tmpDir, "invalid.crt")
+	if err := ioutil.WriteFile(invalid, []byte("not a certificate"), 0644); err != nil {
+		t.Fatalf("writing %s: %s", invalid, err)
+	}
+
+	tests := []struct {
+		name    string
+		ca      string
+		wantErr bool
+	}{
+		{"no_ca", "", false},
+		{"ca", ca, false},
+		{"invalid", invalid, true},
+		{"missing", filepath.Join(tmpDir, "missing.crt"), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			oidc := config.OIDCConfiguration{Issuer: "https://dex.example.com", CA: tt.ca}
+			if err := CheckCA(oidc); (err != nil) != tt.wantErr {
+				t.Errorf("CheckCA() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
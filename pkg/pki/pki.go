@@ -0,0 +1,259 @@
+package pki
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+)
+
+const (
+	// KeyTypeRSA is the RSA key type, sized in bits
+	KeyTypeRSA = "rsa"
+	// KeyTypeECDSA is the ECDSA key type, sized by the bits of its curve
+	KeyTypeECDSA = "ecdsa"
+
+	// DefaultValidity is the validity of the generated CAs, ten years as
+	// kubeadm does
+	DefaultValidity = 10 * 365 * 24 * time.Hour
+)
+
+// now returns the current time, the start of the validity of the CAs
+var now = time.Now
+
+// curves are the ECDSA curves by size
+var curves = map[int]elliptic.Curve{
+	256: elliptic.P256(),
+	384: elliptic.P384(),
+	521: elliptic.P521(),
+}
+
+// Options struct
+// Generation settings of the cluster PKI
+// KeyType: type of the keys, `rsa` or `ecdsa`.
+// KeySize: bits of the RSA keys, 2048 to 4096, or of the ECDSA curve, 256,
+// 384 or 521. The default is 2048 for RSA and 256 for ECDSA.
+// Validity: validity of the CA certificates.
+// Force: overwrite the existing files.
+type Options struct {
+	KeyType  string
+	KeySize  int
+	Validity time.Duration
+	Force    bool
+}
+
+// CA struct
+// Certificate authority of the cluster PKI, in the kubeadm layout
+// Name: common name of the certificate.
+// Cert, Key: paths of the certificate and the key, relative to the
+// certificates directory.
+type CA struct {
+	Name string
+	Cert string
+	Key  string
+}
+
+var (
+	// CAs are the certificate authorities generated for a cluster
+	CAs = []CA{
+		{Name: "kubernetes", Cert: "ca.crt", Key: "ca.key"},
+		{Name: "front-proxy-ca", Cert: "front-proxy-ca.crt", Key: "front-proxy-ca.key"},
+		{Name: "etcd-ca", Cert: filepath.Join("etcd", "ca.crt"), Key: filepath.Join("etcd", "ca.key")},
+	}
+
+	// ServiceAccountKey and ServiceAccountPub are the paths of the key
+	// pair signing the service account tokens, relative to the
+	// certificates directory
+	ServiceAccountKey = "sa.key"
+	ServiceAccountPub = "sa.pub"
+)
+
+// Files returns the paths of all the files of the cluster PKI in `dir`
+func Files(dir string) []string {
+	var files []string
+	for _, ca := range CAs {
+		files = append(files, filepath.Join(dir, ca.Cert), filepath.Join(dir, ca.Key))
+	}
+	return append(files, filepath.Join(dir, ServiceAccountKey), filepath.Join(dir, ServiceAccountPub))
+}
+
+// Validate checks the options and sets the default key size and validity
+func (o *Options) Validate() error {
+	switch strings.ToLower(o.KeyType) {
+	case "", KeyTypeRSA:
+		o.KeyType = KeyTypeRSA
+		if o.KeySize == 0 {
+			o.KeySize = 2048
+		}
+		if o.KeySize < 2048 || o.KeySize > 4096 {
+			return fmt.Errorf("invalid RSA key size %d, use 2048 to 4096 bits", o.KeySize)
+		}
+	case KeyTypeECDSA:
+		o.KeyType = KeyTypeECDSA
+		if o.KeySize == 0 {
+			o.KeySize = 256
+		}
+		if _, ok := curves[o.KeySize]; !ok {
+			return fmt.Errorf("invalid ECDSA key size %d, use 256, 384 or 521", o.KeySize)
+		}
+	default:
+		return fmt.Errorf("unknown key type \"%s\", use rsa or ecdsa", o.KeyType)
+	}
+	if o.Validity == 0 {
+		o.Validity = DefaultValidity
+	}
+	if o.Validity < 0 {
+		return fmt.Errorf("invalid validity %s", o.Validity)
+	}
+	return nil
+}
+
+// Init generates the cluster CA, the front proxy CA, the etcd CA and the
+// service account key pair in `dir`. Nothing is written when one of the
+// files already exists, unless `Force` is set. The paths of the files
+// written are returned.
+func Init(dir string, opts Options) ([]string, error) {
+	if err := opts.Validate(); err != nil {
+		return nil, err
+	}
+	if !opts.Force {
+		var existing []string
+		for _, path := range Files(dir) {
+			if _, err := os.Lstat(path); err == nil {
+				existing = append(existing, path)
+			} else if !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+		if len(existing) > 0 {
+			return nil, fmt.Errorf("refusing to overwrite %s, use --force to replace them", strings.Join(existing, ", "))
+		}
+	}
+
+	// everything is generated before writing, so that a failure leaves no
+	// partial PKI
+	var files []file
+	for _, ca := range CAs {
+		cert, key, err := newCA(ca.Name, opts)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, file{ca.Cert, cert, 0644}, file{ca.Key, key, 0600})
+	}
+	key, pub, err := newKeyPair(opts)
+	if err != nil {
+		return nil, err
+	}
+	files = append(files, file{ServiceAccountKey, key, 0600}, file{ServiceAccountPub, pub, 0644})
+
+	var written []string
+	for _, f := range files {
+		path := filepath.Join(dir, f.name)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return written, err
+		}
+		if _, err := fsutil.WriteFile(path, f.data, f.perm); err != nil {
+			return written, err
+		}
+		written = append(written, path)
+	}
+	return written, nil
+}
+
+// file is a file of the PKI, relative to the certificates directory
+type file struct {
+	name string
+	data []byte
+	perm os.FileMode
+}
+
+// newCA returns the PEM encoded self-signed certificate and key of a CA
+func newCA(name string, opts Options) ([]byte, []byte, error) {
+	key, err := newKey(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to generate a serial number: %v", err)
+	}
+	start := now().UTC()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             start,
+		NotAfter:              start.Add(opts.Validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment | x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, key.Public(), key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to create the %s certificate: %v", name, err)
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), keyPEM, nil
+}
+
+// newKeyPair returns the PEM encoded private and public keys of a key pair
+func newKeyPair(opts Options) ([]byte, []byte, error) {
+	key, err := newKey(opts)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := encodeKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	der, err := x509.MarshalPKIXPublicKey(key.Public())
+	if err != nil {
+		return nil, nil, err
+	}
+	return keyPEM, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}), nil
+}
+
+// newKey generates a private key of the type and size of the options
+func newKey(opts Options) (crypto.Signer, error) {
+	var key crypto.Signer
+	var err error
+	if opts.KeyType == KeyTypeECDSA {
+		key, err = ecdsa.GenerateKey(curves[opts.KeySize], rand.Reader)
+	} else {
+		key, err = rsa.GenerateKey(rand.Reader, opts.KeySize)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to generate a %s key: %v", opts.KeyType, err)
+	}
+	return key, nil
+}
+
+// encodeKey returns the PEM encoding of the private key, in the formats
+// kubeadm writes
+func encodeKey(key crypto.Signer) ([]byte, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)}), nil
+	case *ecdsa.PrivateKey:
+		der, err := x509.MarshalECPrivateKey(k)
+		if err != nil {
+			return nil, err
+		}
+		return pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: der}), nil
+	}
+	return nil, fmt.Errorf("unsupported key %T", key)
+}
@@ -0,0 +1,175 @@
+package pki
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     Options
+		wantType string
+		wantSize int
+		wantErr  bool
+	}{
+		{"defaults", Options{}, KeyTypeRSA, 2048, false},
+		{"rsa_4096", Options{KeyType: "RSA", KeySize: 4096}, KeyTypeRSA, 4096, false},
+		{"ecdsa_default", Options{KeyType: "ecdsa"}, KeyTypeECDSA, 256, false},
+		{"ecdsa_384", Options{KeyType: "ecdsa", KeySize: 384}, KeyTypeECDSA, 384, false},
+		{"rsa_too_small", Options{KeyType: "rsa", KeySize: 1024}, "", 0, true},
+		{"ecdsa_unknown_curve", Options{KeyType: "ecdsa", KeySize: 2048}, "", 0, true},
+		{"unknown_type", Options{KeyType: "dsa"}, "", 0, true},
+		{"negative_validity", Options{Validity: -time.Hour}, "", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := tt.opts
+			err := opts.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if opts.KeyType != tt.wantType || opts.KeySize != tt.wantSize {
+				t.Errorf("Validate() = %s %d, want %s %d", opts.KeyType, opts.KeySize, tt.wantType, tt.wantSize)
+			}
+			if tt.opts.Validity == 0 && opts.Validity != DefaultValidity {
+				t.Errorf("Validate() validity = %s, want %s", opts.Validity, DefaultValidity)
+			}
+		})
+	}
+}
+
+func TestInit(t *testing.T) {
+	defer func(n func() time.Time) { now = n }(now)
+	start := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	now = func() time.Time { return start }
+
+	tmpDir, err := ioutil.TempDir("", "caasp-init-pki")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	for _, opts := range []Options{
+		{KeyType: KeyTypeECDSA, Validity: 24 * time.Hour},
+		{KeyType: KeyTypeRSA, KeySize: 2048, Validity: 24 * time.Hour},
+	} {
+		t.Run(opts.KeyType, func(t *testing.T) {
+			dir := filepath.Join(tmpDir, opts.KeyType, "pki")
+			written, err := Init(dir, opts)
+			if err != nil {
+				t.Fatalf("Init() error = %v", err)
+			}
+			if len(written) != len(Files(dir)) {
+				t.Errorf("Init() wrote %d files, want %d", len(written), len(Files(dir)))
+			}
+
+			for _, ca := range CAs {
+				cert := readPEM(t, filepath.Join(dir, ca.Cert), 0644, "CERTIFICATE")
+				parsed, err := x509.ParseCertificate(cert)
+				if err != nil {
+					t.Fatalf("parsing %s: %s", ca.Cert, err)
+				}
+				if !parsed.IsCA || parsed.Subject.CommonName != ca.Name || parsed.KeyUsage&x509.KeyUsageCertSign == 0 {
+					t.Errorf("%s is not a CA named %s", ca.Cert, ca.Name)
+				}
+				if !parsed.NotBefore.Equal(start) || !parsed.NotAfter.Equal(start.Add(24*time.Hour)) {
+					t.Errorf("%s is valid from %s to %s", ca.Cert, parsed.NotBefore, parsed.NotAfter)
+				}
+				if err := parsed.CheckSignatureFrom(parsed); err != nil {
+					t.Errorf("%s is not self-signed: %s", ca.Cert, err)
+				}
+				if opts.KeyType == KeyTypeECDSA {
+					key, err := x509.ParseECPrivateKey(readPEM(t, filepath.Join(dir, ca.Key), 0600, "EC PRIVATE KEY"))
+					if err != nil || !key.PublicKey.Equal(parsed.PublicKey) {
+						t.Errorf("%s is not the key of %s: %v", ca.Key, ca.Cert, err)
+					}
+				} else {
+					key, err := x509.ParsePKCS1PrivateKey(readPEM(t, filepath.Join(dir, ca.Key), 0600, "RSA PRIVATE KEY"))
+					if err != nil || !key.PublicKey.Equal(parsed.PublicKey) {
+						t.Errorf("%s is not the key of %s: %v", ca.Key, ca.Cert, err)
+					}
+				}
+			}
+
+			pub, err := x509.ParsePKIXPublicKey(readPEM(t, filepath.Join(dir, ServiceAccountPub), 0644, "PUBLIC KEY"))
+			if err != nil {
+				t.Fatalf("parsing %s: %s", ServiceAccountPub, err)
+			}
+			switch pub.(type) {
+			case *ecdsa.PublicKey:
+				readPEM(t, filepath.Join(dir, ServiceAccountKey), 0600, "EC PRIVATE KEY")
+			case *rsa.PublicKey:
+				readPEM(t, filepath.Join(dir, ServiceAccountKey), 0600, "RSA PRIVATE KEY")
+			}
+
+			before, _ := ioutil.ReadFile(filepath.Join(dir, "ca.key"))
+			if _, err := Init(dir, opts); err == nil {
+				t.Errorf("Init() should refuse to overwrite the PKI")
+			}
+			after, _ := ioutil.ReadFile(filepath.Join(dir, "ca.key"))
+			if string(before) != string(after) {
+				t.Errorf("Init() overwrote ca.key without force")
+			}
+
+			opts.Force = true
+			if _, err := Init(dir, opts); err != nil {
+				t.Fatalf("Init() with force error = %v", err)
+			}
+			after, _ = ioutil.ReadFile(filepath.Join(dir, "ca.key"))
+			if string(before) == string(after) {
+				t.Errorf("Init() with force did not replace ca.key")
+			}
+		})
+	}
+
+	t.Run("partial", func(t *testing.T) {
+		dir := filepath.Join(tmpDir, "partial")
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			t.Fatalf("creating %s: %s", dir, err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, ServiceAccountKey), []byte("key"), 0600); err != nil {
+			t.Fatalf("writing %s: %s", ServiceAccountKey, err)
+		}
+		if _, err := Init(dir, Options{KeyType: KeyTypeECDSA}); err == nil {
+			t.Errorf("Init() should refuse to overwrite %s", ServiceAccountKey)
+		}
+		if _, err := os.Stat(filepath.Join(dir, "ca.crt")); !os.IsNotExist(err) {
+			t.Errorf("Init() wrote ca.crt while refusing to overwrite %s", ServiceAccountKey)
+		}
+	})
+
+	t.Run("invalid_options", func(t *testing.T) {
+		if _, err := Init(filepath.Join(tmpDir, "invalid"), Options{KeyType: "dsa"}); err == nil {
+			t.Errorf("Init() should fail with an unknown key type")
+		}
+	})
+}
+
+// readPEM checks the file has the permissions `perm` and returns the content
+// of its PEM block of type `blockType`
+func readPEM(t *testing.T, path string, perm os.FileMode, blockType string) []byte {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("reading %s: %s", path, err)
+	}
+	if info.Mode().Perm() != perm {
+		t.Errorf("%s has mode %s, want %s", path, info.Mode().Perm(), perm)
+	}
+	data, _ := ioutil.ReadFile(path)
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != blockType {
+		t.Fatalf("%s is not a PEM encoded %s", path, blockType)
+	}
+	return block.Bytes
+}
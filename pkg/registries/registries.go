@@ -0,0 +1,150 @@
+package registries
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/reload"
+)
+
+const (
+	registriesTemplate = `# Generated by caasp-init from the kubic-init configuration, do not edit.
+{{if .Role}}# Role of the node: {{.Role}}
+{{end}}{{range .Registries}}
+[[registry]]
+prefix = {{printf "%q" .Prefix}}
+location = {{printf "%q" .Prefix}}
+{{range .Mirrors}}
+[[registry.mirror]]
+location = {{printf "%q" .Location}}{{if .Insecure}}
+insecure = true{{end}}
+{{end}}{{end}}`
+)
+
+// Unit is the systemd unit of CRI-O
+const Unit = "crio.service"
+
+var (
+	registriesFile = "/etc/containers/registries.conf.d/50-caasp-init.conf"
+)
+
+// templateData is what the registries template renders
+// Role: role of the node, seeder or join.
+type templateData struct {
+	Role       string
+	Registries []registry
+}
+
+type registry struct {
+	Prefix  string
+	Mirrors []mirror
+}
+
+type mirror struct {
+	Location string
+	Insecure bool
+}
+
+// WriteConfigFile writes the containers registries configuration drop-in
+// with the mirrors of each registry, only when the runtime engine is CRI-O
+func WriteConfigFile(config *config.KubicInitConfiguration) error {
+	if config == nil {
+		return errors.New("configuration is nil")
+	}
+	if config.Runtime.Engine != "crio" {
+		return nil
+	}
+
+	content, err := Render(config)
+	if err != nil {
+		return err
+	}
+
+	err = fsutil.MkdirAll(filepath.Dir(registriesFile), 0755)
+	if err != nil {
+		return err
+	}
+	_, err = fsutil.WriteFile(registriesFile, content, os.FileMode(0644))
+	return err
+}
+
+// Render returns the content of the registries drop-in for the configuration
+func Render(config *config.KubicInitConfiguration) ([]byte, error) {
+	var regs []registry
+	for _, reg := range config.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		r := registry{Prefix: location(reg.Prefix)}
+		for _, m := range reg.Mirrors {
+			u, err := url.Parse(m.URL)
+			if err != nil {
+				return nil, err
+			}
+			if u.Host == "" {
+				return nil, fmt.Errorf("Error in configuration file: malformed Mirror URL \"%s\"", m.URL)
+			}
+			r.Mirrors = append(r.Mirrors, mirror{
+				Location: u.Host + strings.TrimSuffix(u.Path, "/"),
+				Insecure: m.Insecure,
+			})
+		}
+		regs = append(regs, r)
+	}
+
+	tmpl, err := template.New("registries").Parse(registriesTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	var content bytes.Buffer
+	err = tmpl.Execute(&content, templateData{Role: config.ClusterFormation.Role, Registries: regs})
+	if err != nil {
+		return nil, err
+	}
+	return content.Bytes(), nil
+}
+
+// Drift compares the registries drop-in with what caasp-init renders for
+// the configuration, only when the runtime engine is CRI-O
+func Drift(config *config.KubicInitConfiguration) ([]drift.Finding, error) {
+	if config == nil {
+		return nil, errors.New("configuration is nil")
+	}
+	if config.Runtime.Engine != "crio" {
+		return nil, nil
+	}
+	content, err := Render(config)
+	if err != nil {
+		return nil, err
+	}
+	return drift.File(registriesFile, content)
+}
+
+// location removes the scheme of a registry prefix
+func location(prefix string) string {
+	if i := strings.Index(prefix, "://"); i >= 0 {
+		prefix = prefix[i+3:]
+	}
+	return strings.TrimSuffix(prefix, "/")
+}
+
+// ReloadAction returns what CRI-O needs to do to apply the changes made to
+// the registries drop-in: CRI-O is restarted whenever the drop-in changed.
+func ReloadAction(changes []fsutil.Change) reload.Action {
+	for _, change := range changes {
+		if change.Path == registriesFile && change.Changed() {
+			return reload.Restart
+		}
+	}
+	return reload.None
+}
@@ -0,0 +1,157 @@
+package registries
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/drift"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/reload"
+)
+
+var (
+	crioRegistries = &config.KubicInitConfiguration{
+		Runtime:          config.RuntimeConfiguration{Engine: "crio"},
+		ClusterFormation: config.ClusterFormationConfiguration{Role: config.RoleJoin},
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "https://mycompany.registry.com",
+					Mirrors: []config.Mirror{
+						{URL: "https://first.mirror.com/"},
+						{URL: "http://second.mirror.com:5000", Insecure: true},
+					},
+				},
+				{Prefix: "",
+					Mirrors: []config.Mirror{
+						{URL: ""},
+					},
+				},
+			},
+		},
+	}
+	dockerRegistries = &config.KubicInitConfiguration{
+		Runtime:   config.RuntimeConfiguration{Engine: "docker"},
+		Bootstrap: crioRegistries.Bootstrap,
+	}
+	noScheme = &config.KubicInitConfiguration{
+		Runtime: config.RuntimeConfiguration{Engine: "crio"},
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "mycompany.registry.com",
+					Mirrors: []config.Mirror{
+						{URL: "second.mirror.com"},
+					},
+				},
+			},
+		},
+	}
+)
+
+func TestWriteConfigFile(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-registries")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tests := []struct {
+		name    string
+		config  *config.KubicInitConfiguration
+		file    string
+		want    []string
+		wantErr bool
+	}{
+		{"nil", nil, filepath.Join(tmpDir, "nil.conf"), nil, true},
+		{"docker", dockerRegistries, filepath.Join(tmpDir, "docker.conf"), nil, false},
+		{"crio", crioRegistries, filepath.Join(tmpDir, "registries.conf.d", "crio.conf"), []string{
+			"# Role of the node: join\n",
+			"prefix = \"mycompany.registry.com\"",
+			"location = \"first.mirror.com\"\n\n",
+			"location = \"second.mirror.com:5000\"\ninsecure = true",
+		}, false},
+		{"no_scheme", noScheme, filepath.Join(tmpDir, "error.conf"), nil, true},
+		{"not_writable", crioRegistries, "/sys/registries.conf", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registriesFile = tt.file
+			if err := WriteConfigFile(tt.config); (err != nil) != tt.wantErr {
+				t.Fatalf("WriteConfigFile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			b, err := ioutil.ReadFile(tt.file)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("WriteConfigFile() wrote %s", tt.file)
+				}
+				return
+			}
+			for _, w := range tt.want {
+				if !strings.Contains(string(b), w) {
+					t.Errorf("WriteConfigFile() content %q does not contain %q", string(b), w)
+				}
+			}
+			if strings.Count(string(b), "[[registry]]") != 1 {
+				t.Errorf("WriteConfigFile() content %q should contain one registry", string(b))
+			}
+		})
+	}
+}
+
+func TestDrift(t *testing.T) {
+	defer func(f string) { registriesFile = f }(registriesFile)
+	tmpDir, err := ioutil.TempDir("", "caasp-init-registries")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	registriesFile = filepath.Join(tmpDir, "50-caasp-init.conf")
+
+	tests := []struct {
+		name   string
+		config *config.KubicInitConfiguration
+		edit   func()
+		want   string
+	}{
+		{"docker", dockerRegistries, func() {}, ""},
+		{"missing", crioRegistries, func() {}, drift.Missing},
+		{"same", crioRegistries, func() { WriteConfigFile(crioRegistries) }, ""},
+		{"modified", crioRegistries, func() { ioutil.WriteFile(registriesFile, []byte("# edited\n"), 0644) }, drift.Modified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.edit()
+			got, err := Drift(tt.config)
+			if err != nil {
+				t.Fatalf("Drift() error = %v", err)
+			}
+			if tt.want == "" && got != nil || tt.want != "" && (len(got) != 1 || got[0].Kind != tt.want || got[0].Owner != drift.Owned) {
+				t.Errorf("Drift() = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReloadAction(t *testing.T) {
+	tests := []struct {
+		name    string
+		changes []fsutil.Change
+		want    reload.Action
+	}{
+		{"no_changes", nil, reload.None},
+		{"unchanged", []fsutil.Change{{Path: registriesFile, Action: fsutil.Unchanged}}, reload.None},
+		{"created", []fsutil.Change{{Path: registriesFile, Action: fsutil.Created}}, reload.Restart},
+		{"updated", []fsutil.Change{{Path: registriesFile, Action: fsutil.Updated}}, reload.Restart},
+		{"other_file", []fsutil.Change{{Path: "/etc/docker/daemon.json", Action: fsutil.Updated}}, reload.None},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReloadAction(tt.changes); got != tt.want {
+				t.Errorf("ReloadAction() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package reload
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-systemd/dbus"
+)
+
+// Action to take on a unit after its configuration changed
+type Action int
+
+const (
+	// None the unit does not need to do anything
+	None Action = iota
+
+	// Reload the unit is sent a SIGHUP through its `ExecReload`
+	Reload
+
+	// Restart the unit has to be restarted
+	Restart
+)
+
+func (a Action) String() string {
+	switch a {
+	case Reload:
+		return "reload"
+	case Restart:
+		return "restart"
+	default:
+		return "none"
+	}
+}
+
+// Max returns the most disruptive of the actions
+func Max(actions ...Action) Action {
+	max := None
+	for _, a := range actions {
+		if a > max {
+			max = a
+		}
+	}
+	return max
+}
+
+// UnitManager is the part of the systemd D-Bus API used to reload the units
+type UnitManager interface {
+	ReloadOrTryRestartUnit(name string, mode string, ch chan<- string) (int, error)
+	TryRestartUnit(name string, mode string, ch chan<- string) (int, error)
+	Close()
+}
+
+var (
+	// newUnitManager connects to the systemd D-Bus API
+	newUnitManager = func() (UnitManager, error) {
+		return dbus.New()
+	}
+
+	// timeout for the reload or restart job to finish
+	timeout = 90 * time.Second
+)
+
+// Unit reloads or restarts the systemd unit `name` and waits for the job to
+// finish. Nothing is done for None, or when the unit is not running: it
+// reads the new configuration when it starts.
+func Unit(name string, action Action) error {
+	if action == None {
+		return nil
+	}
+
+	manager, err := newUnitManager()
+	if err != nil {
+		return fmt.Errorf("unable to connect to systemd: %v", err)
+	}
+	defer manager.Close()
+
+	ch := make(chan string, 1)
+	switch action {
+	case Reload:
+		_, err = manager.ReloadOrTryRestartUnit(name, "replace", ch)
+	case Restart:
+		_, err = manager.TryRestartUnit(name, "replace", ch)
+	default:
+		return fmt.Errorf("unknown action %d", action)
+	}
+	if err != nil {
+		return fmt.Errorf("unable to %s %s: %v", action, name, err)
+	}
+
+	select {
+	case result := <-ch:
+		if result != "done" {
+			return fmt.Errorf("unable to %s %s: job %s", action, name, result)
+		}
+	case <-time.After(timeout):
+		return fmt.Errorf("unable to %s %s: timed out after %s", action, name, timeout)
+	}
+	return nil
+}
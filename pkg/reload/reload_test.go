@@ -0,0 +1,107 @@
+package reload
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeBus records the jobs started on the units
+type fakeBus struct {
+	jobs   []string
+	result string
+	err    error
+	closed bool
+}
+
+func (b *fakeBus) job(method, name string, ch chan<- string) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	b.jobs = append(b.jobs, method+" "+name)
+	if b.result != "" {
+		ch <- b.result
+	}
+	return len(b.jobs), nil
+}
+
+func (b *fakeBus) ReloadOrTryRestartUnit(name string, mode string, ch chan<- string) (int, error) {
+	return b.job("reload", name, ch)
+}
+
+func (b *fakeBus) TryRestartUnit(name string, mode string, ch chan<- string) (int, error) {
+	return b.job("restart", name, ch)
+}
+
+func (b *fakeBus) Close() {
+	b.closed = true
+}
+
+func TestUnit(t *testing.T) {
+	defer func(f func() (UnitManager, error), d time.Duration) {
+		newUnitManager = f
+		timeout = d
+	}(newUnitManager, timeout)
+	timeout = 50 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		action   Action
+		bus      *fakeBus
+		busErr   error
+		wantJobs []string
+		wantErr  bool
+	}{
+		{"none", None, &fakeBus{result: "done"}, nil, nil, false},
+		{"reload", Reload, &fakeBus{result: "done"}, nil, []string{"reload docker.service"}, false},
+		{"restart", Restart, &fakeBus{result: "done"}, nil, []string{"restart docker.service"}, false},
+		{"job_failed", Restart, &fakeBus{result: "failed"}, nil, []string{"restart docker.service"}, true},
+		{"call_failed", Reload, &fakeBus{err: errors.New("unit not loaded")}, nil, nil, true},
+		{"timeout", Restart, &fakeBus{}, nil, []string{"restart docker.service"}, true},
+		{"no_bus", Restart, &fakeBus{}, errors.New("no such file"), nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			newUnitManager = func() (UnitManager, error) {
+				if tt.busErr != nil {
+					return nil, tt.busErr
+				}
+				return tt.bus, nil
+			}
+			err := Unit("docker.service", tt.action)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(tt.bus.jobs) != len(tt.wantJobs) {
+				t.Fatalf("Unit() jobs = %v, want %v", tt.bus.jobs, tt.wantJobs)
+			}
+			for i := range tt.wantJobs {
+				if tt.bus.jobs[i] != tt.wantJobs[i] {
+					t.Errorf("Unit() jobs = %v, want %v", tt.bus.jobs, tt.wantJobs)
+				}
+			}
+			if tt.action != None && tt.busErr == nil && !tt.bus.closed {
+				t.Errorf("Unit() did not close the connection")
+			}
+		})
+	}
+}
+
+func TestMax(t *testing.T) {
+	tests := []struct {
+		name    string
+		actions []Action
+		want    Action
+	}{
+		{"empty", nil, None},
+		{"reload", []Action{None, Reload}, Reload},
+		{"restart", []Action{Restart, Reload, None}, Restart},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Max(tt.actions...); got != tt.want {
+				t.Errorf("Max() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
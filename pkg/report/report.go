@@ -0,0 +1,201 @@
+package report
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+// Version of the report format. It changes only when fields are renamed or
+// removed, new fields can be added within a version.
+const Version = "caasp-init.report/v1"
+
+// Report struct
+// What a run of caasp-init did
+// Version: version of the report format.
+// Time: when the run finished.
+// Config: configuration files loaded.
+// Registries: registries and mirrors of the effective configuration.
+// Files: files managed by the run, written or not.
+// Certificates: CA certificates of the mirrors.
+// Warnings: warnings logged during the run.
+// Changed: whether any file changed.
+// Error: reason of the failure, empty when the run succeeded.
+type Report struct {
+	Version      string        `json:"version"`
+	Time         time.Time     `json:"time"`
+	Config       Source        `json:"config"`
+	Registries   []Registry    `json:"registries"`
+	Files        []File        `json:"files"`
+	Certificates []Certificate `json:"certificates"`
+	Warnings     []string      `json:"warnings"`
+	Changed      bool          `json:"changed"`
+	Error        string        `json:"error,omitempty"`
+}
+
+// Source struct
+// Configuration files loaded
+// Path: configuration file.
+// DropIns: drop-in files, in the order they were loaded.
+type Source struct {
+	Path    string   `json:"path"`
+	DropIns []string `json:"dropIns"`
+}
+
+// Registry struct
+// Registry with its mirrors
+type Registry struct {
+	Prefix  string   `json:"prefix"`
+	Mirrors []Mirror `json:"mirrors"`
+}
+
+// Mirror struct
+// Mirror of a registry, without its secrets
+// Credentials: whether credentials are configured for the mirror.
+type Mirror struct {
+	URL         string `json:"url"`
+	Insecure    bool   `json:"insecure"`
+	Credentials bool   `json:"credentials"`
+	Certificate bool   `json:"certificate"`
+}
+
+// File struct
+// File managed by caasp-init
+// Action: created, updated, unchanged or removed.
+// Mode: permissions, in octal.
+// SHA256: checksum of the content, empty when the file was removed.
+// PreviousSHA256: checksum of the content before the run, empty when the file did not exist.
+type File struct {
+	Path           string `json:"path"`
+	Action         string `json:"action"`
+	Mode           string `json:"mode"`
+	SHA256         string `json:"sha256,omitempty"`
+	PreviousSHA256 string `json:"previousSha256,omitempty"`
+}
+
+// Certificate struct
+// CA certificate installed for a mirror
+type Certificate struct {
+	Mirror      string    `json:"mirror"`
+	Path        string    `json:"path"`
+	Subject     string    `json:"subject"`
+	Issuer      string    `json:"issuer"`
+	NotAfter    time.Time `json:"notAfter"`
+	Fingerprint string    `json:"fingerprint"`
+}
+
+// New builds the report of a run that loaded `cfg` from `cfgPath`, made the
+// `changes` and finished with `runErr`. `cfg` is nil when it could not be
+// loaded.
+func New(cfgPath string, cfg *config.KubicInitConfiguration, changes []fsutil.Change, warnings []string, runErr error) *Report {
+	r := &Report{
+		Version:      Version,
+		Time:         time.Now().UTC(),
+		Config:       Source{Path: cfgPath, DropIns: []string{}},
+		Registries:   []Registry{},
+		Files:        []File{},
+		Certificates: []Certificate{},
+		Warnings:     []string{},
+	}
+	for _, w := range warnings {
+		r.Warnings = append(r.Warnings, log.Redact(w))
+	}
+	if dropIns, err := config.DropIns(cfgPath); err == nil && dropIns != nil {
+		r.Config.DropIns = dropIns
+	}
+	if runErr != nil {
+		r.Error = log.Redact(runErr.Error())
+	}
+
+	for _, change := range changes {
+		file := File{
+			Path:   change.Path,
+			Action: string(change.Action),
+			Mode:   fmt.Sprintf("%04o", change.Mode),
+		}
+		if change.Action != fsutil.Removed {
+			file.SHA256 = checksum(change.Content)
+		}
+		if change.Previous != nil {
+			file.PreviousSHA256 = checksum(change.Previous)
+		}
+		r.Files = append(r.Files, file)
+		r.Changed = r.Changed || change.Changed()
+	}
+
+	if cfg == nil {
+		return r
+	}
+	for _, reg := range cfg.Bootstrap.Registries {
+		if reg.Prefix == "" {
+			continue
+		}
+		registry := Registry{Prefix: reg.Prefix, Mirrors: []Mirror{}}
+		for _, m := range reg.Mirrors {
+			registry.Mirrors = append(registry.Mirrors, Mirror{
+				URL:         m.URL,
+				Insecure:    m.Insecure,
+				Credentials: m.Username != "" || m.IdentityToken != "",
+				Certificate: m.Certificate != "",
+			})
+			if m.Certificate != "" {
+				r.addCertificate(m)
+			}
+		}
+		r.Registries = append(r.Registries, registry)
+	}
+	return r
+}
+
+// addCertificate records the CA certificate of the mirror, or a warning
+// when it cannot be parsed
+func (r *Report) addCertificate(mirror config.Mirror) {
+	path, err := certs.Path(mirror)
+	if err != nil {
+		return
+	}
+	parsed, err := certs.ParseCertificates([]byte(mirror.Certificate))
+	if err != nil || len(parsed) == 0 {
+		r.Warnings = append(r.Warnings, fmt.Sprintf("unable to parse the certificate of mirror \"%s\"", mirror.URL))
+		return
+	}
+	cert := parsed[0]
+	fingerprint, _ := certs.Fingerprint(cert, certs.DefaultHashAlgorithm)
+	r.Certificates = append(r.Certificates, Certificate{
+		Mirror:      mirror.URL,
+		Path:        path,
+		Subject:     cert.Subject.String(),
+		Issuer:      cert.Issuer.String(),
+		NotAfter:    cert.NotAfter.UTC(),
+		Fingerprint: fingerprint,
+	})
+}
+
+// Write writes the report as JSON to the file at `path`, or to the
+// standard output when `path` is "-"
+func Write(r *Report, path string) error {
+	b, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	b = append(b, '\n')
+	if path == "-" {
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+	return ioutil.WriteFile(path, b, os.FileMode(0644))
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,170 @@
+package report
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kubic-project/caasp-init/pkg/certs"
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+func newTestCertificate(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %s", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "mirror-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating certificate: %s", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing certificate: %s", err)
+	}
+	return string(certs.EncodeCertificate(cert))
+}
+
+func TestNew(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-report")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cfgPath := filepath.Join(tmpDir, "kubic-init.yaml")
+	dropIn := filepath.Join(config.DropInDir(cfgPath), "10-mirrors.yaml")
+	if err := os.MkdirAll(filepath.Dir(dropIn), 0755); err != nil {
+		t.Fatalf("creating drop-in dir: %s", err)
+	}
+	if err := ioutil.WriteFile(dropIn, []byte("{}\n"), 0644); err != nil {
+		t.Fatalf("writing drop-in: %s", err)
+	}
+
+	cfg := &config.KubicInitConfiguration{
+		Bootstrap: config.BootstrapConfiguration{
+			Registries: []config.Registry{
+				{Prefix: "docker.io", Mirrors: []config.Mirror{
+					{URL: "https://mirror.com", Username: "user", Password: "secret", Certificate: newTestCertificate(t)},
+					{URL: "http://plain.mirror.com", Insecure: true},
+					{URL: "https://broken.mirror.com", Certificate: "not a certificate"},
+				}},
+				{Prefix: "", Mirrors: []config.Mirror{{URL: "https://ignored.com"}}},
+			},
+		},
+	}
+	changes := []fsutil.Change{
+		{Path: "/etc/docker/daemon.json", Action: fsutil.Updated, Mode: 0644, Previous: []byte("{}"), Content: []byte("{}\n")},
+		{Path: "/root/.docker/config.json", Action: fsutil.Unchanged, Mode: 0600, Previous: []byte("{}"), Content: []byte("{}")},
+		{Path: "/etc/docker/certs.d/old.mirror.com/ca.crt", Action: fsutil.Removed, Mode: 0644, Previous: []byte("{}"), PreviousMode: 0644},
+	}
+
+	r := New(cfgPath, cfg, changes, []string{"skipping quay.io"}, nil)
+	if r.Version != Version || r.Error != "" || !r.Changed {
+		t.Errorf("New() = %+v", r)
+	}
+	if r.Config.Path != cfgPath || len(r.Config.DropIns) != 1 || r.Config.DropIns[0] != dropIn {
+		t.Errorf("New() config = %+v", r.Config)
+	}
+	if len(r.Registries) != 1 || len(r.Registries[0].Mirrors) != 3 ||
+		!r.Registries[0].Mirrors[0].Credentials || !r.Registries[0].Mirrors[1].Insecure {
+		t.Errorf("New() registries = %+v", r.Registries)
+	}
+	wantFiles := []File{
+		{"/etc/docker/daemon.json", "updated", "0644", "ca3d163bab055381827226140568f3bef7eaac187cebd76878e0b63e9e442356", "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"},
+		{"/root/.docker/config.json", "unchanged", "0600", "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a", "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"},
+		{"/etc/docker/certs.d/old.mirror.com/ca.crt", "removed", "0644", "", "44136fa355b3678a1146ad16f7e8649e94fb4fc21fe77e8310c060f61caaff8a"},
+	}
+	for i := range wantFiles {
+		if i >= len(r.Files) || r.Files[i] != wantFiles[i] {
+			t.Errorf("New() files = %+v, want %+v", r.Files, wantFiles)
+			break
+		}
+	}
+	if len(r.Certificates) != 1 || r.Certificates[0].Subject != "CN=mirror-ca" ||
+		!r.Certificates[0].NotAfter.Equal(time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC)) ||
+		filepath.Base(r.Certificates[0].Path) != "ca.crt" {
+		t.Errorf("New() certificates = %+v", r.Certificates)
+	}
+	if len(r.Warnings) != 2 {
+		t.Errorf("New() warnings = %q", r.Warnings)
+	}
+
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if strings.Contains(string(b), "secret") {
+		t.Errorf("New() leaked a password: %s", b)
+	}
+}
+
+func TestNewError(t *testing.T) {
+	r := New("/missing/kubic-init.yaml", nil, nil, nil, errors.New("unable to read config"))
+	if r.Error != "unable to read config" || r.Changed || r.Registries == nil || r.Files == nil || r.Config.DropIns == nil {
+		t.Errorf("New() = %+v", r)
+	}
+}
+
+func TestNewRedactsSecrets(t *testing.T) {
+	defer log.ResetSecrets()
+	token := "94dcda.c271f4ff502789ca"
+	log.AddSecret(token)
+
+	r := New("kubic-init.yaml", nil, nil, []string{"token " + token + " expires soon"}, errors.New("invalid token "+token))
+	if r.Error != "invalid token (redacted)" {
+		t.Errorf("New() error = %q", r.Error)
+	}
+	if len(r.Warnings) != 1 || r.Warnings[0] != "token (redacted) expires soon" {
+		t.Errorf("New() warnings = %q", r.Warnings)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-report")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "report.json")
+
+	if err := Write(New("kubic-init.yaml", nil, nil, nil, nil), path); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading report: %s", err)
+	}
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("decoding report: %s", err)
+	}
+	for _, key := range []string{"version", "time", "config", "registries", "files", "certificates", "warnings", "changed"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("Write() report without %q: %s", key, b)
+		}
+	}
+	if err := Write(&Report{}, filepath.Join(tmpDir, "missing", "report.json")); err == nil {
+		t.Errorf("Write() error = nil for a missing directory")
+	}
+}
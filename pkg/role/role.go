@@ -0,0 +1,102 @@
+package role
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/log"
+	"github.com/kubic-project/caasp-init/pkg/netutil"
+)
+
+var (
+	// hostname returns the name of the node
+	hostname = os.Hostname
+
+	// lookupCNAME returns the canonical name of a host
+	lookupCNAME = net.LookupCNAME
+
+	// lookupHost returns the addresses of a host
+	lookupHost = net.LookupHost
+)
+
+// Detect returns the role of the node: the configured one, else seeder when
+// no seeder is set, or when the seeder is the name, the FQDN or an address
+// of the node, and join otherwise. The seeder can be passed with the
+// $SEEDER environment variable when it is not configured.
+func Detect(cfg *config.KubicInitConfiguration, lister netutil.InterfaceLister) (string, error) {
+	if cfg.ClusterFormation.Role != "" {
+		return cfg.ClusterFormation.Role, nil
+	}
+	if cfg.ClusterFormation.Seeder == "" {
+		cfg.ClusterFormation.Seeder = os.Getenv(config.DefaultEnvVarSeeder)
+	}
+	seeder := cfg.ClusterFormation.Seeder
+	if seeder == "" {
+		return config.RoleSeeder, nil
+	}
+	if host, _, err := net.SplitHostPort(seeder); err == nil {
+		seeder = host
+	}
+
+	addrs, err := localAddresses(lister)
+	if err != nil {
+		return "", err
+	}
+	if ip := net.ParseIP(seeder); ip != nil {
+		return roleOf(addrs[ip.String()]), nil
+	}
+
+	name, err := hostname()
+	if err != nil {
+		return "", fmt.Errorf("unable to get the name of the node: %v", err)
+	}
+	names := []string{name}
+	if fqdn, err := lookupCNAME(name); err == nil {
+		names = append(names, strings.TrimSuffix(fqdn, "."))
+	}
+	for _, n := range names {
+		if strings.EqualFold(strings.TrimSuffix(seeder, "."), n) {
+			return config.RoleSeeder, nil
+		}
+	}
+
+	ips, err := lookupHost(seeder)
+	if err != nil {
+		log.WithFields(log.Fields{"seeder": seeder}).Debugf("unable to resolve the seeder: %v", err)
+		return config.RoleJoin, nil
+	}
+	for _, ip := range ips {
+		if parsed := net.ParseIP(ip); parsed != nil && addrs[parsed.String()] {
+			return config.RoleSeeder, nil
+		}
+	}
+	return config.RoleJoin, nil
+}
+
+// localAddresses returns the addresses of the node, without the loopback
+// ones that every node has
+func localAddresses(lister netutil.InterfaceLister) (map[string]bool, error) {
+	ifaces, err := lister.Interfaces()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the network interfaces: %v", err)
+	}
+	addrs := map[string]bool{}
+	for _, iface := range ifaces {
+		for _, addr := range iface.Addrs {
+			if !addr.IP.IsLoopback() {
+				addrs[addr.IP.String()] = true
+			}
+		}
+	}
+	return addrs, nil
+}
+
+func roleOf(seeder bool) string {
+	if seeder {
+		return config.RoleSeeder
+	}
+	return config.RoleJoin
+}
@@ -0,0 +1,92 @@
+package role
+
+import (
+	"errors"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/netutil"
+)
+
+func TestDetect(t *testing.T) {
+	defer func(h func() (string, error), c func(string) (string, error), l func(string) ([]string, error)) {
+		hostname, lookupCNAME, lookupHost = h, c, l
+	}(hostname, lookupCNAME, lookupHost)
+	defer func(v string) { os.Setenv(config.DefaultEnvVarSeeder, v) }(os.Getenv(config.DefaultEnvVarSeeder))
+	os.Setenv(config.DefaultEnvVarSeeder, "")
+
+	hostname = func() (string, error) { return "node1", nil }
+	lookupCNAME = func(host string) (string, error) {
+		if host == "node1" {
+			return "node1.example.com.", nil
+		}
+		return "", errors.New("no such host")
+	}
+	lookupHost = func(host string) ([]string, error) {
+		switch host {
+		case "api.example.com":
+			return []string{"192.168.1.10"}, nil
+		case "localhost":
+			return []string{"127.0.0.1"}, nil
+		case "node2.example.com":
+			return []string{"192.168.1.11"}, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	_, eth0, _ := net.ParseCIDR("192.168.1.10/24")
+	eth0.IP = net.ParseIP("192.168.1.10")
+	_, lo, _ := net.ParseCIDR("127.0.0.1/8")
+	lo.IP = net.ParseIP("127.0.0.1")
+	node := netutil.StaticLister{
+		{Name: "lo", Loopback: true, Addrs: []*net.IPNet{lo}},
+		{Name: "eth0", Addrs: []*net.IPNet{eth0}},
+	}
+
+	tests := []struct {
+		name    string
+		cluster config.ClusterFormationConfiguration
+		env     string
+		lister  netutil.InterfaceLister
+		want    string
+		wantErr bool
+	}{
+		{"configured", config.ClusterFormationConfiguration{Seeder: "node1", Role: config.RoleJoin}, "", node, config.RoleJoin, false},
+		{"no_seeder", config.ClusterFormationConfiguration{}, "", node, config.RoleSeeder, false},
+		{"env_seeder", config.ClusterFormationConfiguration{}, "node2.example.com", node, config.RoleJoin, false},
+		{"hostname", config.ClusterFormationConfiguration{Seeder: "NODE1"}, "", node, config.RoleSeeder, false},
+		{"fqdn", config.ClusterFormationConfiguration{Seeder: "node1.example.com."}, "", node, config.RoleSeeder, false},
+		{"address", config.ClusterFormationConfiguration{Seeder: "192.168.1.10"}, "", node, config.RoleSeeder, false},
+		{"address_port", config.ClusterFormationConfiguration{Seeder: "192.168.1.10:6443"}, "", node, config.RoleSeeder, false},
+		{"resolved_address", config.ClusterFormationConfiguration{Seeder: "api.example.com"}, "", node, config.RoleSeeder, false},
+		{"loopback", config.ClusterFormationConfiguration{Seeder: "localhost"}, "", node, config.RoleJoin, false},
+		{"other_address", config.ClusterFormationConfiguration{Seeder: "192.168.1.11"}, "", node, config.RoleJoin, false},
+		{"other_node", config.ClusterFormationConfiguration{Seeder: "node2.example.com"}, "", node, config.RoleJoin, false},
+		{"unresolved", config.ClusterFormationConfiguration{Seeder: "unknown.example.com"}, "", node, config.RoleJoin, false},
+		{"lister_error", config.ClusterFormationConfiguration{Seeder: "node1"}, "", failingLister{}, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Setenv(config.DefaultEnvVarSeeder, tt.env)
+			cfg := &config.KubicInitConfiguration{ClusterFormation: tt.cluster}
+			got, err := Detect(cfg, tt.lister)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Detect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+			if tt.env != "" && cfg.ClusterFormation.Seeder != tt.env {
+				t.Errorf("Detect() seeder = %q, want $%s %q", cfg.ClusterFormation.Seeder, config.DefaultEnvVarSeeder, tt.env)
+			}
+		})
+	}
+}
+
+// failingLister fails to list the interfaces
+type failingLister struct{}
+
+func (failingLister) Interfaces() ([]netutil.Interface, error) {
+	return nil, errors.New("permission denied")
+}
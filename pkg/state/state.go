@@ -0,0 +1,460 @@
+package state
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+var (
+	manifestFile = "/var/lib/caasp-init/state.json"
+	backupDir    = "/var/lib/caasp-init/backup"
+)
+
+// Manifest struct
+// Files managed by caasp-init on the node
+// Generation: incremented every time a different configuration is applied.
+// Applied: last configuration applied.
+// Files: managed files, in the order they were first written.
+// Dirs: directories created by caasp-init, parents first.
+type Manifest struct {
+	Generation int           `json:"generation"`
+	Applied    Applied       `json:"applied"`
+	Files      []ManagedFile `json:"files"`
+	Dirs       []string      `json:"dirs,omitempty"`
+}
+
+// Applied struct
+// Configuration applied by caasp-init
+// Time: when it was applied.
+// Version: version of caasp-init that applied it.
+// Path: configuration file it was loaded from.
+// SHA256: checksum of the effective configuration.
+// Config: effective configuration, with the secrets replaced by their checksum.
+type Applied struct {
+	Time    time.Time `json:"time"`
+	Version string    `json:"version"`
+	Path    string    `json:"path"`
+	SHA256  string    `json:"sha256"`
+	Config  string    `json:"config"`
+}
+
+// ManagedFile struct
+// File written by caasp-init
+// Path: path of the file.
+// SHA256: checksum of the content caasp-init last wrote.
+// Generation: generation of the configuration that last wrote the file.
+// Version: version of caasp-init that last wrote the file.
+// Backup: copy of the file before caasp-init first wrote it, empty when it did not exist.
+// BackupMode: permissions of the file before caasp-init first wrote it.
+type ManagedFile struct {
+	Path       string      `json:"path"`
+	SHA256     string      `json:"sha256,omitempty"`
+	Generation int         `json:"generation,omitempty"`
+	Version    string      `json:"version,omitempty"`
+	Backup     string      `json:"backup,omitempty"`
+	BackupMode os.FileMode `json:"backupMode,omitempty"`
+}
+
+// Load reads the manifest, an empty one when caasp-init never ran
+func Load() (*Manifest, error) {
+	m := &Manifest{}
+	b, err := ioutil.ReadFile(manifestFile)
+	if os.IsNotExist(err) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("unable to decode %q: %v", manifestFile, err)
+	}
+	return m, nil
+}
+
+// Save writes the manifest
+func (m *Manifest) Save() error {
+	b, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(manifestFile), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(manifestFile, append(b, '\n'), os.FileMode(0600))
+}
+
+// File returns the entry of the file at `path`, nil when it is not managed
+func (m *Manifest) File(path string) *ManagedFile {
+	for i := range m.Files {
+		if m.Files[i].Path == path {
+			return &m.Files[i]
+		}
+	}
+	return nil
+}
+
+// Config returns the last configuration applied, with the secrets replaced by
+// their checksum, nil when none was applied
+func (m *Manifest) Config() (*config.KubicInitConfiguration, error) {
+	if m.Applied.SHA256 == "" {
+		return nil, nil
+	}
+	cfg := &config.KubicInitConfiguration{}
+	if err := yaml.Unmarshal([]byte(m.Applied.Config), cfg); err != nil {
+		return nil, fmt.Errorf("unable to decode the applied configuration: %v", err)
+	}
+	return cfg, nil
+}
+
+// Record adds the files written by `changes` and the directories `dirs`
+// created for them to the manifest, along with the configuration `cfg`
+// loaded from `cfgPath` that produced them and the `version` of caasp-init.
+// The content a file had before caasp-init first wrote it is kept as a
+// backup.
+func Record(changes []fsutil.Change, dirs []string, cfgPath string, cfg *config.KubicInitConfiguration, version string) error {
+	m, err := Load()
+	if err != nil {
+		return err
+	}
+
+	sum, err := config.Checksum(cfg)
+	if err != nil {
+		return err
+	}
+	if sum != m.Applied.SHA256 {
+		redacted, err := config.Redact(cfg, config.HashSecret)
+		if err != nil {
+			return err
+		}
+		content, err := yaml.Marshal(redacted)
+		if err != nil {
+			return err
+		}
+		m.Generation++
+		m.Applied.SHA256 = sum
+		m.Applied.Config = string(content)
+	}
+	m.Applied.Time = time.Now().UTC()
+	m.Applied.Version = version
+	m.Applied.Path = cfgPath
+	return m.record(changes, dirs, version)
+}
+
+// RecordFiles adds the files written by `changes` and the directories `dirs`
+// created for them to the manifest, like Record, without recording the
+// configuration as applied. It keeps the backups of the files written by a
+// run that failed partway, so that reset restores their original content.
+func RecordFiles(changes []fsutil.Change, dirs []string, version string) error {
+	if len(changes) == 0 && len(dirs) == 0 {
+		return nil
+	}
+	m, err := Load()
+	if err != nil {
+		return err
+	}
+	return m.record(changes, dirs, version)
+}
+
+// record adds the files and the directories to the manifest and saves it
+func (m *Manifest) record(changes []fsutil.Change, dirs []string, version string) error {
+	for _, change := range changes {
+		if change.Action == fsutil.Removed {
+			continue
+		}
+		file := m.File(change.Path)
+		if file == nil {
+			m.Files = append(m.Files, ManagedFile{Path: change.Path})
+			file = &m.Files[len(m.Files)-1]
+			if err := backup(file, change); err != nil {
+				return err
+			}
+		}
+		if change.Changed() || file.SHA256 == "" {
+			file.Generation = m.Generation
+			file.Version = version
+		}
+		file.SHA256 = checksum(change.Content)
+	}
+	for _, dir := range dirs {
+		if !m.dir(dir) {
+			m.Dirs = append(m.Dirs, dir)
+		}
+	}
+	return m.Save()
+}
+
+// dir tells whether the directory at `path` was created by caasp-init
+func (m *Manifest) dir(path string) bool {
+	for _, dir := range m.Dirs {
+		if dir == path {
+			return true
+		}
+	}
+	return false
+}
+
+// backup keeps the content the file had before caasp-init first wrote it
+func backup(file *ManagedFile, change fsutil.Change) error {
+	if change.Previous == nil {
+		return nil
+	}
+	file.Backup = filepath.Join(backupDir, change.Path)
+	file.BackupMode = change.PreviousMode
+	err := os.MkdirAll(filepath.Dir(file.Backup), 0700)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(file.Backup, change.Previous, os.FileMode(0600))
+}
+
+// FileStatus struct
+// State of a managed file on disk
+// State: ok, modified, missing or unknown.
+type FileStatus struct {
+	ManagedFile
+	State string
+}
+
+const (
+	// FileOK the file has the content caasp-init last wrote
+	FileOK = "ok"
+
+	// FileModified the file was changed since caasp-init last wrote it
+	FileModified = "modified"
+
+	// FileMissing the file was removed since caasp-init last wrote it
+	FileMissing = "missing"
+
+	// FileUnknown the content caasp-init last wrote was not recorded
+	FileUnknown = "unknown"
+)
+
+// Status compares the managed files on disk with the content caasp-init
+// last wrote
+func (m *Manifest) Status() ([]FileStatus, error) {
+	var status []FileStatus
+	for _, file := range m.Files {
+		s := FileStatus{ManagedFile: file}
+		b, err := ioutil.ReadFile(file.Path)
+		switch {
+		case os.IsNotExist(err):
+			s.State = FileMissing
+		case err != nil:
+			return nil, err
+		case file.SHA256 == "":
+			s.State = FileUnknown
+		case checksum(b) != file.SHA256:
+			s.State = FileModified
+		default:
+			s.State = FileOK
+		}
+		status = append(status, s)
+	}
+	return status, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// Step struct
+// What reset does to a managed file
+// Path: path of the file or directory.
+// Action: restored, removed or missing.
+type Step struct {
+	Path   string
+	Action string
+}
+
+const (
+	// StepRestored the file was restored from its backup
+	StepRestored = "restored"
+
+	// StepRemoved the file, or its empty directory, was removed
+	StepRemoved = "removed"
+
+	// StepMissing the file was already removed
+	StepMissing = "missing"
+)
+
+// Reset removes the files managed by caasp-init, or restores their backups,
+// along with the directories it created once they are empty, and forgets
+// about them. With `dryRun` nothing is changed, the steps are only returned.
+func Reset(dryRun bool) ([]Step, error) {
+	m, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	removed := map[string]bool{}
+	for i := len(m.Files) - 1; i >= 0; i-- {
+		file := m.Files[i]
+		step, err := resetFile(file, dryRun)
+		if err != nil {
+			return steps, err
+		}
+		steps = append(steps, step)
+		if step.Action != StepRestored {
+			removed[file.Path] = true
+		}
+	}
+	dirSteps, err := removeDirs(m.Dirs, removed, dryRun)
+	steps = append(steps, dirSteps...)
+	if err != nil || dryRun {
+		return steps, err
+	}
+	if err := os.RemoveAll(backupDir); err != nil {
+		return steps, err
+	}
+	if err := os.Remove(manifestFile); err != nil && !os.IsNotExist(err) {
+		return steps, err
+	}
+	return steps, nil
+}
+
+// Release stops managing the files at `paths`, which the configuration no
+// longer produces: they are removed, or restored from their backup, as
+// reset does, along with the directories caasp-init created for them once
+// they are empty. The paths that are not managed are ignored.
+func Release(paths []string) ([]Step, error) {
+	m, err := Load()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	removed := map[string]bool{}
+	for _, path := range paths {
+		file := m.File(path)
+		if file == nil {
+			continue
+		}
+		step, err := resetFile(*file, false)
+		if err != nil {
+			return steps, err
+		}
+		steps = append(steps, step)
+		if step.Action != StepRestored {
+			removed[path] = true
+		}
+		if file.Backup != "" {
+			if err := os.Remove(file.Backup); err != nil && !os.IsNotExist(err) {
+				return steps, err
+			}
+		}
+		m.forget(path)
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+
+	dirSteps, err := removeDirs(m.Dirs, removed, false)
+	steps = append(steps, dirSteps...)
+	for _, step := range dirSteps {
+		m.forgetDir(step.Path)
+	}
+	if err != nil {
+		return steps, err
+	}
+	return steps, m.Save()
+}
+
+// forget removes the file at `path` from the manifest
+func (m *Manifest) forget(path string) {
+	for i := range m.Files {
+		if m.Files[i].Path == path {
+			m.Files = append(m.Files[:i], m.Files[i+1:]...)
+			return
+		}
+	}
+}
+
+// forgetDir removes the directory at `path` from the manifest
+func (m *Manifest) forgetDir(path string) {
+	for i := range m.Dirs {
+		if m.Dirs[i] == path {
+			m.Dirs = append(m.Dirs[:i], m.Dirs[i+1:]...)
+			return
+		}
+	}
+}
+
+// removeDirs removes the directories created by caasp-init that are empty
+// once the files in `removed` are gone, children first. With `dryRun`
+// nothing is changed, the steps are only returned.
+func removeDirs(dirs []string, removed map[string]bool, dryRun bool) ([]Step, error) {
+	var steps []Step
+	for i := len(dirs) - 1; i >= 0; i-- {
+		dir := dirs[i]
+		if !emptyDir(dir, removed) {
+			continue
+		}
+		if !dryRun {
+			if err := os.Remove(dir); err != nil {
+				return steps, err
+			}
+			log.WithFields(log.Fields{"path": dir, "action": StepRemoved}).Infof("directory removed")
+		}
+		removed[dir] = true
+		steps = append(steps, Step{Path: dir, Action: StepRemoved})
+	}
+	return steps, nil
+}
+
+func resetFile(file ManagedFile, dryRun bool) (Step, error) {
+	if file.Backup != "" {
+		backup, err := ioutil.ReadFile(file.Backup)
+		if err != nil {
+			return Step{}, fmt.Errorf("unable to read the backup of %q: %v", file.Path, err)
+		}
+		if !dryRun {
+			if err := os.MkdirAll(filepath.Dir(file.Path), 0755); err != nil {
+				return Step{}, err
+			}
+			if _, err := fsutil.WriteFile(file.Path, backup, file.BackupMode); err != nil {
+				return Step{}, err
+			}
+		}
+		return Step{Path: file.Path, Action: StepRestored}, nil
+	}
+
+	if _, err := os.Stat(file.Path); os.IsNotExist(err) {
+		return Step{Path: file.Path, Action: StepMissing}, nil
+	}
+	if !dryRun {
+		if _, err := fsutil.RemoveFile(file.Path); err != nil {
+			return Step{}, err
+		}
+	}
+	return Step{Path: file.Path, Action: StepRemoved}, nil
+}
+
+// emptyDir tells whether the directory exists and is empty once the
+// entries in `removed` are gone
+func emptyDir(dir string, removed map[string]bool) bool {
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if !removed[filepath.Join(dir, entry.Name())] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,370 @@
+package state
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+)
+
+// setup points the manifest and backups to a tmp dir, returning it
+func setup(t *testing.T) (string, func()) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-state")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	oldManifest, oldBackup := manifestFile, backupDir
+	manifestFile = filepath.Join(tmpDir, "var", "state.json")
+	backupDir = filepath.Join(tmpDir, "var", "backup")
+	return tmpDir, func() {
+		manifestFile, backupDir = oldManifest, oldBackup
+		os.RemoveAll(tmpDir)
+	}
+}
+
+func writeFile(t *testing.T, path, content string) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatalf("creating dir: %s", err)
+	}
+	if err := ioutil.WriteFile(path, []byte(content), 0640); err != nil {
+		t.Fatalf("writing %s: %s", path, err)
+	}
+}
+
+func TestRecordAndReset(t *testing.T) {
+	tmpDir, cleanup := setup(t)
+	defer cleanup()
+
+	daemonJSON := filepath.Join(tmpDir, "etc", "docker", "daemon.json")
+	cert := filepath.Join(tmpDir, "etc", "docker", "certs.d", "mirror.com", "ca.crt")
+	cniConf := filepath.Join(tmpDir, "etc", "cni", "net.d", "10-caasp-init.conflist")
+	writeFile(t, daemonJSON, `{"debug": true}`)
+	writeFile(t, filepath.Join(tmpDir, "etc", "docker", "key.json"), "{}")
+	// an empty directory caasp-init did not create
+	if err := os.MkdirAll(filepath.Dir(cniConf), 0755); err != nil {
+		t.Fatalf("creating dir: %s", err)
+	}
+
+	cfg := &config.KubicInitConfiguration{}
+
+	// first run: daemon.json existed, the certificate did not
+	fsutil.ResetChanges()
+	defer fsutil.ResetChanges()
+	if _, err := fsutil.WriteFile(daemonJSON, []byte(`{"iptables": false}`), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := fsutil.MkdirAll(filepath.Dir(cert), 0755); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if _, err := fsutil.WriteFile(cert, []byte("cert"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := fsutil.WriteFile(cniConf, []byte("{}"), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := Record(fsutil.Changes(), fsutil.Dirs(), "kubic-init.yaml", cfg, "1.0"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	// second run: the backup of daemon.json is the content before the first run
+	fsutil.ResetChanges()
+	if _, err := fsutil.WriteFile(daemonJSON, []byte(`{"iptables": false, "log-level": "warn"}`), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := Record(fsutil.Changes(), fsutil.Dirs(), "kubic-init.yaml", cfg, "1.0"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	m, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	want := []ManagedFile{
+		{Path: daemonJSON, SHA256: checksum([]byte(`{"iptables": false, "log-level": "warn"}`)), Generation: 1, Version: "1.0", Backup: filepath.Join(backupDir, daemonJSON), BackupMode: 0640},
+		{Path: cert, SHA256: checksum([]byte("cert")), Generation: 1, Version: "1.0"},
+		{Path: cniConf, SHA256: checksum([]byte("{}")), Generation: 1, Version: "1.0"},
+	}
+	if !reflect.DeepEqual(m.Files, want) {
+		t.Errorf("Load() = %+v, want %+v", m.Files, want)
+	}
+	certsDir := filepath.Join(tmpDir, "etc", "docker", "certs.d")
+	if wantDirs := []string{certsDir, filepath.Dir(cert)}; !reflect.DeepEqual(m.Dirs, wantDirs) {
+		t.Errorf("Load() dirs = %q, want %q", m.Dirs, wantDirs)
+	}
+
+	wantSteps := []Step{
+		{Path: cniConf, Action: StepRemoved},
+		{Path: cert, Action: StepRemoved},
+		{Path: daemonJSON, Action: StepRestored},
+		{Path: filepath.Dir(cert), Action: StepRemoved},
+		{Path: certsDir, Action: StepRemoved},
+	}
+	steps, err := Reset(true)
+	if err != nil {
+		t.Fatalf("Reset(true) error = %v", err)
+	}
+	if !reflect.DeepEqual(steps, wantSteps) {
+		t.Errorf("Reset(true) = %+v, want %+v", steps, wantSteps)
+	}
+	if _, err := os.Stat(cert); err != nil {
+		t.Errorf("Reset(true) changed the files: %v", err)
+	}
+
+	steps, err = Reset(false)
+	if err != nil {
+		t.Fatalf("Reset(false) error = %v", err)
+	}
+	if !reflect.DeepEqual(steps, wantSteps) {
+		t.Errorf("Reset(false) = %+v, want %+v", steps, wantSteps)
+	}
+	if _, err := os.Stat(certsDir); !os.IsNotExist(err) {
+		t.Errorf("Reset(false) did not remove the certificate directories")
+	}
+	if _, err := os.Stat(filepath.Dir(cniConf)); err != nil {
+		t.Errorf("Reset(false) removed a directory not created by caasp-init")
+	}
+	b, _ := ioutil.ReadFile(daemonJSON)
+	if info, err := os.Stat(daemonJSON); err != nil || string(b) != `{"debug": true}` || info.Mode().Perm() != 0640 {
+		t.Errorf("Reset(false) did not restore daemon.json: %s", b)
+	}
+	if _, err := os.Stat(filepath.Join(tmpDir, "etc", "docker", "key.json")); err != nil {
+		t.Errorf("Reset(false) removed a file not managed by caasp-init")
+	}
+	if _, err := os.Stat(manifestFile); !os.IsNotExist(err) {
+		t.Errorf("Reset(false) did not remove the manifest")
+	}
+
+	steps, err = Reset(false)
+	if err != nil || len(steps) != 0 {
+		t.Errorf("Reset(false) = %+v, %v without manifest", steps, err)
+	}
+}
+
+func TestRecordGenerations(t *testing.T) {
+	tmpDir, cleanup := setup(t)
+	defer cleanup()
+
+	daemonJSON := filepath.Join(tmpDir, "daemon.json")
+	registries := filepath.Join(tmpDir, "registries.conf")
+	fsutil.ResetChanges()
+	defer fsutil.ResetChanges()
+	run := func(cfg *config.KubicInitConfiguration, version string, files map[string]string) *Manifest {
+		fsutil.ResetChanges()
+		for _, path := range []string{daemonJSON, registries} {
+			if _, err := fsutil.WriteFile(path, []byte(files[path]), 0644); err != nil {
+				t.Fatalf("WriteFile() error = %v", err)
+			}
+		}
+		if err := Record(fsutil.Changes(), fsutil.Dirs(), "kubic-init.yaml", cfg, version); err != nil {
+			t.Fatalf("Record() error = %v", err)
+		}
+		m, err := Load()
+		if err != nil {
+			t.Fatalf("Load() error = %v", err)
+		}
+		return m
+	}
+
+	cfg := &config.KubicInitConfiguration{ClusterFormation: config.ClusterFormationConfiguration{Token: "94dcda.c271f4ff502789ca"}}
+	files := map[string]string{daemonJSON: "{}", registries: "[]"}
+	m := run(cfg, "1.0", files)
+	if m.Generation != 1 || m.Applied.Version != "1.0" || m.Applied.Path != "kubic-init.yaml" {
+		t.Errorf("Record() = %+v after the first run", m)
+	}
+
+	// same configuration, rendered by a newer version: nothing is written
+	m = run(cfg, "1.1", files)
+	if m.Generation != 1 || m.Applied.Version != "1.1" || m.Files[0].Version != "1.0" {
+		t.Errorf("Record() = %+v with the same configuration", m)
+	}
+
+	// new configuration changing only one file
+	cfg = &config.KubicInitConfiguration{ClusterFormation: config.ClusterFormationConfiguration{Token: "94dcda.0000000000000000"}}
+	files = map[string]string{daemonJSON: `{"debug": true}`, registries: "[]"}
+	m = run(cfg, "1.1", files)
+	if m.Generation != 2 || m.File(daemonJSON).Generation != 2 || m.File(registries).Generation != 1 {
+		t.Errorf("Record() = %+v with a new configuration", m)
+	}
+
+	applied, err := m.Config()
+	if err != nil {
+		t.Fatalf("Config() error = %v", err)
+	}
+	if applied.ClusterFormation.Token != config.HashSecret("94dcda.0000000000000000") {
+		t.Errorf("Config() token = %q, want its checksum", applied.ClusterFormation.Token)
+	}
+	b, _ := ioutil.ReadFile(manifestFile)
+	if strings.Contains(string(b), "94dcda.0000000000000000") {
+		t.Errorf("Record() saved the token in the manifest")
+	}
+}
+
+func TestStatus(t *testing.T) {
+	tmpDir, cleanup := setup(t)
+	defer cleanup()
+
+	ok := filepath.Join(tmpDir, "ok")
+	modified := filepath.Join(tmpDir, "modified")
+	unknown := filepath.Join(tmpDir, "unknown")
+	writeFile(t, ok, "ok")
+	writeFile(t, modified, "edited")
+	writeFile(t, unknown, "unknown")
+	m := &Manifest{Files: []ManagedFile{
+		{Path: ok, SHA256: checksum([]byte("ok"))},
+		{Path: modified, SHA256: checksum([]byte("modified"))},
+		{Path: filepath.Join(tmpDir, "missing"), SHA256: checksum([]byte("missing"))},
+		{Path: unknown},
+	}}
+
+	status, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status() error = %v", err)
+	}
+	want := []string{FileOK, FileModified, FileMissing, FileUnknown}
+	for i, s := range status {
+		if s.State != want[i] {
+			t.Errorf("Status() %s = %s, want %s", s.Path, s.State, want[i])
+		}
+	}
+	if len(status) != len(want) {
+		t.Errorf("Status() = %+v", status)
+	}
+
+	if cfg, err := (&Manifest{}).Config(); cfg != nil || err != nil {
+		t.Errorf("Config() = %v, %v without applied configuration", cfg, err)
+	}
+}
+
+func TestRelease(t *testing.T) {
+	tmpDir, cleanup := setup(t)
+	defer cleanup()
+
+	certsDir := filepath.Join(tmpDir, "certs.d")
+	cert := filepath.Join(certsDir, "old.mirror.com", "ca.crt")
+	kept := filepath.Join(certsDir, "mirror.com", "ca.crt")
+	restored := filepath.Join(tmpDir, "restored.crt")
+	writeFile(t, restored, "previous")
+
+	fsutil.ResetChanges()
+	defer fsutil.ResetChanges()
+	for _, path := range []string{cert, kept, restored} {
+		if err := fsutil.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if _, err := fsutil.WriteFile(path, []byte("cert"), 0644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	if err := Record(fsutil.Changes(), fsutil.Dirs(), "kubic-init.yaml", &config.KubicInitConfiguration{}, "1.0"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+
+	steps, err := Release([]string{cert, restored, filepath.Join(tmpDir, "unmanaged.crt")})
+	if err != nil {
+		t.Fatalf("Release() error = %v", err)
+	}
+	wantSteps := []Step{
+		{Path: cert, Action: StepRemoved},
+		{Path: restored, Action: StepRestored},
+		{Path: filepath.Dir(cert), Action: StepRemoved},
+	}
+	if !reflect.DeepEqual(steps, wantSteps) {
+		t.Errorf("Release() = %+v, want %+v", steps, wantSteps)
+	}
+	if b, _ := ioutil.ReadFile(restored); string(b) != "previous" {
+		t.Errorf("Release() did not restore %s: %s", restored, b)
+	}
+	if _, err := os.Stat(kept); err != nil {
+		t.Errorf("Release() removed a file still produced: %v", err)
+	}
+
+	m, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(m.Files) != 1 || m.Files[0].Path != kept {
+		t.Errorf("Release() kept %+v in the manifest", m.Files)
+	}
+	if want := []string{certsDir, filepath.Dir(kept)}; !reflect.DeepEqual(m.Dirs, want) {
+		t.Errorf("Release() kept the dirs %q, want %q", m.Dirs, want)
+	}
+	if _, err := os.Stat(filepath.Join(backupDir, restored)); !os.IsNotExist(err) {
+		t.Errorf("Release() kept the backup of %s", restored)
+	}
+
+	if steps, err := Release(nil); err != nil || steps != nil {
+		t.Errorf("Release(nil) = %+v, %v", steps, err)
+	}
+}
+
+func TestResetMissingFile(t *testing.T) {
+	tmpDir, cleanup := setup(t)
+	defer cleanup()
+
+	m := &Manifest{Files: []ManagedFile{{Path: filepath.Join(tmpDir, "gone.json")}}}
+	if err := m.Save(); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	steps, err := Reset(false)
+	if err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if len(steps) != 1 || steps[0].Action != StepMissing {
+		t.Errorf("Reset() = %+v", steps)
+	}
+}
+
+func TestLoadMalformed(t *testing.T) {
+	_, cleanup := setup(t)
+	defer cleanup()
+
+	writeFile(t, manifestFile, "{")
+	if _, err := Load(); err == nil {
+		t.Errorf("Load() accepted a malformed manifest")
+	}
+}
+
+func TestRecordFiles(t *testing.T) {
+	tmpDir, cleanup := setup(t)
+	defer cleanup()
+
+	daemonJSON := filepath.Join(tmpDir, "daemon.json")
+	writeFile(t, daemonJSON, `{"debug": true}`)
+
+	// a run failing after writing daemon.json
+	fsutil.ResetChanges()
+	defer fsutil.ResetChanges()
+	if _, err := fsutil.WriteFile(daemonJSON, []byte(`{"iptables": false}`), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := RecordFiles(fsutil.Changes(), fsutil.Dirs(), "1.0"); err != nil {
+		t.Fatalf("RecordFiles() error = %v", err)
+	}
+	m, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if m.Generation != 0 || m.Applied.SHA256 != "" || m.File(daemonJSON) == nil {
+		t.Errorf("RecordFiles() = %+v, want the file recorded without an applied configuration", m)
+	}
+
+	// the next run does not take the content written by the failed one as
+	// the original
+	fsutil.ResetChanges()
+	if _, err := fsutil.WriteFile(daemonJSON, []byte(`{"iptables": false, "log-level": "warn"}`), 0644); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := Record(fsutil.Changes(), fsutil.Dirs(), "kubic-init.yaml", &config.KubicInitConfiguration{}, "1.0"); err != nil {
+		t.Fatalf("Record() error = %v", err)
+	}
+	if _, err := Reset(false); err != nil {
+		t.Fatalf("Reset() error = %v", err)
+	}
+	if got, _ := ioutil.ReadFile(daemonJSON); string(got) != `{"debug": true}` {
+		t.Errorf("Reset() restored %s, want the content before the failed run", got)
+	}
+}
@@ -0,0 +1,120 @@
+package token
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/fsutil"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+// alphabet are the characters of a bootstrap token
+const alphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+
+var (
+	// tokenFile is where the token generated on the seeder is kept
+	tokenFile = "/var/lib/caasp-init/token"
+
+	// random is the source of the generated tokens
+	random io.Reader = rand.Reader
+)
+
+// Generate returns a new random bootstrap token
+func Generate() (string, error) {
+	id, err := randomString(6)
+	if err != nil {
+		return "", err
+	}
+	secret, err := randomString(16)
+	if err != nil {
+		return "", err
+	}
+	return id + "." + secret, nil
+}
+
+// randomString returns `n` characters of the alphabet drawn uniformly
+func randomString(n int) (string, error) {
+	// bytes above the largest multiple of the alphabet size are discarded,
+	// so that every character has the same probability
+	limit := 256 - 256%len(alphabet)
+	s := make([]byte, 0, n)
+	b := make([]byte, 1)
+	for len(s) < n {
+		if _, err := io.ReadFull(random, b); err != nil {
+			return "", fmt.Errorf("unable to generate a token: %v", err)
+		}
+		if int(b[0]) >= limit {
+			continue
+		}
+		s = append(s, alphabet[int(b[0])%len(alphabet)])
+	}
+	return string(s), nil
+}
+
+// Resolve sets the bootstrap token of the configuration when it has none,
+// from the $TOKEN environment variable or the token kept on the node. When
+// there is none, a token is generated and kept for the `seeder`. The token
+// is hidden from the logs from now on.
+func Resolve(cfg *config.KubicInitConfiguration, seeder bool) error {
+	token, source := cfg.ClusterFormation.Token, "configuration"
+	if token == "" {
+		token, source = os.Getenv(config.DefaultEnvVarToken), "$"+config.DefaultEnvVarToken
+	}
+	if token == "" {
+		stored, err := Load()
+		if err != nil {
+			return err
+		}
+		token, source = stored, tokenFile
+	}
+	if token == "" && seeder {
+		generated, err := Generate()
+		if err != nil {
+			return err
+		}
+		log.AddSecret(generated)
+		if err := Store(generated); err != nil {
+			return err
+		}
+		log.WithFields(log.Fields{"path": tokenFile}).Infof("bootstrap token generated")
+		token, source = generated, "generated"
+	}
+	if token == "" {
+		return nil
+	}
+
+	log.AddSecret(token)
+	if err := config.ValidateToken(token); err != nil {
+		return fmt.Errorf("%s: %v", source, err)
+	}
+	log.WithFields(log.Fields{"source": source}).Debugf("bootstrap token loaded")
+	cfg.ClusterFormation.Token = token
+	return nil
+}
+
+// Load returns the token kept on the node, empty when there is none
+func Load() (string, error) {
+	b, err := ioutil.ReadFile(tokenFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
+// Store keeps the token on the node, readable only by root
+func Store(token string) error {
+	if err := os.MkdirAll(filepath.Dir(tokenFile), 0700); err != nil {
+		return err
+	}
+	_, err := fsutil.WriteFile(tokenFile, []byte(token+"\n"), os.FileMode(0600))
+	return err
+}
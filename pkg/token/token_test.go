@@ -0,0 +1,123 @@
+package token
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+	"github.com/kubic-project/caasp-init/pkg/log"
+)
+
+func TestGenerate(t *testing.T) {
+	seen := map[string]bool{}
+	for i := 0; i < 100; i++ {
+		token, err := Generate()
+		if err != nil {
+			t.Fatalf("Generate() error = %v", err)
+		}
+		if err := config.ValidateToken(token); err != nil {
+			t.Fatalf("Generate() = %q: %v", token, err)
+		}
+		if seen[token] {
+			t.Fatalf("Generate() returned %q twice", token)
+		}
+		seen[token] = true
+	}
+}
+
+func TestRandomString(t *testing.T) {
+	defer func(r io.Reader) { random = r }(random)
+
+	// 252 and above are discarded, 36 is "a" again
+	random = bytes.NewReader([]byte{0, 255, 25, 26, 252, 35, 36})
+	got, err := randomString(5)
+	if err != nil {
+		t.Fatalf("randomString() error = %v", err)
+	}
+	if got != "az09a" {
+		t.Errorf("randomString() = %q, want %q", got, "az09a")
+	}
+
+	random = bytes.NewReader([]byte{1, 2})
+	if _, err := randomString(3); err == nil {
+		t.Errorf("randomString() did not fail when the source was exhausted")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	defer func(f string) { tokenFile = f }(tokenFile)
+	defer func(v string) { os.Setenv(config.DefaultEnvVarToken, v) }(os.Getenv(config.DefaultEnvVarToken))
+	defer log.ResetSecrets()
+	tmpDir, err := ioutil.TempDir("", "caasp-init-token")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	configured := "94dcda.c271f4ff502789ca"
+	stored := "abcdef.0123456789abcdef"
+	tests := []struct {
+		name    string
+		token   string
+		env     string
+		stored  string
+		seeder  bool
+		want    string
+		wantErr bool
+	}{
+		{"configured", configured, "", stored, true, configured, false},
+		{"env", "", "123456.aaaaaaaaaaaaaaaa", stored, false, "123456.aaaaaaaaaaaaaaaa", false},
+		{"invalid_env", "", "123456.AAAAAAAAAAAAAAAA", "", false, "", true},
+		{"stored", "", "", stored, true, stored, false},
+		{"invalid_stored", "", "", "garbage", true, "", true},
+		{"join_without_token", "", "", "", false, "", false},
+		{"seeder_generated", "", "", "", true, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokenFile = filepath.Join(tmpDir, tt.name, "token")
+			if tt.stored != "" {
+				os.MkdirAll(filepath.Dir(tokenFile), 0700)
+				if err := ioutil.WriteFile(tokenFile, []byte(tt.stored+"\n"), 0600); err != nil {
+					t.Fatalf("writing %s: %s", tokenFile, err)
+				}
+			}
+			os.Setenv(config.DefaultEnvVarToken, tt.env)
+
+			cfg := &config.KubicInitConfiguration{ClusterFormation: config.ClusterFormationConfiguration{Token: tt.token}}
+			err := Resolve(cfg, tt.seeder)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Resolve() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && strings.Contains(err.Error(), tt.env+tt.stored) {
+				t.Errorf("Resolve() error %q shows the token", err)
+			}
+			got := cfg.ClusterFormation.Token
+			if tt.name != "seeder_generated" {
+				if !tt.wantErr && got != tt.want {
+					t.Errorf("Resolve() token = %q, want %q", got, tt.want)
+				}
+				return
+			}
+
+			if config.ValidateToken(got) != nil {
+				t.Fatalf("Resolve() generated %q", got)
+			}
+			if again, _ := Load(); again != got {
+				t.Errorf("Resolve() kept %q, want %q", again, got)
+			}
+			info, err := os.Stat(tokenFile)
+			if err != nil || info.Mode().Perm() != 0600 {
+				t.Errorf("Resolve() kept the token with mode %v, %v", info.Mode(), err)
+			}
+			if log.Redact(got) != "(redacted)" {
+				t.Errorf("Resolve() did not hide the generated token from the logs")
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package watch
+
+import (
+	"time"
+)
+
+// Config calls `onChange` every time the configuration file at `cfgPath` or
+// one of its drop-in files is written, created, renamed or removed, once no
+// other change happened for `delay`. It returns when `stop` is closed.
+func Config(cfgPath string, delay time.Duration, stop <-chan struct{}, onChange func()) error {
+	w, err := newWatcher(cfgPath)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+	return debounce(w.Events(), w.Errors(), delay, stop, onChange)
+}
+
+// watcher notifies the changes of the configuration files
+type watcher interface {
+	Events() <-chan struct{}
+	Errors() <-chan error
+	Close()
+}
+
+// debounce calls `onChange` after `delay` without events. The events
+// received while `onChange` runs lead to another call.
+func debounce(events <-chan struct{}, errs <-chan error, delay time.Duration, stop <-chan struct{}, onChange func()) error {
+	var timer <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err := <-errs:
+			return err
+		case <-events:
+			timer = time.After(delay)
+		case <-timer:
+			timer = nil
+			onChange()
+		}
+	}
+}
@@ -0,0 +1,167 @@
+//go:build linux
+// +build linux
+
+package watch
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"unsafe"
+
+	"github.com/kubic-project/caasp-init/pkg/config"
+)
+
+const watchMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotifyWatcher watches the directory of the configuration file, for the
+// file itself and the drop-in directory, and the drop-in directory. Watching
+// the directories keeps track of the files replaced by a rename.
+type inotifyWatcher struct {
+	fd        int
+	cfgName   string
+	dropInDir string
+
+	mu       sync.Mutex
+	closing  bool
+	cfgWd    int
+	dropInWd int
+
+	events chan struct{}
+	errs   chan error
+}
+
+func newWatcher(cfgPath string) (watcher, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize inotify: %v", err)
+	}
+	w := &inotifyWatcher{
+		fd:        fd,
+		cfgName:   filepath.Base(cfgPath),
+		dropInDir: config.DropInDir(cfgPath),
+		dropInWd:  -1,
+		events:    make(chan struct{}, 1),
+		errs:      make(chan error, 1),
+	}
+
+	w.cfgWd, err = syscall.InotifyAddWatch(fd, filepath.Dir(cfgPath), watchMask)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, fmt.Errorf("unable to watch %q: %v", filepath.Dir(cfgPath), err)
+	}
+	w.watchDropInDir()
+
+	go w.read()
+	return w, nil
+}
+
+func (w *inotifyWatcher) Events() <-chan struct{} {
+	return w.events
+}
+
+func (w *inotifyWatcher) Errors() <-chan error {
+	return w.errs
+}
+
+// Close removes the watches, which wakes up the reader so it closes the
+// inotify file descriptor
+func (w *inotifyWatcher) Close() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closing {
+		return
+	}
+	w.closing = true
+	syscall.InotifyRmWatch(w.fd, uint32(w.cfgWd))
+	if w.dropInWd >= 0 {
+		syscall.InotifyRmWatch(w.fd, uint32(w.dropInWd))
+	}
+}
+
+// watchDropInDir starts watching the drop-in directory, if it exists
+func (w *inotifyWatcher) watchDropInDir() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closing {
+		return
+	}
+	if info, err := os.Stat(w.dropInDir); err != nil || !info.IsDir() {
+		return
+	}
+	wd, err := syscall.InotifyAddWatch(w.fd, w.dropInDir, watchMask)
+	if err == nil {
+		w.dropInWd = wd
+	}
+}
+
+func (w *inotifyWatcher) isClosing() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closing
+}
+
+func (w *inotifyWatcher) read() {
+	defer syscall.Close(w.fd)
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		n, err := syscall.Read(w.fd, buf)
+		if w.isClosing() {
+			return
+		}
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || n < syscall.SizeofInotifyEvent {
+			w.errs <- fmt.Errorf("unable to read the inotify events: %v", err)
+			return
+		}
+
+		changed := false
+		for offset := 0; offset+syscall.SizeofInotifyEvent <= n; {
+			event := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[offset]))
+			nameStart := offset + syscall.SizeofInotifyEvent
+			name := strings.TrimRight(string(buf[nameStart:nameStart+int(event.Len)]), "\x00")
+			offset = nameStart + int(event.Len)
+
+			if w.relevant(event.Wd, event.Mask, name) {
+				changed = true
+			}
+		}
+		if changed {
+			select {
+			case w.events <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// relevant tells whether an event changes the configuration
+func (w *inotifyWatcher) relevant(wd int32, mask uint32, name string) bool {
+	if mask&syscall.IN_Q_OVERFLOW != 0 {
+		return true
+	}
+	w.mu.Lock()
+	cfgWd, dropInWd := w.cfgWd, w.dropInWd
+	w.mu.Unlock()
+
+	switch int(wd) {
+	case cfgWd:
+		if name == filepath.Base(w.dropInDir) {
+			if mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0 {
+				w.watchDropInDir()
+			}
+			return true
+		}
+		return name == w.cfgName
+	case dropInWd:
+		return strings.HasSuffix(name, ".yaml")
+	}
+	return false
+}
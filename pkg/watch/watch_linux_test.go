@@ -0,0 +1,84 @@
+//go:build linux
+// +build linux
+
+package watch
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestConfig(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "caasp-init-watch")
+	if err != nil {
+		t.Fatalf("creating tmp dir: %s", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	cfgPath := filepath.Join(tmpDir, "kubic-init.yaml")
+	if err := ioutil.WriteFile(cfgPath, []byte("runtime:\n  engine: docker\n"), 0644); err != nil {
+		t.Fatalf("writing config: %s", err)
+	}
+
+	stop := make(chan struct{})
+	calls := make(chan struct{}, 10)
+	done := make(chan error)
+	go func() {
+		done <- Config(cfgPath, 20*time.Millisecond, stop, func() { calls <- struct{}{} })
+	}()
+	// give the watcher the time to start
+	time.Sleep(50 * time.Millisecond)
+
+	tests := []struct {
+		name       string
+		change     func() error
+		wantChange bool
+	}{
+		{"other_file", func() error {
+			return ioutil.WriteFile(filepath.Join(tmpDir, "other.yaml"), []byte("a: b\n"), 0644)
+		}, false},
+		{"write", func() error {
+			return ioutil.WriteFile(cfgPath, []byte("runtime:\n  engine: crio\n"), 0644)
+		}, true},
+		{"rename", func() error {
+			tmp := filepath.Join(tmpDir, ".kubic-init.yaml.swp")
+			if err := ioutil.WriteFile(tmp, []byte("runtime:\n  engine: docker\n"), 0644); err != nil {
+				return err
+			}
+			return os.Rename(tmp, cfgPath)
+		}, true},
+		{"drop_in_dir", func() error {
+			return os.Mkdir(cfgPath+".d", 0755)
+		}, true},
+		{"drop_in", func() error {
+			return ioutil.WriteFile(filepath.Join(cfgPath+".d", "10-mirrors.yaml"), []byte("bootstrap: {}\n"), 0644)
+		}, true},
+		{"drop_in_other_file", func() error {
+			return ioutil.WriteFile(filepath.Join(cfgPath+".d", "README"), []byte("drop-ins\n"), 0644)
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatalf("changing the files: %s", err)
+			}
+			select {
+			case <-calls:
+				if !tt.wantChange {
+					t.Errorf("Config() notified an unrelated change")
+				}
+			case <-time.After(300 * time.Millisecond):
+				if tt.wantChange {
+					t.Errorf("Config() did not notify the change")
+				}
+			}
+		})
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("Config() error = %v", err)
+	}
+}
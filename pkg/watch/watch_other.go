@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package watch
+
+import (
+	"errors"
+)
+
+func newWatcher(cfgPath string) (watcher, error) {
+	return nil, errors.New("watching the configuration is only supported on Linux")
+}
@@ -0,0 +1,49 @@
+package watch
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestDebounce(t *testing.T) {
+	events := make(chan struct{}, 1)
+	errs := make(chan error, 1)
+	stop := make(chan struct{})
+	calls := make(chan struct{}, 10)
+
+	done := make(chan error)
+	go func() {
+		done <- debounce(events, errs, 50*time.Millisecond, stop, func() { calls <- struct{}{} })
+	}()
+
+	// a burst of events leads to a single call
+	for i := 0; i < 5; i++ {
+		events <- struct{}{}
+		time.Sleep(10 * time.Millisecond)
+	}
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatalf("debounce() did not call onChange")
+	}
+	select {
+	case <-calls:
+		t.Fatalf("debounce() called onChange twice for a single burst")
+	case <-time.After(150 * time.Millisecond):
+	}
+
+	close(stop)
+	if err := <-done; err != nil {
+		t.Errorf("debounce() error = %v", err)
+	}
+}
+
+func TestDebounceError(t *testing.T) {
+	errs := make(chan error, 1)
+	errs <- errors.New("inotify failed")
+	err := debounce(nil, errs, time.Millisecond, nil, func() {})
+	if err == nil {
+		t.Errorf("debounce() did not return the watcher error")
+	}
+}